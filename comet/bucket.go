@@ -45,11 +45,11 @@ func (b *Bucket) Del(subKey string) {
 	b.cLock.Unlock()
 }
 
-func (b *Bucket) Broadcast(ver int16, operation int32, msg []byte) {
+// Channels return a copy of all the channels.
+func (b *Bucket) Channels() (chs []*Channel) {
 	var (
 		chl int
 		ch  *Channel
-		chs []*Channel
 	)
 	b.cLock.Lock()
 	chl = len(b.chs)
@@ -62,8 +62,27 @@ func (b *Bucket) Broadcast(ver int16, operation int32, msg []byte) {
 		chs = append(chs, ch)
 	}
 	b.cLock.Unlock()
-	for _, ch = range chs {
+	return
+}
+
+// Keys return a copy of all the sub keys.
+func (b *Bucket) Keys() (keys []string) {
+	b.cLock.Lock()
+	keys = make([]string, 0, len(b.chs))
+	for key := range b.chs {
+		keys = append(keys, key)
+	}
+	b.cLock.Unlock()
+	return
+}
+
+// Broadcast push the message to all the channels of the app.
+func (b *Bucket) Broadcast(appId int32, ver int16, operation, priority int32, msg []byte) {
+	for _, ch := range b.Channels() {
+		if ch.AppId != appId {
+			continue
+		}
 		// ignore error
-		ch.PushMsg(ver, operation, msg)
+		ch.PushMsg(ver, operation, priority, 0, msg)
 	}
 }
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"github.com/Terry-Mao/goim/define"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -12,17 +14,22 @@ const (
 
 // Channel used by message pusher send msg to write goroutine.
 type Channel struct {
-	signal   chan int
-	CliProto Ring
-	SvrProto Ring
-	cLock    sync.Mutex
+	signal       chan int
+	CliProto     Ring
+	SvrProto     Ring
+	SvrProtoHigh Ring  // high priority server proto, drained first
+	AppId        int32 // the app of the user, set after auth
+	Ack          bool  // the tcp client read the message id header, set after auth
+	closing      int32 // close the conn after the server protos written
+	cLock        sync.Mutex
 }
 
-func NewChannel(cliProto, svrProto int) *Channel {
+func NewChannel(cliProto, svrProto, svrProtoHigh int) *Channel {
 	c := new(Channel)
 	c.signal = make(chan int, signalNum)
 	InitRing(&c.CliProto, cliProto)
 	InitRing(&c.SvrProto, svrProto)
+	InitRing(&c.SvrProtoHigh, svrProtoHigh)
 	return c
 }
 
@@ -48,26 +55,62 @@ func (c *Channel) Finish() {
 	}
 }
 
-// not goroutine safe, must push one by one.
-func (c *Channel) PushMsg(ver int16, operation int32, body []byte) (err error) {
-	var proto *Proto
+// Close tell the dispatcher to close the conn after the queued server protos
+// written, the reader exit with the closed conn.
+func (c *Channel) Close() {
+	atomic.StoreInt32(&c.closing, 1)
+	c.Signal()
+}
+
+// Closing return true if the channel is closed by the server.
+func (c *Channel) Closing() bool {
+	return atomic.LoadInt32(&c.closing) == 1
+}
+
+// svrRing get the server proto ring by the message priority.
+func (c *Channel) svrRing(priority int32) *Ring {
+	if priority == define.PRIORITY_HIGH {
+		return &c.SvrProtoHigh
+	}
+	return &c.SvrProto
+}
+
+// SvrProtoGet get a server proto, the high priority ring first, the returned
+// ring must be GetAdv after the proto is sent.
+func (c *Channel) SvrProtoGet() (proto *Proto, r *Ring, err error) {
+	r = &c.SvrProtoHigh
+	if proto, err = r.Get(); err != nil {
+		r = &c.SvrProto
+		proto, err = r.Get()
+	}
+	return
+}
+
+// not goroutine safe, must push one by one, msgId 0 means no message id.
+func (c *Channel) PushMsg(ver int16, operation int32, priority int32, msgId int64, body []byte) (err error) {
+	var (
+		proto *Proto
+		r     = c.svrRing(priority)
+	)
 	c.cLock.Lock()
 	// fetch a proto from channel free list
-	if proto, err = c.SvrProto.Set(); err != nil {
+	if proto, err = r.Set(); err != nil {
 		c.cLock.Unlock()
 		return
 	}
 	proto.Ver = ver
 	proto.Operation = operation
+	proto.MsgId = msgId
 	proto.Body = body
-	c.SvrProto.SetAdv()
+	r.SetAdv()
 	c.cLock.Unlock()
 	c.Signal()
 	return
 }
 
-// not goroutine safe, must push one by one.
-func (c *Channel) PushMsgs(ver []int32, operations []int32, bodies [][]byte) (idx int32, err error) {
+// not goroutine safe, must push one by one, the msgIds could be shorter than
+// the bodies, the message without id is not acked.
+func (c *Channel) PushMsgs(ver []int32, operations []int32, msgIds []int64, bodies [][]byte) (idx int32, err error) {
 	var (
 		proto *Proto
 		n     int32
@@ -80,6 +123,10 @@ func (c *Channel) PushMsgs(ver []int32, operations []int32, bodies [][]byte) (id
 		}
 		proto.Ver = int16(ver[n])
 		proto.Operation = operations[n]
+		proto.MsgId = 0
+		if n < int32(len(msgIds)) {
+			proto.MsgId = msgIds[n]
+		}
 		proto.Body = bodies[n]
 		c.SvrProto.SetAdv()
 		idx = n
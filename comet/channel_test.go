@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/Terry-Mao/goim/define"
+	"testing"
+)
+
+func TestChannelPriority(t *testing.T) {
+	ch := NewChannel(1, 2, 2)
+	if err := ch.PushMsg(1, 1, define.PRIORITY_NORMAL, 0, nil); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if err := ch.PushMsg(1, 2, define.PRIORITY_HIGH, 0, nil); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	p, r, err := ch.SvrProtoGet()
+	if err != nil || p.Operation != 2 || r != &ch.SvrProtoHigh {
+		t.Errorf("high priority proto not first, error(%v)", err)
+		t.FailNow()
+	}
+	r.GetAdv()
+	p, r, err = ch.SvrProtoGet()
+	if err != nil || p.Operation != 1 || r != &ch.SvrProto {
+		t.Errorf("normal priority proto not second, error(%v)", err)
+		t.FailNow()
+	}
+	r.GetAdv()
+	if _, _, err = ch.SvrProtoGet(); err != ErrRingEmpty {
+		t.Errorf("ch.SvrProtoGet() error(%v)", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"encoding/json"
+	"fmt"
+	"github.com/Terry-Mao/goim/define"
+	"github.com/Terry-Mao/goim/libs/client"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	benchDialTimeout = 5 * time.Second
+	benchMaxLatency  = 1 << 20 // max latency samples
+)
+
+// benchBody is the pushed body, ts is the unix nano when pushed.
+type benchBody struct {
+	Ts int64 `json:"ts"`
+}
+
+// benchStat is the statistics of the load generation.
+type benchStat struct {
+	connected   int64
+	failed      int64
+	disconnects int64
+	received    int64
+	stopped     int32
+	lock        sync.Mutex
+	latencies   []time.Duration
+	start       time.Time
+	lastConnect int64 // unix nano of the last connected
+}
+
+var (
+	bench = &benchStat{}
+)
+
+func (s *benchStat) connect(ok bool) {
+	if ok {
+		atomic.AddInt64(&s.connected, 1)
+		atomic.StoreInt64(&s.lastConnect, time.Now().UnixNano())
+	} else {
+		atomic.AddInt64(&s.failed, 1)
+	}
+}
+
+func (s *benchStat) disconnect() {
+	if atomic.LoadInt32(&s.stopped) == 0 {
+		atomic.AddInt64(&s.disconnects, 1)
+	}
+}
+
+// receive record the latency of the pushed body with the timestamp.
+func (s *benchStat) receive(body []byte) {
+	var b benchBody
+	atomic.AddInt64(&s.received, 1)
+	if err := json.Unmarshal(body, &b); err != nil || b.Ts == 0 {
+		return
+	}
+	d := time.Now().Sub(time.Unix(0, b.Ts))
+	s.lock.Lock()
+	if len(s.latencies) < benchMaxLatency {
+		s.latencies = append(s.latencies, d)
+	}
+	s.lock.Unlock()
+}
+
+// report print the summary.
+func (s *benchStat) report() {
+	var (
+		p50, p99, max time.Duration
+		rate          float64
+		connected     = atomic.LoadInt64(&s.connected)
+		last          = atomic.LoadInt64(&s.lastConnect)
+	)
+	atomic.StoreInt32(&s.stopped, 1)
+	if last > 0 {
+		if d := time.Unix(0, last).Sub(s.start).Seconds(); d > 0 {
+			rate = float64(connected) / d
+		}
+	}
+	s.lock.Lock()
+	sort.Sort(durations(s.latencies))
+	if n := len(s.latencies); n > 0 {
+		p50 = s.latencies[n*50/100]
+		p99 = s.latencies[n*99/100]
+		max = s.latencies[n-1]
+	}
+	samples := len(s.latencies)
+	s.lock.Unlock()
+	fmt.Printf("connected: %d, failed: %d, disconnects: %d, connect rate: %.2f/s\n", connected, atomic.LoadInt64(&s.failed), atomic.LoadInt64(&s.disconnects), rate)
+	fmt.Printf("received: %d, latency samples: %d, p50: %s, p99: %s, max: %s\n", atomic.LoadInt64(&s.received), samples, p50, p99, max)
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// runBench start Conf.BenchConns connections evenly in Conf.BenchRamp, run
+// Conf.BenchDuration then report.
+func runBench() {
+	var interval time.Duration
+	if Conf.BenchConns > 1 {
+		interval = Conf.BenchRamp / time.Duration(Conf.BenchConns)
+	}
+	log.Info("bench start %d connections, ramp: %s, duration: %s", Conf.BenchConns, Conf.BenchRamp, Conf.BenchDuration)
+	bench.start = time.Now()
+	end := time.After(Conf.BenchDuration)
+	go func() {
+		for i := 0; i < Conf.BenchConns; i++ {
+			go benchConn()
+			if interval > 0 {
+				time.Sleep(interval)
+			}
+		}
+	}()
+	<-end
+	bench.report()
+}
+
+// benchConn connect by the client, record the pushed messages until
+// disconnected.
+func benchConn() {
+	c, err := client.Dial(client.Options{
+		Network:     network(),
+		Addr:        addr(),
+		Token:       Conf.BenchToken,
+		Heartbeat:   Conf.BenchHeartbeat,
+		DialTimeout: benchDialTimeout,
+	})
+	if err != nil {
+		log.Error("client.Dial(\"%s\") error(%v)", addr(), err)
+		bench.connect(false)
+		return
+	}
+	bench.connect(true)
+	for p := range c.Messages() {
+		if p.Operation == define.OP_SEND_SMS_REPLY {
+			bench.receive(p.Body)
+		}
+	}
+	bench.disconnect()
+}
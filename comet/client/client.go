@@ -0,0 +1,37 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"github.com/Terry-Mao/goim/define"
+	"github.com/Terry-Mao/goim/libs/client"
+	"time"
+)
+
+const (
+	testInterval = 10 * time.Second
+)
+
+// runClient connect the comet, send the test operation periodically and
+// print the pushed messages.
+func runClient() {
+	c, err := client.Dial(client.Options{Network: network(), Addr: addr(), Token: "test", Reconnect: true})
+	if err != nil {
+		log.Error("client.Dial(\"%s\") error(%v)", addr(), err)
+		return
+	}
+	defer c.Close()
+	log.Debug("auth ok, heartbeat: %s", c.Heartbeat())
+	go func() {
+		for {
+			if p, err := c.Request(define.OP_TEST, nil); err != nil {
+				log.Error("c.Request() error(%v)", err)
+			} else {
+				log.Debug("test reply body: %s", string(p.Body))
+			}
+			time.Sleep(testInterval)
+		}
+	}()
+	for p := range c.Messages() {
+		log.Debug("op: %d, body: %s", p.Operation, string(p.Body))
+	}
+}
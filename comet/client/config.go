@@ -4,6 +4,7 @@ import (
 	"flag"
 	"github.com/Terry-Mao/goconf"
 	"runtime"
+	"time"
 )
 
 var (
@@ -31,6 +32,12 @@ type Config struct {
 	Type          int    `goconf:"proto:type"`
 	// sub
 	SubKey string `goconf:sub:sub.key`
+	// bench
+	BenchConns     int           `goconf:"bench:conns"`
+	BenchRamp      time.Duration `goconf:"bench:ramp:time"`
+	BenchDuration  time.Duration `goconf:"bench:duration:time"`
+	BenchHeartbeat time.Duration `goconf:"bench:heartbeat:time"`
+	BenchToken     string        `goconf:"bench:token"`
 }
 
 func NewConfig() *Config {
@@ -49,6 +56,12 @@ func NewConfig() *Config {
 		Type:          ProtoTCP,
 		// sub
 		SubKey: "Terry-Mao",
+		// bench
+		BenchConns:     0,
+		BenchRamp:      10 * time.Second,
+		BenchDuration:  60 * time.Second,
+		BenchHeartbeat: 10 * time.Second,
+		BenchToken:     "test",
 	}
 }
 
@@ -5,7 +5,6 @@ import (
 	"flag"
 	"github.com/Terry-Mao/goim/libs/perf"
 	"runtime"
-	"time"
 )
 
 func main() {
@@ -17,10 +16,9 @@ func main() {
 	log.LoadConfiguration(Conf.Log)
 	defer log.Close()
 	perf.Init(Conf.PprofBind)
-	if Conf.Type == ProtoTCP {
-		initTCP()
-	} else if Conf.Type == ProtoWebsocket {
-		initWebsocket()
+	if Conf.BenchConns > 0 {
+		runBench()
+		return
 	}
-	time.Sleep(10 * time.Second)
+	runClient()
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+const (
+	// body codec
+	codecJSON     = "json"
+	codecProtobuf = "protobuf"
+	codecMsgpack  = "msgpack"
+	codecRaw      = "raw"
+)
+
+var (
+	codecLock  sync.RWMutex
+	bodyCodecs = map[string]BodyCodec{
+		codecJSON:     jsonCodec{},
+		codecProtobuf: binaryCodec(codecProtobuf),
+		codecMsgpack:  binaryCodec(codecMsgpack),
+		codecRaw:      binaryCodec(codecRaw),
+	}
+)
+
+// BodyCodec convert the proto body to the json value embedded in the
+// websocket and http long polling frame, the tcp frame carry the body bytes
+// directly. Comet never parse the body, so a codec only decide how the bytes
+// are represented in json.
+type BodyCodec interface {
+	// Name get the codec name which negotiated by client.
+	Name() string
+	// Encode encode the body to a json value.
+	Encode(body []byte) (json.RawMessage, error)
+	// Decode decode the json value to the body.
+	Decode(data json.RawMessage) ([]byte, error)
+}
+
+// RegisterCodec register a body codec, replace the one with the same name.
+func RegisterCodec(c BodyCodec) {
+	codecLock.Lock()
+	bodyCodecs[c.Name()] = c
+	codecLock.Unlock()
+}
+
+// GetCodec get a registered body codec by name.
+func GetCodec(name string) (c BodyCodec, err error) {
+	var ok bool
+	codecLock.RLock()
+	c, ok = bodyCodecs[name]
+	codecLock.RUnlock()
+	if !ok {
+		err = ErrCodec
+	}
+	return
+}
+
+// bodyCodec get the codec of the proto, the codec negotiated by the
+// connection first, then the codec of the proto ver, json by default.
+func bodyCodec(c BodyCodec, ver int16) BodyCodec {
+	if c != nil {
+		return c
+	}
+	if c = Conf.CodecVers[ver]; c != nil {
+		return c
+	}
+	return jsonCodec{}
+}
+
+// jsonCodec embed the json body as is.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return codecJSON
+}
+
+func (jsonCodec) Encode(body []byte) (json.RawMessage, error) {
+	if body == nil {
+		return emptyJSONBody, nil
+	}
+	return body, nil
+}
+
+func (jsonCodec) Decode(data json.RawMessage) ([]byte, error) {
+	return data, nil
+}
+
+// binaryCodec embed the binary body such as protobuf and msgpack as a
+// base64 json string.
+type binaryCodec string
+
+func (c binaryCodec) Name() string {
+	return string(c)
+}
+
+func (c binaryCodec) Encode(body []byte) (json.RawMessage, error) {
+	if body == nil {
+		return emptyStringBody, nil
+	}
+	return json.Marshal(body)
+}
+
+func (c binaryCodec) Decode(data json.RawMessage) (body []byte, err error) {
+	if len(data) == 0 {
+		return
+	}
+	err = json.Unmarshal(data, &body)
+	return
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBodyCodec(t *testing.T) {
+	Conf = NewConfig()
+	c, err := GetCodec(codecProtobuf)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	Conf.CodecVers[2] = c
+	if bodyCodec(nil, 1).Name() != codecJSON || bodyCodec(nil, 2).Name() != codecProtobuf {
+		t.Error("bodyCodec() by ver failed")
+		t.FailNow()
+	}
+	if raw, _ := GetCodec(codecRaw); bodyCodec(raw, 2).Name() != codecRaw {
+		t.Error("bodyCodec() negotiated failed")
+		t.FailNow()
+	}
+	body := []byte{0x08, 0x96, 0x01}
+	data, err := c.Encode(body)
+	if err != nil || string(data) != "\"CJYB\"" {
+		t.Errorf("c.Encode() %s error(%v)", data, err)
+		t.FailNow()
+	}
+	if b, err := c.Decode(data); err != nil || !bytes.Equal(b, body) {
+		t.Errorf("c.Decode() %v error(%v)", b, err)
+		t.FailNow()
+	}
+	if data, _ = bodyCodec(nil, 1).Encode(nil); string(data) != "{}" {
+		t.Errorf("json codec Encode(nil) %s", data)
+		t.FailNow()
+	}
+	if _, err = GetCodec("xml"); err != ErrCodec {
+		t.Errorf("GetCodec(\"xml\") error(%v)", err)
+		t.FailNow()
+	}
+}
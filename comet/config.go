@@ -20,6 +20,7 @@ import (
 	"flag"
 	"github.com/Terry-Mao/goconf"
 	"runtime"
+	"strconv"
 	"time"
 )
 
@@ -35,22 +36,30 @@ func init() {
 
 type Config struct {
 	// base section
-	PidFile   string   `goconf:"base:pidfile"`
-	Dir       string   `goconf:"base:dir"`
-	Log       string   `goconf:"base:log"`
-	MaxProc   int      `goconf:"base:maxproc"`
-	PprofBind []string `goconf:"base:pprof.bind:,"`
-	StatBind  []string `goconf:"base:stat.bind:,"`
-	ServerId  int32    `goconf:"base:server.id"`
+	PidFile   string        `goconf:"base:pidfile"`
+	Dir       string        `goconf:"base:dir"`
+	Log       string        `goconf:"base:log"`
+	MaxProc   int           `goconf:"base:maxproc"`
+	PprofBind []string      `goconf:"base:pprof.bind:,"`
+	StatBind  []string      `goconf:"base:stat.bind:,"`
+	ServerId  int32         `goconf:"base:server.id"`
+	Drain     time.Duration `goconf:"base:drain:time"`
 	// tcp
 	TCPBind      []string `goconf:"tcp:bind:,"`
 	TCPSndbuf    int      `goconf:"tcp:sndbuf:memory"`
 	TCPRcvbuf    int      `goconf:"tcp:rcvbuf:memory"`
 	TCPKeepalive bool     `goconf:"tcp:keepalive"`
 	// websocket
-	WebsocketBind []string `goconf:"websocket:bind:,"`
+	WebsocketBind          []string      `goconf:"websocket:bind:,"`
+	WebsocketReadLimit     int           `goconf:"websocket:read.limit:memory"`
+	WebsocketPing          time.Duration `goconf:"websocket:ping:time"`
+	WebsocketCompress      bool          `goconf:"websocket:compress"`
+	WebsocketCompressLevel int           `goconf:"websocket:compress.level"`
 	// http
 	HTTPBind []string `goconf:"http:bind:,"`
+	// mqtt
+	MQTTBind  []string `goconf:"mqtt:bind:,"`
+	MQTTTopic string   `goconf:"mqtt:topic"`
 	// proto section
 	HandshakeTimeout time.Duration `goconf:"proto:handshake.timeout:time"`
 	WriteTimeout     time.Duration `goconf:"proto:write.timeout:time"`
@@ -59,21 +68,42 @@ type Config struct {
 	ReadBufSize      int           `goconf:"proto:readbuf.size"`
 	WriteBufSize     int           `goconf:"proto:writebuf.size"`
 	// timer
-	Timer     int `goconf:"proto:timer"`
-	TimerSize int `goconf:"proto:timer.size"`
+	Timer     int    `goconf:"proto:timer"`
+	TimerSize int    `goconf:"proto:timer.size"`
+	TimerType string `goconf:"proto:timer.type"`
 	// bucket
-	Bucket   int `goconf:"bucket:bucket.num"`
-	CliProto int `goconf:"bucket:cli.proto.num"`
-	SvrProto int `goconf:"bucket:svr.proto.num"`
-	Channel  int `goconf:"bucket:channel.num"`
+	Bucket       int `goconf:"bucket:bucket.num"`
+	CliProto     int `goconf:"bucket:cli.proto.num"`
+	SvrProto     int `goconf:"bucket:svr.proto.num"`
+	SvrProtoHigh int `goconf:"bucket:svr.proto.high.num"`
+	Channel      int `goconf:"bucket:channel.num"`
 	// push
 	HTTPPushAddrs    []string      `goconf:"push:http.addrs:,"`
 	HTTPReadTimeout  time.Duration `goconf:"push:http.read.timeout:time"`
 	HTTPWriteTimeout time.Duration `goconf:"push:http.write.timeout:time"`
+	HTTPPushBodyMax  int           `goconf:"push:http.body.max:memory"`
 	RPCPushAddrs     []string      `goconf:"push:rpc.addrs:,"`
 	// logic
-	LogicNetwork string `goconf:"logic:network"`
-	LogicAddr    string `goconf:"logic:addr"`
+	LogicNetwork    string        `goconf:"logic:network"`
+	LogicAddr       string        `goconf:"logic:addr"`
+	LogicHeartbeat  time.Duration `goconf:"logic:heartbeat:time"`
+	LogicRenew      time.Duration `goconf:"logic:renew:time"`
+	LogicRenewBatch int           `goconf:"logic:renew.batch"`
+	// limit
+	LimitAction     string               `goconf:"limit:action"`
+	LimitOps        map[int32]*LimitRule `goconf:"-"`
+	LimitConn       int                  `goconf:"limit:conn.max"`
+	LimitConnIP     int                  `goconf:"limit:conn.ip.max"`
+	LimitConnIPRate string               `goconf:"limit:conn.ip.rate"`
+	LimitConnIPRule *LimitRule           `goconf:"-"`
+	// codec
+	CodecVers map[int16]BodyCodec `goconf:"-"`
+	// registry
+	RegistryType     string        `goconf:"registry:type"`
+	RegistryDir      string        `goconf:"registry:dir"`
+	RegistryTTL      time.Duration `goconf:"registry:ttl:time"`
+	RegistryAddr     string        `goconf:"registry:addr"`
+	RegistryCapacity int           `goconf:"registry:capacity"`
 }
 
 func NewConfig() *Config {
@@ -85,15 +115,23 @@ func NewConfig() *Config {
 		MaxProc:   runtime.NumCPU(),
 		PprofBind: []string{"localhost:6971"},
 		StatBind:  []string{"localhost:6972"},
+		Drain:     1 * time.Second,
 		// tcp
 		TCPBind:      []string{"localhost:8080"},
 		TCPSndbuf:    1024,
 		TCPRcvbuf:    1024,
 		TCPKeepalive: false,
 		// websocket
-		WebsocketBind: []string{"localhost:8090"},
+		WebsocketBind:          []string{"localhost:8090"},
+		WebsocketReadLimit:     maxPackLen,
+		WebsocketPing:          0,
+		WebsocketCompress:      false,
+		WebsocketCompressLevel: 1,
 		// http
 		HTTPBind: []string{"localhost:8070"},
+		// mqtt
+		MQTTBind:  []string{},
+		MQTTTopic: "goim/push",
 		// proto section
 		HandshakeTimeout: 5 * time.Second,
 		WriteTimeout:     5 * time.Second,
@@ -103,13 +141,31 @@ func NewConfig() *Config {
 		WriteBufSize:     1024,
 		Timer:            1024,
 		TimerSize:        1000,
+		TimerType:        timerTypeHeap,
 		// bucket
-		Bucket:   1024,
-		CliProto: 1024,
-		SvrProto: 1024,
-		Channel:  1024,
+		Bucket:       1024,
+		CliProto:     1024,
+		SvrProto:     1024,
+		SvrProtoHigh: 64,
+		Channel:      1024,
 		// push
-		RPCPushAddrs: []string{"localhost:8083"},
+		HTTPPushAddrs:    []string{},
+		HTTPReadTimeout:  5 * time.Second,
+		HTTPWriteTimeout: 5 * time.Second,
+		HTTPPushBodyMax:  1024 * 1024,
+		RPCPushAddrs:     []string{"localhost:8083"},
+		// logic
+		LogicHeartbeat:  10 * time.Second,
+		LogicRenew:      3 * time.Minute,
+		LogicRenewBatch: 1000,
+		// limit
+		LimitAction: limitActionDrop,
+		LimitOps:    make(map[int32]*LimitRule),
+		// codec
+		CodecVers: make(map[int16]BodyCodec),
+		// registry
+		RegistryDir: "./registry",
+		RegistryTTL: 30 * time.Second,
 	}
 }
 
@@ -123,7 +179,13 @@ func InitConfig() (err error) {
 	if err := gconf.Unmarshal(Conf); err != nil {
 		return err
 	}
-	return nil
+	if Conf.TimerType != timerTypeHeap && Conf.TimerType != timerTypeWheel {
+		return ErrTimerType
+	}
+	if err := parseCodecVers(gconf, Conf); err != nil {
+		return err
+	}
+	return parseLimitOps(gconf, Conf)
 }
 
 func ReloadConfig() (*Config, error) {
@@ -135,6 +197,72 @@ func ReloadConfig() (*Config, error) {
 	if err := ngconf.Unmarshal(conf); err != nil {
 		return nil, err
 	}
+	if err := parseLimitOps(ngconf, conf); err != nil {
+		return nil, err
+	}
+	if err := parseCodecVers(ngconf, conf); err != nil {
+		return nil, err
+	}
 	gconf = ngconf
 	return conf, nil
 }
+
+// parseLimitOps parse the [limit.ops] section, key is the operation and value
+// is the "rate,burst" of the token bucket, the conn.ip.rate is parsed too.
+func parseLimitOps(gconf *goconf.Config, conf *Config) (err error) {
+	switch conf.LimitAction {
+	case limitActionDrop, limitActionReply, limitActionDisconnect:
+	default:
+		return ErrLimitAction
+	}
+	if conf.LimitConnIPRate != "" {
+		if conf.LimitConnIPRule, err = parseLimitRule(conf.LimitConnIPRate); err != nil {
+			return
+		}
+	}
+	section := gconf.Get("limit.ops")
+	if section == nil {
+		return nil
+	}
+	for _, op := range section.Keys() {
+		v, err := section.String(op)
+		if err != nil {
+			return err
+		}
+		opi, err := strconv.ParseInt(op, 10, 32)
+		if err != nil {
+			return err
+		}
+		rule, err := parseLimitRule(v)
+		if err != nil {
+			return err
+		}
+		conf.LimitOps[int32(opi)] = rule
+	}
+	return nil
+}
+
+// parseCodecVers parse the [codec.vers] section, key is the proto ver and
+// value is the body codec name.
+func parseCodecVers(gconf *goconf.Config, conf *Config) error {
+	section := gconf.Get("codec.vers")
+	if section == nil {
+		return nil
+	}
+	for _, ver := range section.Keys() {
+		name, err := section.String(ver)
+		if err != nil {
+			return err
+		}
+		veri, err := strconv.ParseInt(ver, 10, 16)
+		if err != nil {
+			return err
+		}
+		codec, err := GetCodec(name)
+		if err != nil {
+			return err
+		}
+		conf.CodecVers[int16(veri)] = codec
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ var (
 	// server
 	ErrHandshake = errors.New("handshake failed")
 	ErrOperation = errors.New("request operation not valid")
+	ErrDraining  = errors.New("server draining")
 	// codec
 	ErrProtoPackLen   = errors.New("default server codec pack length error")
 	ErrProtoHeaderLen = errors.New("default server codec header length error")
@@ -18,11 +19,27 @@ var (
 	ErrTimerFull   = errors.New("timer full")
 	ErrTimerEmpty  = errors.New("timer empty")
 	ErrTimerNoItem = errors.New("timer item not exist")
+	ErrTimerType   = errors.New("timer type must be heap or wheel")
 	// channel
 	ErrPushMsgArg   = errors.New("rpc pushmsg arg error")
 	ErrPushMsgsArg  = errors.New("rpc pushmsgs arg error")
 	ErrMPushMsgArg  = errors.New("rpc mpushmsg arg error")
 	ErrMPushMsgsArg = errors.New("rpc mpushmsgs arg error")
+	// mqtt
+	ErrMQTTPacket     = errors.New("mqtt packet not valid")
+	ErrMQTTProto      = errors.New("mqtt protocol must be MQTT 3.1.1")
+	ErrMQTTQos        = errors.New("mqtt qos 2 not support")
+	ErrMQTTDisconnect = errors.New("mqtt client disconnect")
+	// codec
+	ErrCodec = errors.New("body codec not exist")
 	// rpc
-	ErrLogic = errors.New("logic rpc is not available")
+	ErrLogic       = errors.New("logic rpc is not available")
+	ErrSessionLost = errors.New("session lost, reconnect")
+	// limit
+	ErrLimitRule   = errors.New("limit rule must be \"rate,burst\"")
+	ErrLimitAction = errors.New("limit action must be drop, reply or disconnect")
+	ErrRateLimit   = errors.New("client operation rate limited")
+	ErrConnLimit   = errors.New("max connections exceeded")
+	ErrConnIPLimit = errors.New("max connections of ip exceeded")
+	ErrConnIPRate  = errors.New("accept rate of ip limited")
 )
@@ -19,12 +19,17 @@ const (
 	headerLenSize = 2
 )
 
+var (
+	httpLimiter *IPLimiter
+)
+
 func InitHTTP() (err error) {
 	var (
 		listener     *net.TCPListener
 		addr         *net.TCPAddr
 		httpServeMux = http.NewServeMux()
 	)
+	httpLimiter = NewIPLimiter(Conf.LimitOps)
 	httpServeMux.HandleFunc("/sub", serveHTTP)
 	for _, bind := range Conf.HTTPBind {
 		if addr, err = net.ResolveTCPAddr("tcp4", bind); err != nil {
@@ -53,28 +58,55 @@ func serveHTTP(w http.ResponseWriter, r *http.Request) {
 		rAddr = r.RemoteAddr
 		// timer
 		tr = DefaultServer.round.Timer(rand.Int())
+		ip = remoteIP(r)
 	)
+	// limit before the handshake timer armed
+	if err := connLimiter.Acquire(ip); err != nil {
+		log.Warn("connLimiter.Acquire(\"%s\") error(%v)", ip, err)
+		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer connLimiter.Release(ip)
 	log.Debug("start websocket serve with \"%s\"", rAddr)
 	DefaultServer.serveHTTP(w, r, tr)
 }
 
-func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request, tr *Timer) {
+func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request, tr Timer) {
 	var (
-		b    *Bucket
-		ch   *Channel
-		hb   time.Duration // heartbeat
-		key  string
-		cb   string
-		err  error
-		trd  *TimerData
-		conn net.Conn
-		rwr  *bufio.ReadWriter
-		hj   http.Hijacker
-		p    = new(Proto)
-		ok   bool
+		b     *Bucket
+		ch    *Channel
+		hb    time.Duration // heartbeat
+		key   string
+		appId int32
+		cb    string
+		err   error
+		trd   *TimerData
+		conn  net.Conn
+		rwr   *bufio.ReadWriter
+		hj    http.Hijacker
+		codec BodyCodec
+		p     = new(Proto)
+		ok    bool
 	)
-	if key, cb, hb, err = server.authHTTP(r, p); err != nil {
-		http.Error(w, "auth failed", http.StatusForbidden)
+	// negotiate body codec, use the codec of proto ver if not set
+	if name := r.URL.Query().Get("codec"); name != "" {
+		if codec, err = GetCodec(name); err != nil {
+			log.Error("GetCodec(\"%s\") error(%v)", name, err)
+			http.Error(w, "codec not support", http.StatusBadRequest)
+			return
+		}
+	}
+	if key, cb, appId, hb, err = server.authHTTP(r, p); err != nil {
+		switch err {
+		case ErrRateLimit:
+			server.errorHTTP(w, cb, codec, p, define.ERR_RATE_LIMIT, err)
+		case ErrOperation:
+			server.errorHTTP(w, cb, codec, p, define.ERR_OPERATION, err)
+		case ErrDraining:
+			server.errorHTTP(w, cb, codec, p, define.ERR_DRAINING, err)
+		default:
+			server.errorHTTP(w, cb, codec, p, define.ERR_AUTH, err)
+		}
 		return
 	}
 	if hj, ok = w.(http.Hijacker); !ok {
@@ -98,10 +130,14 @@ func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request, tr *Time
 	// register key->channel
 	b = server.Bucket(key)
 	// no client send
-	ch = NewChannel(0, 1)
+	ch = NewChannel(0, 1, 1)
+	ch.AppId = appId
 	b.Put(key, ch)
+	if err := server.operator.Online(key); err != nil {
+		log.Error("%s operator do online error(%v)", key, err)
+	}
 	// hanshake ok start dispatch goroutine
-	server.dispatchHTTP(rwr, cb, ch)
+	server.dispatchHTTP(rwr, cb, codec, ch)
 	// dialog finish
 	// revoke the subkey
 	// revoke the remote subkey
@@ -126,31 +162,32 @@ func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request, tr *Time
 // dispatch accepts connections on the listener and serves requests
 // for each incoming connection.  dispatch blocks; the caller typically
 // invokes it in a go statement.
-func (server *Server) dispatchHTTP(rwr *bufio.ReadWriter, cb string, ch *Channel) {
+func (server *Server) dispatchHTTP(rwr *bufio.ReadWriter, cb string, codec BodyCodec, ch *Channel) {
 	var (
 		p   *Proto
+		r   *Ring
 		err error
 	)
 	log.Debug("start dispatch goroutine")
 	if !ch.Ready() {
 		return
 	}
-	// fetch message from svrbox(server send)
-	if p, err = ch.SvrProto.Get(); err != nil {
+	// fetch message from svrbox(server send), high priority first
+	if p, r, err = ch.SvrProtoGet(); err != nil {
 		log.Debug("channel no more server message, wait signal")
 		return
 	}
 	// just forward the message
-	if err = server.writeHTTPResponse(rwr, cb, p); err != nil {
+	if err = server.writeHTTPResponse(rwr, cb, codec, p); err != nil {
 		log.Error("server.sendTCPResponse() error(%v)", err)
 		return
 	}
-	ch.SvrProto.GetAdv()
+	r.GetAdv()
 	return
 }
 
 // auth for goim handshake with client, use rsa & aes.
-func (server *Server) authHTTP(r *http.Request, p *Proto) (subKey, callback string, heartbeat time.Duration, err error) {
+func (server *Server) authHTTP(r *http.Request, p *Proto) (subKey, callback string, appId int32, heartbeat time.Duration, err error) {
 	var (
 		pStr   string
 		pInt   int64
@@ -174,24 +211,69 @@ func (server *Server) authHTTP(r *http.Request, p *Proto) (subKey, callback stri
 		return
 	}
 	p.SeqId = int32(pInt)
+	callback = params.Get("cb")
 	if p.Operation != define.OP_AUTH {
 		log.Warn("auth operation not valid: %d", p.Operation)
 		err = ErrOperation
 		return
 	}
-	callback = params.Get("cb")
+	if !httpLimiter.Allow(remoteIP(r), p.Operation) {
+		log.Warn("operation: %d rate limited, action: %s", p.Operation, Conf.LimitAction)
+		err = ErrRateLimit
+		return
+	}
+	if server.Draining() {
+		err = ErrDraining
+		return
+	}
 	p.Body = []byte(params.Get("t"))
-	if subKey, heartbeat, err = server.operator.Connect(p); err != nil {
+	if subKey, appId, heartbeat, err = server.operator.Connect(p); err != nil {
 		log.Error("operator.Connect error(%v)", err)
 	}
 	return
 }
 
+// errorHTTP reply the error of the rejected request.
+func (server *Server) errorHTTP(w http.ResponseWriter, cb string, codec BodyCodec, p *Proto, code int32, err error) {
+	errorReply(p, code, err)
+	server.replyHTTP(w, cb, codec, p)
+}
+
+// replyHTTP write the proto to the not hijacked request.
+func (server *Server) replyHTTP(w http.ResponseWriter, cb string, codec BodyCodec, p *Proto) {
+	var (
+		pb  []byte
+		err error
+	)
+	if p.Body, err = bodyCodec(codec, p.Ver).Encode(p.Body); err != nil {
+		log.Error("codec.Encode() error(%v)", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if pb, err = json.Marshal(p); err != nil {
+		log.Error("json.Marshal() error(%v)", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if len(cb) != 0 {
+		pb = append([]byte(cb+"="), pb...)
+	}
+	if _, err = w.Write(pb); err != nil {
+		log.Error("http w.Write() error(%v)", err)
+	}
+}
+
+// remoteIP return the client ip of the request.
+func remoteIP(r *http.Request) string {
+	return addrIP(r.RemoteAddr)
+}
+
 // sendResponse send resp to client, sendResponse must be goroutine safe.
-func (server *Server) writeHTTPResponse(rwr *bufio.ReadWriter, cb string, proto *Proto) (err error) {
+func (server *Server) writeHTTPResponse(rwr *bufio.ReadWriter, cb string, codec BodyCodec, proto *Proto) (err error) {
 	var pb []byte
-	if proto.Body == nil {
-		proto.Body = emptyJSONBody
+	if proto.Body, err = bodyCodec(codec, proto.Ver).Encode(proto.Body); err != nil {
+		log.Error("codec.Encode() error(%v)", err)
+		return
 	}
 	if pb, err = json.Marshal(proto); err != nil {
 		log.Error("json.Marshal() error(%v)", err)
@@ -0,0 +1,340 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"encoding/json"
+	"github.com/Terry-Mao/goim/define"
+	inet "github.com/Terry-Mao/goim/libs/net"
+	proto "github.com/Terry-Mao/goim/proto/comet"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// the http push api share the handlers of PushRPC
+	httpPushRPC = &PushRPC{}
+)
+
+// InitHTTPPush start the http push api, the handlers are the same with
+// PushRPC, so the services not using protorpc can push to comet directly.
+func InitHTTPPush() (err error) {
+	var network, addr string
+	for i := 0; i < len(Conf.HTTPPushAddrs); i++ {
+		httpServeMux := http.NewServeMux()
+		httpServeMux.HandleFunc("/1/push", httpPush)
+		httpServeMux.HandleFunc("/1/pushs", httpPushs)
+		httpServeMux.HandleFunc("/1/push/msgs", httpPushMsgs)
+		httpServeMux.HandleFunc("/1/pushs/msgs", httpMPushMsgs)
+		httpServeMux.HandleFunc("/1/push/all", httpPushAll)
+		log.Info("start http push listen:\"%s\"", Conf.HTTPPushAddrs[i])
+		if network, addr, err = inet.ParseNetwork(Conf.HTTPPushAddrs[i]); err != nil {
+			log.Error("inet.ParseNetwork() error(%v)", err)
+			return
+		}
+		go httpPushListen(httpServeMux, network, addr)
+	}
+	return
+}
+
+func httpPushListen(mux *http.ServeMux, network, addr string) {
+	httpServer := &http.Server{Handler: mux, ReadTimeout: Conf.HTTPReadTimeout, WriteTimeout: Conf.HTTPWriteTimeout}
+	httpServer.SetKeepAlivesEnabled(true)
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		log.Error("net.Listen(\"%s\", \"%s\") error(%v)", network, addr, err)
+		panic(err)
+	}
+	if err := httpServer.Serve(l); err != nil {
+		log.Error("server.Serve() error(%v)", err)
+		panic(err)
+	}
+}
+
+// retPWrite marshal the result and write to client(post).
+func retPWrite(w http.ResponseWriter, r *http.Request, res map[string]interface{}, body *string, start time.Time) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		log.Error("json.Marshal(\"%v\") error(%v)", res, err)
+		return
+	}
+	dataStr := string(data)
+	if _, err := w.Write([]byte(dataStr)); err != nil {
+		log.Error("w.Write(\"%s\") error(%v)", dataStr, err)
+	}
+	log.Info("req: \"%s\", post: \"%s\", res:\"%s\", ip:\"%s\", time:\"%fs\"", r.URL.String(), *body, dataStr, r.RemoteAddr, time.Now().Sub(start).Seconds())
+}
+
+// readPushBody read the request body, the body larger than
+// Conf.HTTPPushBodyMax is rejected.
+func readPushBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	return ioutil.ReadAll(http.MaxBytesReader(w, r.Body, int64(Conf.HTTPPushBodyMax)))
+}
+
+// parsePushParams parse the ver, operation and priority url params, the
+// operation is define.OP_SEND_SMS_REPLY if not set.
+func parsePushParams(r *http.Request) (ver int32, operation int32, priority int32, err error) {
+	var (
+		i      int64
+		params = r.URL.Query()
+		verStr = params.Get("ver")
+		opStr  = params.Get("operation")
+		priStr = params.Get("priority")
+	)
+	operation = define.OP_SEND_SMS_REPLY
+	if verStr != "" {
+		if i, err = strconv.ParseInt(verStr, 10, 16); err != nil {
+			return
+		}
+		ver = int32(i)
+	}
+	if opStr != "" {
+		if i, err = strconv.ParseInt(opStr, 10, 32); err != nil {
+			return
+		}
+		operation = int32(i)
+	}
+	if priStr != "" {
+		if i, err = strconv.ParseInt(priStr, 10, 32); err != nil {
+			return
+		}
+		priority = int32(i)
+	}
+	return
+}
+
+// httpPush push a message to a specified sub key.
+// POST /1/push?key=xxx&ver=1&operation=5&priority=0, body is the message.
+func httpPush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		bodyBytes []byte
+		body      string
+		err       error
+		arg       = &proto.PushMsgArg{}
+		res       = map[string]interface{}{"ret": OK}
+	)
+	defer retPWrite(w, r, res, &body, time.Now())
+	if bodyBytes, err = readPushBody(w, r); err != nil {
+		log.Error("readPushBody() error(%v)", err)
+		res["ret"] = ParamErr
+		return
+	}
+	body = string(bodyBytes)
+	if arg.Key = r.URL.Query().Get("key"); arg.Key == "" {
+		res["ret"] = ParamErr
+		return
+	}
+	if arg.Ver, arg.Operation, arg.Priority, err = parsePushParams(r); err != nil {
+		log.Error("parsePushParams(\"%s\") error(%v)", r.URL.String(), err)
+		res["ret"] = ParamErr
+		return
+	}
+	arg.Msg = bodyBytes
+	if err = httpPushRPC.PushMsg(arg, &proto.NoReply{}); err != nil {
+		log.Error("PushMsg(\"%s\") error(%v)", arg.Key, err)
+		res["ret"] = InternalErr
+	}
+}
+
+type pushsBodyMsg struct {
+	Msg  json.RawMessage `json:"m"`
+	Keys []string        `json:"k"`
+}
+
+// httpPushs push a message to multiple sub keys.
+// POST /1/pushs?ver=1&operation=5&priority=0, body {"m":{"test":1},"k":["1_1","2_1"]}.
+func httpPushs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		bodyBytes []byte
+		body      string
+		err       error
+		msg       pushsBodyMsg
+		arg       = &proto.MPushMsgArg{}
+		reply     = &proto.MPushMsgReply{}
+		res       = map[string]interface{}{"ret": OK}
+	)
+	defer retPWrite(w, r, res, &body, time.Now())
+	if bodyBytes, err = readPushBody(w, r); err != nil {
+		log.Error("readPushBody() error(%v)", err)
+		res["ret"] = ParamErr
+		return
+	}
+	body = string(bodyBytes)
+	if err = json.Unmarshal(bodyBytes, &msg); err != nil || len(msg.Keys) == 0 {
+		log.Error("json.Unmarshal(\"%s\") error(%v)", body, err)
+		res["ret"] = ParamErr
+		return
+	}
+	if arg.Ver, arg.Operation, arg.Priority, err = parsePushParams(r); err != nil {
+		log.Error("parsePushParams(\"%s\") error(%v)", r.URL.String(), err)
+		res["ret"] = ParamErr
+		return
+	}
+	arg.Keys = msg.Keys
+	arg.Msg = msg.Msg
+	err = httpPushRPC.MPushMsg(arg, reply)
+	res["index"] = reply.Index
+	if err != nil {
+		log.Error("MPushMsg() error(%v)", err)
+		res["ret"] = InternalErr
+	}
+}
+
+// pushMsgsBodyMsg is a message of the multiple messages push, the key is only
+// used by /1/pushs/msgs, the operation is define.OP_SEND_SMS_REPLY if not set.
+type pushMsgsBodyMsg struct {
+	Key       string          `json:"k"`
+	Ver       int32           `json:"v"`
+	Operation int32           `json:"o"`
+	Msg       json.RawMessage `json:"m"`
+}
+
+// parsePushMsgs parse the multiple messages body.
+func parsePushMsgs(body []byte) (keys []string, vers, operations []int32, msgs [][]byte, err error) {
+	var bodyMsgs []pushMsgsBodyMsg
+	if err = json.Unmarshal(body, &bodyMsgs); err != nil {
+		return
+	}
+	if len(bodyMsgs) == 0 {
+		err = ErrPushMsgsArg
+		return
+	}
+	keys = make([]string, len(bodyMsgs))
+	vers = make([]int32, len(bodyMsgs))
+	operations = make([]int32, len(bodyMsgs))
+	msgs = make([][]byte, len(bodyMsgs))
+	for i, m := range bodyMsgs {
+		if m.Operation == 0 {
+			m.Operation = define.OP_SEND_SMS_REPLY
+		}
+		keys[i], vers[i], operations[i], msgs[i] = m.Key, m.Ver, m.Operation, m.Msg
+	}
+	return
+}
+
+// httpPushMsgs push multiple messages to a specified sub key.
+// POST /1/push/msgs?key=xxx, body [{"v":1,"o":5,"m":{"test":1}}].
+func httpPushMsgs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		bodyBytes []byte
+		body      string
+		err       error
+		arg       = &proto.PushMsgsArg{}
+		reply     = &proto.PushMsgsReply{}
+		res       = map[string]interface{}{"ret": OK}
+	)
+	defer retPWrite(w, r, res, &body, time.Now())
+	if bodyBytes, err = readPushBody(w, r); err != nil {
+		log.Error("readPushBody() error(%v)", err)
+		res["ret"] = ParamErr
+		return
+	}
+	body = string(bodyBytes)
+	if arg.Key = r.URL.Query().Get("key"); arg.Key == "" {
+		res["ret"] = ParamErr
+		return
+	}
+	if _, arg.Vers, arg.Operations, arg.Msgs, err = parsePushMsgs(bodyBytes); err != nil {
+		log.Error("parsePushMsgs(\"%s\") error(%v)", body, err)
+		res["ret"] = ParamErr
+		return
+	}
+	err = httpPushRPC.PushMsgs(arg, reply)
+	res["index"] = reply.Index
+	if err != nil {
+		log.Error("PushMsgs(\"%s\") error(%v)", arg.Key, err)
+		res["ret"] = InternalErr
+	}
+}
+
+// httpMPushMsgs push a message to each sub key.
+// POST /1/pushs/msgs, body [{"k":"1_1","v":1,"o":5,"m":{"test":1}}].
+func httpMPushMsgs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		bodyBytes []byte
+		body      string
+		err       error
+		arg       = &proto.MPushMsgsArg{}
+		reply     = &proto.MPushMsgsReply{}
+		res       = map[string]interface{}{"ret": OK}
+	)
+	defer retPWrite(w, r, res, &body, time.Now())
+	if bodyBytes, err = readPushBody(w, r); err != nil {
+		log.Error("readPushBody() error(%v)", err)
+		res["ret"] = ParamErr
+		return
+	}
+	body = string(bodyBytes)
+	if arg.Keys, arg.Vers, arg.Operations, arg.Msgs, err = parsePushMsgs(bodyBytes); err != nil {
+		log.Error("parsePushMsgs(\"%s\") error(%v)", body, err)
+		res["ret"] = ParamErr
+		return
+	}
+	err = httpPushRPC.MPushMsgs(arg, reply)
+	res["index"] = reply.Index
+	if err != nil {
+		log.Error("MPushMsgs() error(%v)", err)
+		res["ret"] = InternalErr
+	}
+}
+
+// httpPushAll broadcast a message to all the channels of the app in the comet.
+// POST /1/push/all?ver=1&operation=5&appid=1, body is the message, appid is
+// optional, default 0.
+func httpPushAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		bodyBytes []byte
+		body      string
+		appId     int64
+		err       error
+		arg       = &proto.BoardcastArg{}
+		res       = map[string]interface{}{"ret": OK}
+	)
+	defer retPWrite(w, r, res, &body, time.Now())
+	if bodyBytes, err = readPushBody(w, r); err != nil {
+		log.Error("readPushBody() error(%v)", err)
+		res["ret"] = ParamErr
+		return
+	}
+	body = string(bodyBytes)
+	if arg.Ver, arg.Operation, _, err = parsePushParams(r); err != nil {
+		log.Error("parsePushParams(\"%s\") error(%v)", r.URL.String(), err)
+		res["ret"] = ParamErr
+		return
+	}
+	if appIdStr := r.URL.Query().Get("appid"); appIdStr != "" {
+		if appId, err = strconv.ParseInt(appIdStr, 10, 32); err != nil {
+			log.Error("strconv.ParseInt(\"%s\") error(%v)", appIdStr, err)
+			res["ret"] = ParamErr
+			return
+		}
+	}
+	arg.AppId = int32(appId)
+	arg.Msg = bodyBytes
+	if err = httpPushRPC.Broadcast(arg, &proto.NoReply{}); err != nil {
+		log.Error("Broadcast() error(%v)", err)
+		res["ret"] = InternalErr
+	}
+}
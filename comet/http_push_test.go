@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/Terry-Mao/goim/define"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParsePushParams(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/1/push?key=1_1", nil)
+	ver, op, pri, err := parsePushParams(r)
+	if err != nil || ver != 0 || op != define.OP_SEND_SMS_REPLY || pri != define.PRIORITY_NORMAL {
+		t.Errorf("parsePushParams() ver: %d, op: %d, priority: %d, error(%v)", ver, op, pri, err)
+		t.FailNow()
+	}
+	r, _ = http.NewRequest("POST", "/1/push?key=1_1&ver=1&operation=8&priority=1", nil)
+	ver, op, pri, err = parsePushParams(r)
+	if err != nil || ver != 1 || op != 8 || pri != define.PRIORITY_HIGH {
+		t.Errorf("parsePushParams() ver: %d, op: %d, priority: %d, error(%v)", ver, op, pri, err)
+		t.FailNow()
+	}
+	r, _ = http.NewRequest("POST", "/1/push?operation=x", nil)
+	if _, _, _, err = parsePushParams(r); err == nil {
+		t.Error("parsePushParams() expect error")
+		t.FailNow()
+	}
+}
+
+func TestParsePushMsgs(t *testing.T) {
+	keys, vers, ops, msgs, err := parsePushMsgs([]byte(`[{"k":"1_1","v":1,"o":8,"m":{"test":1}},{"k":"2_1","m":"test"}]`))
+	if err != nil || len(keys) != 2 || keys[1] != "2_1" || vers[0] != 1 || ops[0] != 8 || ops[1] != define.OP_SEND_SMS_REPLY || string(msgs[0]) != `{"test":1}` || string(msgs[1]) != `"test"` {
+		t.Fatalf("parsePushMsgs() keys: %v, vers: %v, ops: %v, msgs: %s, error(%v)", keys, vers, ops, msgs, err)
+	}
+	if _, _, _, _, err = parsePushMsgs([]byte(`[]`)); err == nil {
+		t.Fatal("parsePushMsgs() expect error")
+	}
+}
+
+func TestReadPushBody(t *testing.T) {
+	Conf = NewConfig()
+	Conf.HTTPPushBodyMax = 4
+	r, _ := http.NewRequest("POST", "/1/push?key=1_1", strings.NewReader("test"))
+	if body, err := readPushBody(httptest.NewRecorder(), r); err != nil || string(body) != "test" {
+		t.Fatalf("readPushBody() body: %s, error(%v)", body, err)
+	}
+	r, _ = http.NewRequest("POST", "/1/push?key=1_1", strings.NewReader("test1"))
+	if _, err := readPushBody(httptest.NewRecorder(), r); err == nil {
+		t.Fatal("readPushBody() expect error")
+	}
+}
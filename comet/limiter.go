@@ -0,0 +1,307 @@
+package main
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// limit action
+	limitActionDrop       = "drop"
+	limitActionReply      = "reply"
+	limitActionDisconnect = "disconnect"
+	// http limiter
+	httpLimiterIdle  = 1 * time.Minute
+	httpLimiterSweep = 1 * time.Minute
+)
+
+// LimitRule is the token bucket config of a client operation.
+type LimitRule struct {
+	Rate  float64 // tokens per second
+	Burst float64 // bucket capacity
+}
+
+// parseLimitRule parse the "rate,burst" config value.
+func parseLimitRule(s string) (rule *LimitRule, err error) {
+	var (
+		idx         int
+		rate, burst float64
+	)
+	if idx = strings.IndexByte(s, ','); idx == -1 {
+		err = ErrLimitRule
+		return
+	}
+	if rate, err = strconv.ParseFloat(strings.TrimSpace(s[:idx]), 64); err != nil {
+		return
+	}
+	if burst, err = strconv.ParseFloat(strings.TrimSpace(s[idx+1:]), 64); err != nil {
+		return
+	}
+	if rate <= 0 || burst < 1 {
+		err = ErrLimitRule
+		return
+	}
+	rule = &LimitRule{Rate: rate, Burst: burst}
+	return
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refill the bucket lazily and take a token, false if it's empty.
+func (b *tokenBucket) take(rule *LimitRule, now time.Time) bool {
+	if b.tokens += now.Sub(b.last).Seconds() * rule.Rate; b.tokens > rule.Burst {
+		b.tokens = rule.Burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Limiter is the per connection client operation limiter, every limited
+// operation has it's own token bucket which refilled lazily.
+// Limiter is not goroutine safe, only the dispatch goroutine use it.
+type Limiter struct {
+	rules   map[int32]*LimitRule
+	buckets map[int32]*tokenBucket
+}
+
+// NewLimiter new a limiter with the operation rules.
+func NewLimiter(rules map[int32]*LimitRule) *Limiter {
+	l := new(Limiter)
+	l.rules = rules
+	return l
+}
+
+// Allow take a token of the operation, return false if the bucket is empty.
+func (l *Limiter) Allow(operation int32) bool {
+	var (
+		ok   bool
+		now  time.Time
+		rule *LimitRule
+		b    *tokenBucket
+	)
+	if rule, ok = l.rules[operation]; !ok {
+		return true
+	}
+	now = time.Now()
+	if l.buckets == nil {
+		l.buckets = make(map[int32]*tokenBucket, len(l.rules))
+	}
+	if b, ok = l.buckets[operation]; !ok {
+		b = &tokenBucket{tokens: rule.Burst, last: now}
+		l.buckets[operation] = b
+	}
+	return b.take(rule, now)
+}
+
+// idle check no bucket of the limiter is taken in httpLimiterIdle.
+func (l *Limiter) idle(now time.Time) bool {
+	for _, b := range l.buckets {
+		if now.Sub(b.last) < httpLimiterIdle {
+			return false
+		}
+	}
+	return true
+}
+
+// IPLimiter is the limiter of http long polling, a http client create a new
+// connection for every poll, so the limiter is kept by remote ip.
+type IPLimiter struct {
+	lock     sync.Mutex
+	rules    map[int32]*LimitRule
+	limiters map[string]*Limiter
+}
+
+// NewIPLimiter new a ip limiter with the operation rules and start sweep the
+// idle limiters.
+func NewIPLimiter(rules map[int32]*LimitRule) *IPLimiter {
+	l := new(IPLimiter)
+	l.rules = rules
+	l.limiters = make(map[string]*Limiter)
+	go l.sweep()
+	return l
+}
+
+// Allow take a token of the operation for the ip.
+func (l *IPLimiter) Allow(ip string, operation int32) (ok bool) {
+	l.lock.Lock()
+	if _, ok = l.rules[operation]; !ok {
+		l.lock.Unlock()
+		return true
+	}
+	lim, has := l.limiters[ip]
+	if !has {
+		lim = NewLimiter(l.rules)
+		l.limiters[ip] = lim
+	}
+	ok = lim.Allow(operation)
+	l.lock.Unlock()
+	return
+}
+
+// Reload replace the operation rules, the limiters of the ips restart.
+func (l *IPLimiter) Reload(rules map[int32]*LimitRule) {
+	l.lock.Lock()
+	l.rules = rules
+	l.limiters = make(map[string]*Limiter)
+	l.lock.Unlock()
+}
+
+func (l *IPLimiter) sweep() {
+	var (
+		ip  string
+		now time.Time
+		lim *Limiter
+	)
+	for {
+		time.Sleep(httpLimiterSweep)
+		now = time.Now()
+		l.lock.Lock()
+		for ip, lim = range l.limiters {
+			if lim.idle(now) {
+				delete(l.limiters, ip)
+			}
+		}
+		l.lock.Unlock()
+	}
+}
+
+type ipConn struct {
+	conns  int
+	bucket *tokenBucket
+}
+
+// ConnLimiter limit the concurrent connections of the node and every remote
+// ip, and the accept rate of every remote ip.
+type ConnLimiter struct {
+	lock  sync.Mutex
+	max   int        // 0 is unlimited
+	ipMax int        // 0 is unlimited
+	rate  *LimitRule // nil is unlimited
+	conns int
+	ips   map[string]*ipConn
+}
+
+var (
+	connLimiter *ConnLimiter
+)
+
+// addrIP return the ip of the "ip:port" address.
+func addrIP(addr string) string {
+	if ip, _, err := net.SplitHostPort(addr); err == nil {
+		return ip
+	}
+	return addr
+}
+
+// InitConnLimiter init the connection limiter shared by all the listeners.
+func InitConnLimiter() {
+	connLimiter = NewConnLimiter(Conf.LimitConn, Conf.LimitConnIP, Conf.LimitConnIPRule)
+}
+
+// NewConnLimiter new a connection limiter and start sweep the idle ips.
+func NewConnLimiter(max, ipMax int, rate *LimitRule) *ConnLimiter {
+	l := &ConnLimiter{max: max, ipMax: ipMax, rate: rate, ips: make(map[string]*ipConn)}
+	go l.sweep()
+	return l
+}
+
+// Reload replace the limits, the connections are kept and the accept rate
+// of the ips restart.
+func (l *ConnLimiter) Reload(max, ipMax int, rate *LimitRule) {
+	now := time.Now()
+	l.lock.Lock()
+	l.max = max
+	l.ipMax = ipMax
+	l.rate = rate
+	for ip, c := range l.ips {
+		if c.conns == 0 {
+			delete(l.ips, ip)
+			continue
+		}
+		c.bucket = nil
+		if rate != nil {
+			c.bucket = &tokenBucket{tokens: rate.Burst, last: now}
+		}
+	}
+	l.lock.Unlock()
+}
+
+// Acquire take a connection of the ip, the connection must be released
+// after closed if no error returned.
+func (l *ConnLimiter) Acquire(ip string) (err error) {
+	var (
+		ok  bool
+		c   *ipConn
+		now = time.Now()
+	)
+	l.lock.Lock()
+	if c, ok = l.ips[ip]; !ok {
+		c = new(ipConn)
+		if l.rate != nil {
+			c.bucket = &tokenBucket{tokens: l.rate.Burst, last: now}
+		}
+	}
+	if l.max > 0 && l.conns >= l.max {
+		err = ErrConnLimit
+	} else if l.ipMax > 0 && c.conns >= l.ipMax {
+		err = ErrConnIPLimit
+	} else if c.bucket != nil && !c.bucket.take(l.rate, now) {
+		err = ErrConnIPRate
+	} else {
+		c.conns++
+		l.conns++
+	}
+	if !ok && (c.conns > 0 || c.bucket != nil) {
+		l.ips[ip] = c
+	}
+	l.lock.Unlock()
+	DefaultStat.IncrConnReject(err)
+	return
+}
+
+// Release release a connection of the ip.
+func (l *ConnLimiter) Release(ip string) {
+	l.lock.Lock()
+	if c, ok := l.ips[ip]; ok {
+		c.conns--
+		l.conns--
+		// the bucket kept until idle
+		if c.conns == 0 && c.bucket == nil {
+			delete(l.ips, ip)
+		}
+	}
+	l.lock.Unlock()
+}
+
+// Conns return the current connections.
+func (l *ConnLimiter) Conns() int {
+	l.lock.Lock()
+	n := l.conns
+	l.lock.Unlock()
+	return n
+}
+
+func (l *ConnLimiter) sweep() {
+	var now time.Time
+	for {
+		time.Sleep(httpLimiterSweep)
+		now = time.Now()
+		l.lock.Lock()
+		for ip, c := range l.ips {
+			if c.conns == 0 && (c.bucket == nil || now.Sub(c.bucket.last) >= httpLimiterIdle) {
+				delete(l.ips, ip)
+			}
+		}
+		l.lock.Unlock()
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(map[int32]*LimitRule{1: {Rate: 10, Burst: 2}})
+	// not limited operation
+	for i := 0; i < 10; i++ {
+		if !l.Allow(2) {
+			t.FailNow()
+		}
+	}
+	if !l.Allow(1) || !l.Allow(1) {
+		t.FailNow()
+	}
+	if l.Allow(1) {
+		t.FailNow()
+	}
+	time.Sleep(150 * time.Millisecond)
+	if !l.Allow(1) {
+		t.FailNow()
+	}
+	if l.Allow(1) {
+		t.FailNow()
+	}
+}
+
+func TestParseLimitRule(t *testing.T) {
+	rule, err := parseLimitRule("10, 20")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if rule.Rate != 10 || rule.Burst != 20 {
+		t.FailNow()
+	}
+	if _, err = parseLimitRule("10"); err != ErrLimitRule {
+		t.FailNow()
+	}
+	if _, err = parseLimitRule("0,1"); err != ErrLimitRule {
+		t.FailNow()
+	}
+}
+
+func TestConnLimiter(t *testing.T) {
+	l := NewConnLimiter(3, 2, &LimitRule{Rate: 0.001, Burst: 3})
+	if err := l.Acquire("1.1.1.1"); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if err := l.Acquire("1.1.1.1"); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// per ip max connections
+	if err := l.Acquire("1.1.1.1"); err != ErrConnIPLimit {
+		t.Errorf("error(%v)", err)
+		t.FailNow()
+	}
+	if err := l.Acquire("2.2.2.2"); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	// global max connections
+	if err := l.Acquire("3.3.3.3"); err != ErrConnLimit {
+		t.Errorf("error(%v)", err)
+		t.FailNow()
+	}
+	l.Release("1.1.1.1")
+	if l.Conns() != 2 {
+		t.FailNow()
+	}
+	if err := l.Acquire("1.1.1.1"); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	l.Release("1.1.1.1")
+	// per ip accept rate, burst 3 taken
+	if err := l.Acquire("1.1.1.1"); err != ErrConnIPRate {
+		t.Errorf("error(%v)", err)
+		t.FailNow()
+	}
+	if DefaultStat.ConnReject != 1 || DefaultStat.ConnIPReject != 1 || DefaultStat.ConnIPRateReject != 1 {
+		t.Errorf("stat: %v", DefaultStat)
+	}
+}
+
+func TestLimiterReload(t *testing.T) {
+	l := NewIPLimiter(map[int32]*LimitRule{4: &LimitRule{Rate: 0.001, Burst: 1}})
+	if !l.Allow("1.1.1.1", 4) || l.Allow("1.1.1.1", 4) {
+		t.FailNow()
+	}
+	l.Reload(map[int32]*LimitRule{5: &LimitRule{Rate: 0.001, Burst: 1}})
+	if !l.Allow("1.1.1.1", 4) || !l.Allow("1.1.1.1", 4) {
+		t.Errorf("operation 4 limited after reload")
+	}
+	if !l.Allow("1.1.1.1", 5) || l.Allow("1.1.1.1", 5) {
+		t.Errorf("operation 5 not limited after reload")
+	}
+	c := NewConnLimiter(1, 0, nil)
+	if err := c.Acquire("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	c.Reload(2, 0, nil)
+	if err := c.Acquire("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Conns() != 2 {
+		t.Errorf("conns: %d", c.Conns())
+	}
+}
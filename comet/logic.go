@@ -10,10 +10,25 @@ import (
 var (
 	logicRpcClient *protorpc.Client
 	logicRpcQuit   = make(chan struct{}, 1)
+	logicCallDone  = make(chan *protorpc.Call, logicCallDoneSize)
 
 	logicService           = "RPC"
 	logicServiceConnect    = "RPC.Connect"
 	logicServiceDisconnect = "RPC.Disconnect"
+	logicServiceAck        = "RPC.Ack"
+	logicServiceHeartbeat  = "RPC.Heartbeat"
+	logicServiceRenew      = "RPC.Renew"
+	logicServiceOnline     = "RPC.Online"
+
+	// the logic purge the sessions if the comet restarted
+	cometStartTime = time.Now().UnixNano()
+	// the first heartbeat purge the sessions of the last run, even the logic
+	// restarted or never saw the last run
+	cometFirstBeat = true
+)
+
+const (
+	logicCallDoneSize = 1024
 )
 
 func InitLogicRpc(network, addr string) (err error) {
@@ -22,11 +37,12 @@ func InitLogicRpc(network, addr string) (err error) {
 		log.Error("rpc.Dial(\"%s\", \"%s\") error(%s)", network, addr, err)
 	}
 	go protorpc.Reconnect(&logicRpcClient, logicRpcQuit, network, addr)
+	go callProc()
 	log.Debug("logic rpc addr %s:%s connected", network, addr)
 	return
 }
 
-func connect(p *Proto) (key string, heartbeat time.Duration, err error) {
+func connect(p *Proto) (key string, appId int32, heartbeat time.Duration, err error) {
 	if logicRpcClient == nil {
 		err = ErrLogic
 		return
@@ -38,6 +54,7 @@ func connect(p *Proto) (key string, heartbeat time.Duration, err error) {
 		return
 	}
 	key = reply.Key
+	appId = reply.AppId
 	heartbeat = 5 * 60 * time.Second
 	return
 }
@@ -56,3 +73,102 @@ func disconnect(key string) (has bool, err error) {
 	has = reply.Has
 	return
 }
+
+// heartbeat report the comet alive to logic, kick all the clients if the
+// sessions of the comet were purged.
+func heartbeat() (err error) {
+	if logicRpcClient == nil {
+		err = ErrLogic
+		return
+	}
+	arg := &proto.HeartbeatArg{Server: Conf.ServerId, StartTime: cometStartTime, First: cometFirstBeat}
+	reply := &proto.HeartbeatReply{}
+	if err = logicRpcClient.Call(logicServiceHeartbeat, arg, reply); err != nil {
+		log.Error("c.Call(\"%s\", \"%v\", &ret) error(%v)", logicServiceHeartbeat, arg, err)
+		return
+	}
+	cometFirstBeat = false
+	if reply.Purged {
+		DefaultServer.Kick()
+	}
+	return
+}
+
+func heartbeatProc() {
+	for {
+		time.Sleep(Conf.LogicHeartbeat)
+		heartbeat()
+	}
+}
+
+// renew extend the router session leases of the keys, kick the clients of
+// the lost keys.
+func renew(keys []string) (err error) {
+	if logicRpcClient == nil {
+		err = ErrLogic
+		return
+	}
+	arg := &proto.RenewArg{Server: Conf.ServerId, Keys: keys}
+	reply := &proto.RenewReply{}
+	if err = logicRpcClient.Call(logicServiceRenew, arg, reply); err != nil {
+		log.Error("c.Call(\"%s\", %d keys, &ret) error(%v)", logicServiceRenew, len(keys), err)
+		return
+	}
+	if len(reply.Lost) > 0 {
+		DefaultServer.KickKeys(reply.Lost)
+	}
+	return
+}
+
+// renewProc renew the keys of all the buckets in batches of
+// Conf.LogicRenewBatch, the whole bucket if not set.
+func renewProc() {
+	var (
+		i, j int
+		keys []string
+		b    *Bucket
+	)
+	for {
+		time.Sleep(Conf.LogicRenew)
+		for _, b = range DefaultServer.Buckets {
+			keys = b.Keys()
+			for i = 0; i < len(keys); i = j {
+				if j = i + Conf.LogicRenewBatch; j > len(keys) || j <= i {
+					j = len(keys)
+				}
+				renew(keys[i:j])
+			}
+		}
+	}
+}
+
+// ack report the message id to logic asynchronously, don't block the
+// dispatch goroutine.
+func ack(key string, msgId int64) {
+	if logicRpcClient == nil {
+		log.Error("ack(\"%s\", %d) error(%v)", key, msgId, ErrLogic)
+		return
+	}
+	arg := &proto.AckArg{Key: key, MsgId: msgId}
+	logicRpcClient.Go(logicServiceAck, arg, &proto.AckReply{}, logicCallDone)
+}
+
+// online report the key registered asynchronously, the logic replay the
+// offline messages to it.
+func online(key string) {
+	if logicRpcClient == nil {
+		log.Error("online(\"%s\") error(%v)", key, ErrLogic)
+		return
+	}
+	arg := &proto.OnlineArg{Server: Conf.ServerId, Key: key}
+	logicRpcClient.Go(logicServiceOnline, arg, &proto.OnlineReply{}, logicCallDone)
+}
+
+// callProc log the failed asynchronous calls.
+func callProc() {
+	for call := range logicCallDone {
+		if call.Error != nil {
+			log.Error("c.Call(\"%s\", \"%v\") error(%v)", call.ServiceMethod, call.Args, call.Error)
+		}
+	}
+}
@@ -30,9 +30,19 @@ func main() {
 	for i := 0; i < Conf.Bucket; i++ {
 		buckets[i] = NewBucket(Conf.Channel, Conf.CliProto, Conf.SvrProto)
 	}
-	round := NewRound(Conf.ReadBuf, Conf.WriteBuf, Conf.Timer, Conf.TimerSize)
+	round := NewRound(Conf.ReadBuf, Conf.WriteBuf, Conf.Timer, Conf.TimerSize, Conf.TimerType)
 	operator := new(DefaultOperator)
 	DefaultServer = NewServer(buckets, round, operator)
+	if Conf.LogicHeartbeat > 0 {
+		// purge the sessions of the last run before accept
+		heartbeat()
+		go heartbeatProc()
+	}
+	if Conf.LogicRenew > 0 {
+		go renewProc()
+	}
+	InitConnLimiter()
+	InitStat()
 	if err := InitTCP(); err != nil {
 		panic(err)
 	}
@@ -42,10 +52,21 @@ func main() {
 	if err := InitHTTP(); err != nil {
 		panic(err)
 	}
+	if err := InitMQTT(); err != nil {
+		panic(err)
+	}
 	// start rpc
 	if err := InitRPCPush(); err != nil {
 		panic(err)
 	}
+	// start http push
+	if err := InitHTTPPush(); err != nil {
+		panic(err)
+	}
+	// register after the push rpc started
+	if err := InitRegistry(); err != nil {
+		panic(err)
+	}
 	// block until a signal is received.
 	InitSignal()
 }
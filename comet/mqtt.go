@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bufio"
+	log "code.google.com/p/log4go"
+	"github.com/Terry-Mao/goim/define"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// internal operation of the mqtt packets which handled by comet
+	mqttOpSubscribe   = int32(-1)
+	mqttOpUnsubscribe = int32(-2)
+)
+
+// InitMQTT listen all mqtt.bind and start accept connections.
+func InitMQTT() (err error) {
+	var (
+		listener *net.TCPListener
+		addr     *net.TCPAddr
+	)
+	for _, bind := range Conf.MQTTBind {
+		if addr, err = net.ResolveTCPAddr("tcp4", bind); err != nil {
+			log.Error("net.ResolveTCPAddr(\"tcp4\", \"%s\") error(%v)", bind, err)
+			return
+		}
+		if listener, err = net.ListenTCP("tcp4", addr); err != nil {
+			log.Error("net.ListenTCP(\"tcp4\", \"%s\") error(%v)", bind, err)
+			return
+		}
+		log.Debug("start mqtt listen: \"%s\"", bind)
+		// split N core accept
+		for i := 0; i < Conf.MaxProc; i++ {
+			go acceptMQTT(DefaultServer, listener)
+		}
+	}
+	return
+}
+
+// Accept accepts connections on the listener and serves requests
+// for each incoming connection.  Accept blocks; the caller typically
+// invokes it in a go statement.
+func acceptMQTT(server *Server, lis *net.TCPListener) {
+	var (
+		conn *net.TCPConn
+		err  error
+		ip   string
+		r    int
+	)
+	for {
+		if conn, err = lis.AcceptTCP(); err != nil {
+			// if listener close then return
+			log.Error("listener.Accept(\"%s\") error(%v)", lis.Addr().String(), err)
+			return
+		}
+		if err = conn.SetKeepAlive(Conf.TCPKeepalive); err != nil {
+			log.Error("conn.SetKeepAlive() error(%v)", err)
+			return
+		}
+		if err = conn.SetReadBuffer(Conf.TCPSndbuf); err != nil {
+			log.Error("conn.SetReadBuffer() error(%v)", err)
+			return
+		}
+		if err = conn.SetWriteBuffer(Conf.TCPRcvbuf); err != nil {
+			log.Error("conn.SetWriteBuffer() error(%v)", err)
+			return
+		}
+		// limit before the handshake timer armed
+		ip = addrIP(conn.RemoteAddr().String())
+		if err = connLimiter.Acquire(ip); err != nil {
+			log.Warn("connLimiter.Acquire(\"%s\") error(%v)", ip, err)
+			conn.Close()
+			continue
+		}
+		go serveMQTT(server, conn, r)
+		if r++; r == maxInt {
+			r = 0
+		}
+	}
+}
+
+func serveMQTT(server *Server, conn *net.TCPConn, r int) {
+	var (
+		// bufpool
+		rrp = server.round.Reader(r) // reader
+		wrp = server.round.Writer(r) // writer
+		// timer
+		tr = server.round.Timer(r)
+		// buf
+		rr = NewBufioReaderSize(rrp, conn, Conf.ReadBufSize)  // reader buf
+		wr = NewBufioWriterSize(wrp, conn, Conf.WriteBufSize) // writer buf
+		// ip addr
+		lAddr = conn.LocalAddr().String()
+		rAddr = conn.RemoteAddr().String()
+	)
+	log.Debug("start mqtt serve \"%s\" with \"%s\"", lAddr, rAddr)
+	server.serveMQTT(conn, rrp, wrp, rr, wr, tr)
+	connLimiter.Release(addrIP(rAddr))
+}
+
+func (server *Server) serveMQTT(conn *net.TCPConn, rrp, wrp *sync.Pool, rr *bufio.Reader, wr *bufio.Writer, tr Timer) {
+	var (
+		b   *Bucket
+		p   *Proto
+		hb  time.Duration // heartbeat
+		key string
+		err error
+		trd *TimerData
+		pk  = new(mqttPacket)
+		ch  = NewChannel(Conf.CliProto, Conf.SvrProto, Conf.SvrProtoHigh)
+	)
+	// auth
+	if trd, err = tr.Add(Conf.HandshakeTimeout, conn); err != nil {
+		log.Error("handshake: timer.Add() error(%v)", err)
+		goto failed
+	}
+	key, hb, err = server.authMQTT(rr, wr, pk, ch)
+	tr.Del(trd)
+	if err != nil {
+		log.Error("server.authMQTT() error(%v)", err)
+		goto failed
+	}
+	// register key->channel
+	b = server.Bucket(key)
+	b.Put(key, ch)
+	if err := server.operator.Online(key); err != nil {
+		log.Error("%s operator do online error(%v)", key, err)
+	}
+	// hanshake ok start dispatch goroutine
+	go server.dispatchMQTT(conn, wrp, wr, ch, hb, tr)
+	for {
+		// fetch a proto from channel free list
+		if p, err = ch.CliProto.Set(); err != nil {
+			log.Error("%s fetch client proto error(%v)", key, err)
+			goto failed
+		}
+		// parse request packet
+		if err = server.readMQTTRequest(rr, pk, p); err != nil {
+			if err != ErrMQTTDisconnect {
+				log.Error("%s read client request error(%v)", key, err)
+			}
+			goto failed
+		}
+		// send to writer
+		ch.CliProto.SetAdv()
+		ch.Signal()
+	}
+failed:
+	// dialog finish
+	// may call twice
+	if err = conn.Close(); err != nil {
+		log.Error("reader: conn.Close() error(%v)", err)
+	}
+	PutBufioReader(rrp, rr)
+	if b != nil {
+		b.Del(key)
+		log.Debug("wake up dispatch goroutine")
+		ch.Finish()
+	}
+	if err = server.operator.Disconnect(key); err != nil {
+		log.Error("%s operator do disconnect error(%v)", key, err)
+	}
+	log.Debug("%s serverconn goroutine exit", key)
+	return
+}
+
+// dispatch accepts connections on the listener and serves requests
+// for each incoming connection.  dispatch blocks; the caller typically
+// invokes it in a go statement.
+func (server *Server) dispatchMQTT(conn *net.TCPConn, wrp *sync.Pool, wr *bufio.Writer, ch *Channel, hb time.Duration, tr Timer) {
+	var (
+		p    *Proto
+		err  error
+		trd  *TimerData
+		r    *Ring
+		subs []string // subscribed topic filters
+		lim  = NewLimiter(Conf.LimitOps)
+	)
+	log.Debug("start dispatch goroutine")
+	if trd, err = tr.Add(hb, conn); err != nil {
+		log.Error("dispatch: timer.Add() error(%v)", err)
+		goto failed
+	}
+	for {
+		if !ch.Ready() {
+			goto failed
+		}
+		// fetch message from clibox(client send)
+		for {
+			if p, err = ch.CliProto.Get(); err != nil {
+				break
+			}
+			// every packet received keep the client alive
+			if !trd.Lazy(hb) {
+				tr.Del(trd)
+				if trd, err = tr.Add(hb, conn); err != nil {
+					log.Error("dispatch: timer.Add() error(%v)", err)
+					goto failed
+				}
+			}
+			if !lim.Allow(p.Operation) {
+				log.Warn("operation: %d rate limited, action: %s", p.Operation, Conf.LimitAction)
+				if Conf.LimitAction == limitActionDisconnect {
+					err = ErrRateLimit
+					goto failed
+				}
+				// mqtt has no error reply, the reply action drops it too
+				p.Reset()
+				ch.CliProto.GetAdv()
+				continue
+			} else if p.Operation == define.OP_HEARTBEAT {
+				// PINGREQ
+				p.Body = nil
+				p.Operation = define.OP_HEARTBEAT_REPLY
+			} else if p.Operation == mqttOpSubscribe || p.Operation == mqttOpUnsubscribe {
+				if subs, err = server.subscribeMQTT(wr, subs, p); err != nil {
+					log.Error("server.subscribeMQTT() error(%v)", err)
+					goto failed
+				}
+				ch.CliProto.GetAdv()
+				continue
+			} else {
+				// PUBLISH, process message
+				if err = server.operator.Operate(p); err != nil {
+					log.Error("operator.Operate() error(%v)", err)
+					goto failed
+				}
+			}
+			if err = server.writeMQTTResponse(wr, p); err != nil {
+				log.Error("server.writeMQTTResponse() error(%v)", err)
+				goto failed
+			}
+			ch.CliProto.GetAdv()
+		}
+		// fetch message from svrbox(server send), high priority first
+		for {
+			if p, r, err = ch.SvrProtoGet(); err != nil {
+				log.Warn("ch.SvrProtoGet() error(%v)", err)
+				break
+			}
+			// publish the message to the subscribed client
+			if err = server.writeMQTTPublish(wr, subs, p); err != nil {
+				log.Error("server.writeMQTTPublish() error(%v)", err)
+				goto failed
+			}
+			r.GetAdv()
+		}
+		// kicked by the server after the error reply written
+		if ch.Closing() {
+			goto failed
+		}
+	}
+failed:
+	// wake reader up
+	if err = conn.Close(); err != nil {
+		log.Warn("conn.Close() error(%v)", err)
+	}
+	// deltimer
+	tr.Del(trd)
+	PutBufioWriter(wrp, wr)
+	log.Debug("dispatch goroutine exit")
+	return
+}
+
+// auth for mqtt CONNECT, the password is the token.
+func (server *Server) authMQTT(rr *bufio.Reader, wr *bufio.Writer, pk *mqttPacket, ch *Channel) (subKey string, heartbeat time.Duration, err error) {
+	var (
+		p    *Proto
+		code byte
+		c    = new(mqttConnectPacket)
+	)
+	// WARN
+	// don't adv the cli proto, after auth simply discard it.
+	if p, err = ch.CliProto.Set(); err != nil {
+		return
+	}
+	if err = readMQTTPacket(rr, pk); err != nil {
+		return
+	}
+	if pk.Type != mqttConnect {
+		log.Warn("auth packet type not valid: %d", pk.Type)
+		err = ErrOperation
+		return
+	}
+	if code, err = parseMQTTConnect(pk.Body, c); err == nil {
+		p.Operation = define.OP_AUTH
+		p.Body = c.Password
+		if server.Draining() {
+			err = ErrDraining
+			code = mqttConnUnavailable
+		} else if subKey, ch.AppId, heartbeat, err = server.operator.Connect(p); err != nil {
+			log.Error("operator.Connect error(%v)", err)
+			code = mqttConnNotAuthorized
+		} else if c.KeepAlive > 0 {
+			// the client keep alive with the grace period in the spec, 0
+			// disable it and the server heartbeat is used
+			heartbeat = mqttHeartbeat(c.KeepAlive)
+		}
+	}
+	p.Reset()
+	// CONNACK, session present is always 0
+	if werr := writeMQTTPacket(wr, mqttConnack, 0, []byte{0, code}); werr != nil {
+		log.Error("[%s] writeMQTTPacket() error(%v)", subKey, werr)
+		if err == nil {
+			err = werr
+		}
+		return
+	}
+	if werr := wr.Flush(); werr != nil && err == nil {
+		err = werr
+	}
+	return
+}
+
+// mqttHeartbeat return the heartbeat of the client keep alive seconds, one
+// and a half times of it as the spec.
+func mqttHeartbeat(keepAlive uint16) time.Duration {
+	return time.Duration(keepAlive) * time.Second * 3 / 2
+}
+
+// readMQTTRequest read a mqtt packet and convert to the proto.
+// PINGREQ     -> OP_HEARTBEAT
+// PUBLISH     -> OP_SEND_SMS, seq is the packet id(qos 1) and body is the payload
+// SUBSCRIBE   -> mqttOpSubscribe, seq is the packet id and body is the topics
+// UNSUBSCRIBE -> mqttOpUnsubscribe, seq is the packet id and body is the topics
+func (server *Server) readMQTTRequest(rr *bufio.Reader, pk *mqttPacket, proto *Proto) (err error) {
+	var (
+		off   int
+		id    uint16
+		qos   byte
+		topic []byte
+	)
+	if err = readMQTTPacket(rr, pk); err != nil {
+		return
+	}
+	log.Debug("read mqtt packet type: %d", pk.Type)
+	switch pk.Type {
+	case mqttPingreq:
+		proto.Operation = define.OP_HEARTBEAT
+		proto.SeqId = 0
+		proto.Body = nil
+	case mqttPublish:
+		if qos = (pk.Flags >> 1) & 0x03; qos > 1 {
+			return ErrMQTTQos
+		}
+		if topic, off, err = mqttBytes(pk.Body, 0); err != nil {
+			return
+		}
+		if qos == 1 {
+			if id, off, err = mqttUint16(pk.Body, off); err != nil {
+				return
+			}
+		}
+		log.Debug("mqtt publish topic: %s", topic)
+		proto.Operation = define.OP_SEND_SMS
+		proto.SeqId = int32(id)
+		proto.Body = pk.Body[off:]
+	case mqttSubscribe, mqttUnsubscribe:
+		if id, off, err = mqttUint16(pk.Body, 0); err != nil {
+			return
+		}
+		if pk.Type == mqttSubscribe {
+			proto.Operation = mqttOpSubscribe
+		} else {
+			proto.Operation = mqttOpUnsubscribe
+		}
+		proto.SeqId = int32(id)
+		proto.Body = pk.Body[off:]
+	case mqttDisconnect:
+		return ErrMQTTDisconnect
+	default:
+		log.Warn("mqtt packet type not valid: %d", pk.Type)
+		return ErrMQTTPacket
+	}
+	return
+}
+
+// subscribeMQTT update the subscribed topic filters and reply SUBACK or
+// UNSUBACK, only qos 0 is granted.
+func (server *Server) subscribeMQTT(wr *bufio.Writer, subs []string, proto *Proto) (nsubs []string, err error) {
+	var (
+		i, j   int
+		topics []string
+		codes  []byte
+		id     = uint16(proto.SeqId)
+	)
+	nsubs = subs
+	if topics, err = parseMQTTTopics(proto.Body, proto.Operation == mqttOpSubscribe); err != nil {
+		return
+	}
+	if proto.Operation == mqttOpSubscribe {
+		codes = make([]byte, len(topics))
+		for i = 0; i < len(topics); i++ {
+			if !validMQTTFilter(topics[i]) {
+				codes[i] = mqttSubackFailure
+				continue
+			}
+			codes[i] = mqttSubackQos0
+			for j = 0; j < len(nsubs); j++ {
+				if nsubs[j] == topics[i] {
+					break
+				}
+			}
+			if j == len(nsubs) {
+				nsubs = append(nsubs, topics[i])
+			}
+		}
+		err = writeMQTTPacket(wr, mqttSuback, 0, mqttPacketId(id), codes)
+	} else {
+		for i = 0; i < len(topics); i++ {
+			for j = 0; j < len(nsubs); j++ {
+				if nsubs[j] == topics[i] {
+					nsubs = append(nsubs[:j], nsubs[j+1:]...)
+					break
+				}
+			}
+		}
+		err = writeMQTTPacket(wr, mqttUnsuback, 0, mqttPacketId(id))
+	}
+	if err != nil {
+		return
+	}
+	err = wr.Flush()
+	proto.Reset()
+	return
+}
+
+// writeMQTTResponse reply the client request, PINGRESP for heartbeat and
+// PUBACK for qos 1 PUBLISH.
+func (server *Server) writeMQTTResponse(wr *bufio.Writer, proto *Proto) (err error) {
+	if proto.Operation == define.OP_HEARTBEAT_REPLY {
+		err = writeMQTTPacket(wr, mqttPingresp, 0)
+	} else if proto.SeqId != 0 {
+		err = writeMQTTPacket(wr, mqttPuback, 0, mqttPacketId(uint16(proto.SeqId)))
+	} else {
+		// qos 0 PUBLISH no reply
+		proto.Reset()
+		return
+	}
+	if err != nil {
+		return
+	}
+	if err = wr.Flush(); err != nil {
+		log.Error("mqtt wr.Flush() error(%v)", err)
+	}
+	proto.Reset()
+	return
+}
+
+// writeMQTTPublish publish the server message with qos 0, the topic is
+// "mqtt.topic/operation", discard it if the client not subscribe.
+func (server *Server) writeMQTTPublish(wr *bufio.Writer, subs []string, proto *Proto) (err error) {
+	var (
+		ok    bool
+		topic = Conf.MQTTTopic + "/" + strconv.FormatInt(int64(proto.Operation), 10)
+	)
+	for _, sub := range subs {
+		if ok = matchMQTTTopic(sub, topic); ok {
+			break
+		}
+	}
+	if ok {
+		if err = writeMQTTPacket(wr, mqttPublish, 0, mqttString(topic), proto.Body); err != nil {
+			return
+		}
+		if err = wr.Flush(); err != nil {
+			log.Error("mqtt wr.Flush() error(%v)", err)
+		}
+	}
+	proto.Reset()
+	return
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+)
+
+// mqtt 3.1.1 control packet type
+const (
+	mqttConnect     = byte(1)
+	mqttConnack     = byte(2)
+	mqttPublish     = byte(3)
+	mqttPuback      = byte(4)
+	mqttSubscribe   = byte(8)
+	mqttSuback      = byte(9)
+	mqttUnsubscribe = byte(10)
+	mqttUnsuback    = byte(11)
+	mqttPingreq     = byte(12)
+	mqttPingresp    = byte(13)
+	mqttDisconnect  = byte(14)
+)
+
+const (
+	mqttProtoName  = "MQTT"
+	mqttProtoLevel = byte(4)
+	// connect flags
+	mqttFlagUsername = byte(0x80)
+	mqttFlagPassword = byte(0x40)
+	mqttFlagWill     = byte(0x04)
+	// connack return code
+	mqttConnAccepted      = byte(0)
+	mqttConnBadProto      = byte(1)
+	mqttConnUnavailable   = byte(3)
+	mqttConnNotAuthorized = byte(5)
+	// suback return code
+	mqttSubackQos0    = byte(0)
+	mqttSubackFailure = byte(0x80)
+	// remaining length
+	mqttMaxLenSize = 4
+)
+
+// mqttPacket is a mqtt control packet, body is the variable header and the
+// payload.
+type mqttPacket struct {
+	Type  byte
+	Flags byte
+	Body  []byte
+}
+
+// mqttConnectPacket is the parsed CONNECT packet.
+type mqttConnectPacket struct {
+	ClientId  string
+	Username  string
+	Password  []byte
+	KeepAlive uint16
+}
+
+// readMQTTPacket read a mqtt control packet.
+func readMQTTPacket(rr *bufio.Reader, pk *mqttPacket) (err error) {
+	var (
+		b       byte
+		i       int
+		bodyLen int
+	)
+	if b, err = rr.ReadByte(); err != nil {
+		return
+	}
+	pk.Type = b >> 4
+	pk.Flags = b & 0x0f
+	// remaining length, at most 4 bytes
+	for i = 0; i < mqttMaxLenSize; i++ {
+		if b, err = rr.ReadByte(); err != nil {
+			return
+		}
+		bodyLen |= int(b&0x7f) << uint(7*i)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if i == mqttMaxLenSize {
+		return ErrMQTTPacket
+	}
+	if bodyLen > maxPackLen {
+		return ErrProtoPackLen
+	}
+	if bodyLen > 0 {
+		pk.Body = make([]byte, bodyLen)
+		err = ReadAll(rr, pk.Body)
+	} else {
+		pk.Body = nil
+	}
+	return
+}
+
+// writeMQTTPacket write a mqtt control packet, the caller flush the writer.
+func writeMQTTPacket(wr *bufio.Writer, typ, flags byte, body ...[]byte) (err error) {
+	var (
+		b       byte
+		bodyLen int
+	)
+	for _, bs := range body {
+		bodyLen += len(bs)
+	}
+	if err = wr.WriteByte(typ<<4 | flags); err != nil {
+		return
+	}
+	for {
+		b = byte(bodyLen & 0x7f)
+		if bodyLen >>= 7; bodyLen > 0 {
+			b |= 0x80
+		}
+		if err = wr.WriteByte(b); err != nil {
+			return
+		}
+		if bodyLen == 0 {
+			break
+		}
+	}
+	for _, bs := range body {
+		if _, err = wr.Write(bs); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// mqttUint16 read a two bytes integer at the offset.
+func mqttUint16(b []byte, off int) (n uint16, next int, err error) {
+	if off+2 > len(b) {
+		err = ErrMQTTPacket
+		return
+	}
+	n = uint16(b[off])<<8 | uint16(b[off+1])
+	next = off + 2
+	return
+}
+
+// mqttBytes read a two bytes length prefixed field at the offset.
+func mqttBytes(b []byte, off int) (bs []byte, next int, err error) {
+	var n uint16
+	if n, off, err = mqttUint16(b, off); err != nil {
+		return
+	}
+	if off+int(n) > len(b) {
+		err = ErrMQTTPacket
+		return
+	}
+	bs = b[off : off+int(n)]
+	next = off + int(n)
+	return
+}
+
+// mqttString encode a two bytes length prefixed string.
+func mqttString(s string) []byte {
+	b := make([]byte, 2+len(s))
+	b[0] = byte(len(s) >> 8)
+	b[1] = byte(len(s))
+	copy(b[2:], s)
+	return b
+}
+
+// mqttPacketId encode a packet identifier.
+func mqttPacketId(id uint16) []byte {
+	return []byte{byte(id >> 8), byte(id)}
+}
+
+// parseMQTTConnect parse the CONNECT packet body, the will topic and will
+// message are skipped.
+func parseMQTTConnect(b []byte, c *mqttConnectPacket) (code byte, err error) {
+	var (
+		off   int
+		flags byte
+		bs    []byte
+	)
+	code = mqttConnBadProto
+	if bs, off, err = mqttBytes(b, off); err != nil {
+		return
+	}
+	if string(bs) != mqttProtoName || off+2 > len(b) || b[off] != mqttProtoLevel {
+		err = ErrMQTTProto
+		return
+	}
+	flags = b[off+1]
+	if c.KeepAlive, off, err = mqttUint16(b, off+2); err != nil {
+		return
+	}
+	if bs, off, err = mqttBytes(b, off); err != nil {
+		return
+	}
+	c.ClientId = string(bs)
+	if flags&mqttFlagWill != 0 {
+		// will topic and will message
+		if _, off, err = mqttBytes(b, off); err != nil {
+			return
+		}
+		if _, off, err = mqttBytes(b, off); err != nil {
+			return
+		}
+	}
+	if flags&mqttFlagUsername != 0 {
+		if bs, off, err = mqttBytes(b, off); err != nil {
+			return
+		}
+		c.Username = string(bs)
+	}
+	if flags&mqttFlagPassword != 0 {
+		if c.Password, off, err = mqttBytes(b, off); err != nil {
+			return
+		}
+	}
+	code = mqttConnAccepted
+	return
+}
+
+// parseMQTTTopics parse the topic filters of SUBSCRIBE(with qos) or
+// UNSUBSCRIBE(without qos) after the packet identifier.
+func parseMQTTTopics(b []byte, qos bool) (topics []string, err error) {
+	var (
+		off int
+		bs  []byte
+	)
+	for off < len(b) {
+		if bs, off, err = mqttBytes(b, off); err != nil {
+			return
+		}
+		if qos {
+			// only qos 0 is granted, ignore the requested qos
+			if off >= len(b) {
+				err = ErrMQTTPacket
+				return
+			}
+			off++
+		}
+		topics = append(topics, string(bs))
+	}
+	if len(topics) == 0 {
+		err = ErrMQTTPacket
+	}
+	return
+}
+
+// validMQTTFilter check the topic filter wildcards.
+func validMQTTFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+	levels := strings.Split(filter, "/")
+	for i, l := range levels {
+		if strings.Contains(l, "#") && (l != "#" || i != len(levels)-1) {
+			return false
+		}
+		if strings.Contains(l, "+") && l != "+" {
+			return false
+		}
+	}
+	return true
+}
+
+// matchMQTTTopic check the topic name match the topic filter.
+func matchMQTTTopic(filter, topic string) bool {
+	var (
+		fs = strings.Split(filter, "/")
+		ts = strings.Split(topic, "/")
+	)
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) {
+			return false
+		}
+		if f != "+" && f != ts[i] {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
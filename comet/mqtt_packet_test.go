@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestMQTTPacket(t *testing.T) {
+	var (
+		buf = new(bytes.Buffer)
+		wr  = bufio.NewWriter(buf)
+		pk  = new(mqttPacket)
+		// 200 bytes payload, 2 bytes remaining length
+		payload = bytes.Repeat([]byte{'a'}, 200)
+	)
+	if err := writeMQTTPacket(wr, mqttPublish, 0, mqttString("goim/push/5"), payload); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	wr.Flush()
+	if err := readMQTTPacket(bufio.NewReader(buf), pk); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	topic, off, err := mqttBytes(pk.Body, 0)
+	if err != nil || pk.Type != mqttPublish || string(topic) != "goim/push/5" || !bytes.Equal(pk.Body[off:], payload) {
+		t.Errorf("read packet type: %d, topic: %s, error(%v)", pk.Type, topic, err)
+		t.FailNow()
+	}
+}
+
+func TestParseMQTTConnect(t *testing.T) {
+	var (
+		c    = new(mqttConnectPacket)
+		body []byte
+	)
+	body = append(body, mqttString(mqttProtoName)...)
+	body = append(body, mqttProtoLevel, mqttFlagUsername|mqttFlagPassword, 0, 60)
+	body = append(body, mqttString("device1")...)
+	body = append(body, mqttString("user")...)
+	body = append(body, mqttString("token")...)
+	code, err := parseMQTTConnect(body, c)
+	if err != nil || code != mqttConnAccepted || c.ClientId != "device1" || c.Username != "user" || string(c.Password) != "token" || c.KeepAlive != 60 {
+		t.Errorf("parseMQTTConnect() code: %d, %v, error(%v)", code, c, err)
+		t.FailNow()
+	}
+	body[len(mqttString(mqttProtoName))] = 3
+	if code, err = parseMQTTConnect(body, c); err != ErrMQTTProto || code != mqttConnBadProto {
+		t.Errorf("parseMQTTConnect() code: %d, error(%v)", code, err)
+		t.FailNow()
+	}
+}
+
+func TestMatchMQTTTopic(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		match         bool
+	}{
+		{"goim/push/#", "goim/push/5", true},
+		{"goim/#", "goim/push/5", true},
+		{"goim/+/5", "goim/push/5", true},
+		{"goim/push/5", "goim/push/5", true},
+		{"goim/push/6", "goim/push/5", false},
+		{"goim/+", "goim/push/5", false},
+		{"goim/push/5/+", "goim/push/5", false},
+	}
+	for _, test := range tests {
+		if matchMQTTTopic(test.filter, test.topic) != test.match {
+			t.Errorf("matchMQTTTopic(\"%s\", \"%s\") != %v", test.filter, test.topic, test.match)
+		}
+	}
+	if validMQTTFilter("goim/#/5") || validMQTTFilter("goim/a+") || !validMQTTFilter("goim/+/#") {
+		t.Error("validMQTTFilter() failed")
+	}
+}
@@ -3,16 +3,23 @@ package main
 import (
 	log "code.google.com/p/log4go"
 	"github.com/Terry-Mao/goim/define"
+	"strconv"
 	"time"
 )
 
 type Operator interface {
 	// Operate process the common operation such as send message etc.
 	Operate(*Proto) error
-	// Connect used for auth user and return a sub key & hearbeat.
-	Connect(*Proto) (string, time.Duration, error)
+	// Connect used for auth user and return a sub key, the app of the user
+	// & hearbeat.
+	Connect(*Proto) (string, int32, time.Duration, error)
+	// Online report the subkey registered and ready for the pushed
+	// messages, the pushed before are dropped.
+	Online(string) error
 	// Disconnect used for revoke the subkey.
 	Disconnect(string) error
+	// Ack report the pushed message is received by the subkey.
+	Ack(string, *Proto) error
 }
 
 type DefaultOperator struct {
@@ -37,8 +44,13 @@ func (operator *DefaultOperator) Operate(p *Proto) error {
 	return nil
 }
 
-func (operator *DefaultOperator) Connect(p *Proto) (key string, heartbeat time.Duration, err error) {
-	key, heartbeat, err = connect(p)
+func (operator *DefaultOperator) Connect(p *Proto) (key string, appId int32, heartbeat time.Duration, err error) {
+	key, appId, heartbeat, err = connect(p)
+	return
+}
+
+func (operator *DefaultOperator) Online(key string) (err error) {
+	online(key)
 	return
 }
 
@@ -52,3 +64,18 @@ func (operator *DefaultOperator) Disconnect(key string) (err error) {
 	}
 	return
 }
+
+// Ack report the message id in the body to logic, the reply is sent even
+// if the body is invalid.
+func (operator *DefaultOperator) Ack(key string, p *Proto) (err error) {
+	var msgId int64
+	body := string(p.Body)
+	p.Body = nil
+	p.Operation = define.OP_ACK_REPLY
+	if msgId, err = strconv.ParseInt(body, 10, 64); err != nil {
+		log.Error("strconv.ParseInt(\"%s\") error(%v)", body, err)
+		return
+	}
+	ack(key, msgId)
+	return
+}
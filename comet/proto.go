@@ -9,6 +9,7 @@ const (
 	VerSize       = 2
 	OperationSize = 4
 	SeqIdSize     = 4
+	MsgIdSize     = 8
 )
 
 var (
@@ -22,11 +23,14 @@ var (
 // binary codec
 // websocket & http:
 // raw codec, with http header stored ver, operation, seqid
+// the pushed message with a message id, the tcp header is extended with the
+// message id after seqid, the client ack it by OP_ACK.
 type Proto struct {
-	Ver       int16           `json:"ver"`  // protocol version
-	Operation int32           `json:"op"`   // operation for request
-	SeqId     int32           `json:"seq"`  // sequence number chosen by client
-	Body      json.RawMessage `json:"body"` // binary body bytes(json.RawMessage is []byte)
+	Ver       int16           `json:"ver"`           // protocol version
+	Operation int32           `json:"op"`            // operation for request
+	SeqId     int32           `json:"seq"`           // sequence number chosen by client
+	MsgId     int64           `json:"mid,omitempty"` // message id assigned by logic
+	Body      json.RawMessage `json:"body"`          // binary body bytes(json.RawMessage is []byte)
 }
 
 func (p *Proto) Reset() {
@@ -34,5 +38,5 @@ func (p *Proto) Reset() {
 }
 
 func (p *Proto) String() string {
-	return fmt.Sprintf("\n-------- proto --------\nver: %d\nop: %d\nseq: %d\nbody: %s\n-----------------------", p.Ver, p.Operation, p.SeqId, string(p.Body))
+	return fmt.Sprintf("\n-------- proto --------\nver: %d\nop: %d\nseq: %d\nmid: %d\nbody: %s\n-----------------------", p.Ver, p.Operation, p.SeqId, p.MsgId, string(p.Body))
 }
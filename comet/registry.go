@@ -0,0 +1,52 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"github.com/Terry-Mao/goim/libs/registry"
+)
+
+var (
+	registryQuit chan struct{}
+	registryDone chan struct{}
+)
+
+// InitRegistry register the comet to the registry and renew it every third
+// of the ttl, the job and logic watch the registry for the comets.
+func InitRegistry() (err error) {
+	var (
+		r    registry.Registry
+		node = &registry.Node{ServerId: Conf.ServerId, Addr: Conf.RegistryAddr, Capacity: Conf.RegistryCapacity}
+	)
+	if Conf.RegistryType == "" {
+		return
+	}
+	if node.Addr == "" && len(Conf.RPCPushAddrs) > 0 {
+		node.Addr = Conf.RPCPushAddrs[0]
+	}
+	if node.Capacity == 0 {
+		node.Capacity = Conf.LimitConn
+	}
+	if r, err = registry.New(Conf.RegistryType, Conf.RegistryDir, Conf.RegistryTTL); err != nil {
+		log.Error("registry.New(\"%s\") error(%v)", Conf.RegistryType, err)
+		return
+	}
+	registryQuit = make(chan struct{})
+	registryDone = make(chan struct{})
+	go func() {
+		registry.Keepalive(r, node, Conf.RegistryTTL/3, registryQuit)
+		r.Close()
+		close(registryDone)
+	}()
+	log.Info("comet server: %d registered addr: %s", node.ServerId, node.Addr)
+	return
+}
+
+// CloseRegistry deregister the comet, the pushes stop before drain.
+func CloseRegistry() {
+	if registryQuit == nil {
+		return
+	}
+	close(registryQuit)
+	<-registryDone
+	registryQuit = nil
+}
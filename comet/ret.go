@@ -0,0 +1,7 @@
+package main
+
+const (
+	OK          = 1
+	ParamErr    = 65534
+	InternalErr = 65535
+)
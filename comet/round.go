@@ -10,7 +10,7 @@ type Round struct {
 	writers []*sync.Pool
 	//rpackers   []*sync.Pool
 	//wpackers   []*sync.Pool
-	timers    []*Timer
+	timers    []Timer
 	readerIdx int
 	writerIdx int
 	//rpackerIdx int
@@ -18,7 +18,7 @@ type Round struct {
 	timerIdx int
 }
 
-func NewRound(readBuf, writeBuf, timer, timerSize int) *Round {
+func NewRound(readBuf, writeBuf, timer, timerSize int, timerType string) *Round {
 	r := new(Round)
 	log.Debug("create %d reader buffer pool", readBuf)
 	r.readerIdx = readBuf
@@ -46,18 +46,18 @@ func NewRound(readBuf, writeBuf, timer, timerSize int) *Round {
 			r.wpackers[i] = new(sync.Pool)
 		}
 	*/
-	log.Debug("create %d timer", timer)
+	log.Debug("create %d %s timer", timer, timerType)
 	r.timerIdx = timer
-	r.timers = make([]*Timer, timer)
+	r.timers = make([]Timer, timer)
 	for i := 0; i < timer; i++ {
-		r.timers[i] = NewTimer(timerSize)
+		r.timers[i] = NewTimer(timerType, timerSize)
 	}
 	// start timer process
 	go TimerProcess(r.timers)
 	return r
 }
 
-func (r *Round) Timer(rn int) *Timer {
+func (r *Round) Timer(rn int) Timer {
 	return r.timers[rn%r.timerIdx]
 }
 
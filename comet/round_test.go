@@ -5,7 +5,7 @@ import (
 )
 
 func TestRound(t *testing.T) {
-	r := NewRound(10, 10, 2, 10, 2, 10)
+	r := NewRound(10, 10, 2, 10, timerTypeHeap)
 	t0 := r.Timer(0)
 	if t0 == nil {
 		t.FailNow()
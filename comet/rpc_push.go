@@ -2,6 +2,7 @@ package main
 
 import (
 	log "code.google.com/p/log4go"
+	"github.com/Terry-Mao/goim/define"
 	inet "github.com/Terry-Mao/goim/libs/net"
 	proto "github.com/Terry-Mao/goim/proto/comet"
 	rpc "github.com/Terry-Mao/protorpc"
@@ -53,7 +54,7 @@ func (this *PushRPC) PushMsg(arg *proto.PushMsgArg, reply *proto.NoReply) (err e
 	}
 	bucket := DefaultServer.Bucket(arg.Key)
 	if channel := bucket.Get(arg.Key); channel != nil {
-		err = channel.PushMsg(int16(arg.Ver), arg.Operation, arg.Msg)
+		err = channel.PushMsg(int16(arg.Ver), arg.Operation, arg.Priority, arg.MsgId, arg.Msg)
 	}
 	return
 }
@@ -67,7 +68,7 @@ func (this *PushRPC) PushMsgs(arg *proto.PushMsgsArg, reply *proto.PushMsgsReply
 	}
 	bucket := DefaultServer.Bucket(arg.Key)
 	if channel := bucket.Get(arg.Key); channel != nil {
-		reply.Index, err = channel.PushMsgs(arg.Vers, arg.Operations, arg.Msgs)
+		reply.Index, err = channel.PushMsgs(arg.Vers, arg.Operations, arg.MsgIds, arg.Msgs)
 	}
 	return
 }
@@ -88,7 +89,7 @@ func (this *PushRPC) MPushMsg(arg *proto.MPushMsgArg, reply *proto.MPushMsgReply
 	for n, key = range arg.Keys {
 		bucket = DefaultServer.Bucket(key)
 		if channel = bucket.Get(key); channel != nil {
-			if err = channel.PushMsg(int16(arg.Ver), arg.Operation, arg.Msg); err != nil {
+			if err = channel.PushMsg(int16(arg.Ver), arg.Operation, arg.Priority, arg.MsgId, arg.Msg); err != nil {
 				return
 			}
 			reply.Index = int32(n)
@@ -104,6 +105,7 @@ func (this *PushRPC) MPushMsgs(arg *proto.MPushMsgsArg, reply *proto.MPushMsgsRe
 		channel *Channel
 		key     string
 		n       int
+		msgId   int64
 	)
 	reply.Index = -1
 	if arg == nil || len(arg.Keys) != len(arg.Vers) || len(arg.Vers) != len(arg.Operations) || len(arg.Operations) != len(arg.Msgs) {
@@ -113,7 +115,10 @@ func (this *PushRPC) MPushMsgs(arg *proto.MPushMsgsArg, reply *proto.MPushMsgsRe
 	for n, key = range arg.Keys {
 		bucket = DefaultServer.Bucket(key)
 		if channel = bucket.Get(key); channel != nil {
-			if err = channel.PushMsg(int16(arg.Vers[n]), arg.Operations[n], arg.Msgs[n]); err != nil {
+			if msgId = 0; n < len(arg.MsgIds) {
+				msgId = arg.MsgIds[n]
+			}
+			if err = channel.PushMsg(int16(arg.Vers[n]), arg.Operations[n], define.PRIORITY_NORMAL, msgId, arg.Msgs[n]); err != nil {
 				return
 			}
 			reply.Index = int32(n)
@@ -124,7 +129,7 @@ func (this *PushRPC) MPushMsgs(arg *proto.MPushMsgsArg, reply *proto.MPushMsgsRe
 
 func (this *PushRPC) Broadcast(arg *proto.BoardcastArg, reply *proto.NoReply) (err error) {
 	for _, bucket := range DefaultServer.Buckets {
-		go bucket.Broadcast(int16(arg.Ver), arg.Operation, arg.Msg)
+		go bucket.Broadcast(arg.AppId, int16(arg.Ver), arg.Operation, define.PRIORITY_NORMAL, arg.Msg)
 	}
 	return
 }
@@ -2,12 +2,24 @@ package main
 
 import (
 	log "code.google.com/p/log4go"
+	"encoding/json"
+	"github.com/Terry-Mao/goim/define"
 	"github.com/Terry-Mao/goim/libs/hash/cityhash"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// internal operation of the client proto, the dispatch goroutine write
+	// the error reply then close the connection.
+	protoOpError = int32(-4)
 )
 
 var (
-	maxInt        = 1<<31 - 1
-	emptyJSONBody = []byte("{}")
+	maxInt          = 1<<31 - 1
+	emptyJSONBody   = []byte("{}")
+	emptyStringBody = []byte("\"\"")
 )
 
 type Server struct {
@@ -15,6 +27,7 @@ type Server struct {
 	bucketIdx uint32
 	round     *Round // accept round store
 	operator  Operator
+	draining  int32
 }
 
 // NewServer returns a new Server.
@@ -32,3 +45,70 @@ func (server *Server) Bucket(subKey string) *Bucket {
 	log.Debug("\"%s\" hit channel bucket index: %d use cityhash", subKey, idx)
 	return server.Buckets[idx]
 }
+
+// authReplyBody tell the client the heartbeat in seconds, the client must
+// send heartbeat within it.
+func authReplyBody(heartbeat time.Duration) []byte {
+	return []byte("{\"heartbeat\":" + strconv.FormatInt(int64(heartbeat/time.Second), 10) + "}")
+}
+
+type errorReplyBody struct {
+	Code int32  `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// errorReply set the proto as the error reply of the code, it's written
+// before the connection closed.
+func errorReply(p *Proto, code int32, err error) {
+	p.Operation = define.OP_ERROR_REPLY
+	p.MsgId = 0
+	p.Body, _ = json.Marshal(&errorReplyBody{Code: code, Msg: err.Error()})
+}
+
+// Draining return true if the server is shutting down.
+func (server *Server) Draining() bool {
+	return atomic.LoadInt32(&server.draining) == 1
+}
+
+// Drain reject the new handshakes and tell all the connected clients to
+// reconnect another comet, then wait the error replies written.
+func (server *Server) Drain(wait time.Duration) {
+	atomic.StoreInt32(&server.draining, 1)
+	server.replyAll(define.ERR_DRAINING, ErrDraining)
+	log.Info("comet draining, wait %s", wait)
+	time.Sleep(wait)
+}
+
+// Kick tell all the connected clients to reconnect, used when the sessions
+// are purged by the router.
+func (server *Server) Kick() {
+	server.replyAll(define.ERR_SESSION_LOST, ErrSessionLost)
+	log.Warn("comet sessions lost, kick all the clients")
+}
+
+// KickKeys tell the clients of the sub keys to reconnect and close the
+// connections, used when the sessions of them are lost on the router.
+func (server *Server) KickKeys(keys []string) {
+	p := new(Proto)
+	errorReply(p, define.ERR_SESSION_LOST, ErrSessionLost)
+	for _, key := range keys {
+		if ch := server.Bucket(key).Get(key); ch != nil {
+			ch.PushMsg(p.Ver, p.Operation, define.PRIORITY_HIGH, 0, p.Body)
+			ch.Close()
+		}
+	}
+	log.Warn("comet sessions lost: %d, kick the clients", len(keys))
+}
+
+// replyAll push the error reply to all the channels of all the apps and
+// close the connections after it written.
+func (server *Server) replyAll(code int32, err error) {
+	p := new(Proto)
+	errorReply(p, code, err)
+	for _, b := range server.Buckets {
+		for _, ch := range b.Channels() {
+			ch.PushMsg(p.Ver, p.Operation, define.PRIORITY_HIGH, 0, p.Body)
+			ch.Close()
+		}
+	}
+}
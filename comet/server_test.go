@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/Terry-Mao/goim/define"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	var (
+		reply errorReplyBody
+		b     = NewBucket(1, 1, 1)
+		ch    = NewChannel(1, 1, 1)
+		s     = NewServer([]*Bucket{b}, nil, nil)
+	)
+	b.Put("1", ch)
+	if s.Draining() {
+		t.FailNow()
+	}
+	s.Drain(0)
+	if !s.Draining() {
+		t.FailNow()
+	}
+	p, r, err := ch.SvrProtoGet()
+	if err != nil || r != &ch.SvrProtoHigh {
+		t.Errorf("ch.SvrProtoGet() error(%v)", err)
+		t.FailNow()
+	}
+	if p.Operation != define.OP_ERROR_REPLY {
+		t.Errorf("operation: %d", p.Operation)
+		t.FailNow()
+	}
+	if err = json.Unmarshal(p.Body, &reply); err != nil {
+		t.Errorf("json.Unmarshal(\"%s\") error(%v)", p.Body, err)
+		t.FailNow()
+	}
+	if reply.Code != define.ERR_DRAINING || reply.Msg != ErrDraining.Error() {
+		t.Errorf("reply: %v", reply)
+		t.FailNow()
+	}
+}
+
+func TestAuthMQTTDraining(t *testing.T) {
+	var (
+		body []byte
+		in   = new(bytes.Buffer)
+		out  = new(bytes.Buffer)
+		wr   = bufio.NewWriter(in)
+		pk   = new(mqttPacket)
+		s    = NewServer(nil, nil, nil)
+	)
+	body = append(body, mqttString(mqttProtoName)...)
+	body = append(body, mqttProtoLevel, mqttFlagPassword, 0, 60)
+	body = append(body, mqttString("device1")...)
+	body = append(body, mqttString("token")...)
+	writeMQTTPacket(wr, mqttConnect, 0, body)
+	wr.Flush()
+	s.Drain(0)
+	wr = bufio.NewWriter(out)
+	if _, _, err := s.authMQTT(bufio.NewReader(in), wr, pk, NewChannel(1, 1, 1)); err != ErrDraining {
+		t.Fatalf("authMQTT() error(%v)", err)
+	}
+	if err := readMQTTPacket(bufio.NewReader(out), pk); err != nil || pk.Type != mqttConnack || !bytes.Equal(pk.Body, []byte{0, mqttConnUnavailable}) {
+		t.Fatalf("connack: %v error(%v)", pk.Body, err)
+	}
+}
+
+func TestBucketBroadcast(t *testing.T) {
+	var (
+		b   = NewBucket(2, 1, 1)
+		ch0 = NewChannel(1, 1, 1)
+		ch1 = NewChannel(1, 1, 1)
+	)
+	ch1.AppId = 1
+	b.Put("1", ch0)
+	b.Put("1_1", ch1)
+	b.Broadcast(1, 0, define.OP_SEND_SMS_REPLY, define.PRIORITY_NORMAL, []byte("{}"))
+	if _, _, err := ch0.SvrProtoGet(); err == nil {
+		t.Error("app 0 channel received the app 1 broadcast")
+		t.FailNow()
+	}
+	if p, _, err := ch1.SvrProtoGet(); err != nil || p.Operation != define.OP_SEND_SMS_REPLY {
+		t.Errorf("ch1.SvrProtoGet() error(%v)", err)
+		t.FailNow()
+	}
+}
+
+func TestKickClose(t *testing.T) {
+	var (
+		b     = NewBucket(1, 1, 1)
+		ch    = NewChannel(1, 1, 1)
+		round = NewRound(1, 1, 1, 10, timerTypeHeap)
+		s     = NewServer([]*Bucket{b}, round, nil)
+	)
+	Conf = NewConfig()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Put("1", ch)
+	wrp := round.Writer(0)
+	go s.dispatchTCP("1", conn.(*net.TCPConn), wrp, NewBufioWriterSize(wrp, conn, Conf.WriteBufSize), ch, time.Minute, round.Timer(0))
+	s.KickKeys([]string{"1"})
+	// the error reply is written then the conn closed
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	data, err := ioutil.ReadAll(c)
+	if err != nil {
+		t.Fatalf("conn not closed, error(%v)", err)
+	}
+	if len(data) <= int(rawHeaderLen) {
+		t.Fatalf("error reply: %v", data)
+	}
+}
@@ -16,6 +16,8 @@ func InitSignal() {
 		log.Info("comet[%s] get a signal %s", Ver, s.String())
 		switch s {
 		case syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGSTOP, syscall.SIGINT:
+			CloseRegistry()
+			DefaultServer.Drain(Conf.Drain)
 			return
 		case syscall.SIGHUP:
 			reload()
@@ -32,4 +34,8 @@ func reload() {
 		return
 	}
 	Conf = newConf
+	// the new connections use the new operation rules, the existing ones
+	// keep theirs
+	httpLimiter.Reload(Conf.LimitOps)
+	connLimiter.Reload(Conf.LimitConn, Conf.LimitConnIP, Conf.LimitConnIPRule)
 }
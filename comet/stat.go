@@ -1,10 +1,67 @@
 package main
 
-// TODO
-// bucket
-// channel
-// ring
-// timer
-// push
-// bufio
-// operation
+import (
+	log "code.google.com/p/log4go"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+var (
+	DefaultStat = new(Stat)
+)
+
+// Stat is the counters of the comet, get them by http "/stat".
+type Stat struct {
+	ConnReject       int64 `json:"conn_reject"`         // rejected by the global max connections
+	ConnIPReject     int64 `json:"conn_ip_reject"`      // rejected by the per ip max connections
+	ConnIPRateReject int64 `json:"conn_ip_rate_reject"` // rejected by the per ip accept rate
+}
+
+// IncrConnReject count the rejected connection by the limiter error.
+func (s *Stat) IncrConnReject(err error) {
+	switch err {
+	case ErrConnLimit:
+		atomic.AddInt64(&s.ConnReject, 1)
+	case ErrConnIPLimit:
+		atomic.AddInt64(&s.ConnIPReject, 1)
+	case ErrConnIPRate:
+		atomic.AddInt64(&s.ConnIPRateReject, 1)
+	}
+}
+
+// InitStat listen the stat.bind and serve the stat info.
+func InitStat() {
+	statServeMux := http.NewServeMux()
+	statServeMux.HandleFunc("/stat", serveStat)
+	for _, addr := range Conf.StatBind {
+		log.Info("start stat listen: \"%s\"", addr)
+		go func(addr string) {
+			if err := http.ListenAndServe(addr, statServeMux); err != nil {
+				log.Error("http.ListenAndServe(\"%s\", statServeMux) error(%v)", addr, err)
+				panic(err)
+			}
+		}(addr)
+	}
+}
+
+func serveStat(w http.ResponseWriter, r *http.Request) {
+	var (
+		data []byte
+		err  error
+		res  = map[string]interface{}{
+			"conn":                connLimiter.Conns(),
+			"conn_reject":         atomic.LoadInt64(&DefaultStat.ConnReject),
+			"conn_ip_reject":      atomic.LoadInt64(&DefaultStat.ConnIPReject),
+			"conn_ip_rate_reject": atomic.LoadInt64(&DefaultStat.ConnIPRateReject),
+		}
+	)
+	if data, err = json.Marshal(res); err != nil {
+		log.Error("json.Marshal(\"%v\") error(%v)", res, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if _, err = w.Write(data); err != nil {
+		log.Error("w.Write(\"%s\") error(%v)", data, err)
+	}
+}
@@ -10,7 +10,7 @@ import (
 )
 
 const (
-	maxPackIntBuf = 4
+	maxPackIntBuf = 8
 )
 
 // InitTCP listen all tcp.bind and start accept connections.
@@ -44,6 +44,7 @@ func acceptTCP(server *Server, lis *net.TCPListener) {
 	var (
 		conn *net.TCPConn
 		err  error
+		ip   string
 		r    int
 	)
 	for {
@@ -64,6 +65,13 @@ func acceptTCP(server *Server, lis *net.TCPListener) {
 			log.Error("conn.SetWriteBuffer() error(%v)", err)
 			return
 		}
+		// limit before the handshake timer armed
+		ip = addrIP(conn.RemoteAddr().String())
+		if err = connLimiter.Acquire(ip); err != nil {
+			log.Warn("connLimiter.Acquire(\"%s\") error(%v)", ip, err)
+			conn.Close()
+			continue
+		}
 		go serveTCP(server, conn, r)
 		if r++; r == maxInt {
 			r = 0
@@ -87,9 +95,10 @@ func serveTCP(server *Server, conn *net.TCPConn, r int) {
 	)
 	log.Debug("start tcp serve \"%s\" with \"%s\"", lAddr, rAddr)
 	server.serveTCP(conn, rrp, wrp, rr, wr, tr)
+	connLimiter.Release(addrIP(rAddr))
 }
 
-func (server *Server) serveTCP(conn *net.TCPConn, rrp, wrp *sync.Pool, rr *bufio.Reader, wr *bufio.Writer, tr *Timer) {
+func (server *Server) serveTCP(conn *net.TCPConn, rrp, wrp *sync.Pool, rr *bufio.Reader, wr *bufio.Writer, tr Timer) {
 	var (
 		b   *Bucket
 		p   *Proto
@@ -97,7 +106,7 @@ func (server *Server) serveTCP(conn *net.TCPConn, rrp, wrp *sync.Pool, rr *bufio
 		key string
 		err error
 		trd *TimerData
-		ch  = NewChannel(Conf.CliProto, Conf.SvrProto)
+		ch  = NewChannel(Conf.CliProto, Conf.SvrProto, Conf.SvrProtoHigh)
 		pb  = make([]byte, maxPackIntBuf)
 	)
 	// auth
@@ -114,8 +123,11 @@ func (server *Server) serveTCP(conn *net.TCPConn, rrp, wrp *sync.Pool, rr *bufio
 	// register key->channel
 	b = server.Bucket(key)
 	b.Put(key, ch)
+	if err := server.operator.Online(key); err != nil {
+		log.Error("%s operator do online error(%v)", key, err)
+	}
 	// hanshake ok start dispatch goroutine
-	go server.dispatchTCP(conn, wrp, wr, ch, hb, tr)
+	go server.dispatchTCP(key, conn, wrp, wr, ch, hb, tr)
 	for {
 		// fetch a proto from channel free list
 		if p, err = ch.CliProto.Set(); err != nil {
@@ -123,8 +135,15 @@ func (server *Server) serveTCP(conn *net.TCPConn, rrp, wrp *sync.Pool, rr *bufio
 			goto failed
 		}
 		// parse request protocol
-		if err = server.readTCPRequest(rr, pb, p); err != nil {
+		if _, err = server.readTCPRequest(rr, pb, p); err != nil {
 			log.Error("%s read client request error(%v)", key, err)
+			if err == ErrProtoPackLen {
+				// let the dispatch goroutine write the error then close
+				errorReply(p, define.ERR_FRAME_LARGE, err)
+				p.Operation = protoOpError
+				ch.CliProto.SetAdv()
+				ch.Signal()
+			}
 			goto failed
 		}
 		// send to writer
@@ -134,8 +153,14 @@ func (server *Server) serveTCP(conn *net.TCPConn, rrp, wrp *sync.Pool, rr *bufio
 failed:
 	// dialog finish
 	// may call twice
-	if err = conn.Close(); err != nil {
-		log.Error("reader: conn.Close() error(%v)")
+	if err == ErrProtoPackLen && b != nil {
+		// the dispatch goroutine close the conn after the error reply
+		err = conn.CloseRead()
+	} else {
+		err = conn.Close()
+	}
+	if err != nil {
+		log.Error("reader: conn.Close() error(%v)", err)
 	}
 	PutBufioReader(rrp, rr)
 	if b != nil {
@@ -153,11 +178,13 @@ failed:
 // dispatch accepts connections on the listener and serves requests
 // for each incoming connection.  dispatch blocks; the caller typically
 // invokes it in a go statement.
-func (server *Server) dispatchTCP(conn *net.TCPConn, wrp *sync.Pool, wr *bufio.Writer, ch *Channel, hb time.Duration, tr *Timer) {
+func (server *Server) dispatchTCP(key string, conn *net.TCPConn, wrp *sync.Pool, wr *bufio.Writer, ch *Channel, hb time.Duration, tr Timer) {
 	var (
 		p   *Proto
 		err error
 		trd *TimerData
+		r   *Ring
+		lim = NewLimiter(Conf.LimitOps)
 		pb  = make([]byte, maxPackIntBuf) // avoid false sharing
 	)
 	log.Debug("start dispatch goroutine")
@@ -174,7 +201,21 @@ func (server *Server) dispatchTCP(conn *net.TCPConn, wrp *sync.Pool, wr *bufio.W
 			if p, err = ch.CliProto.Get(); err != nil {
 				break
 			}
-			if p.Operation == define.OP_HEARTBEAT {
+			if !lim.Allow(p.Operation) {
+				log.Warn("operation: %d rate limited, action: %s", p.Operation, Conf.LimitAction)
+				if Conf.LimitAction == limitActionDisconnect {
+					err = ErrRateLimit
+					server.writeTCPError(wr, pb, p, define.ERR_RATE_LIMIT, err)
+					goto failed
+				}
+				if Conf.LimitAction == limitActionDrop {
+					p.Reset()
+					ch.CliProto.GetAdv()
+					continue
+				}
+				// reply the error and keep the conn
+				errorReply(p, define.ERR_RATE_LIMIT, ErrRateLimit)
+			} else if p.Operation == define.OP_HEARTBEAT {
 				// Use a previous timer value if difference between it and a new
 				// value is less than TIMER_LAZY_DELAY milliseconds: this allows
 				// to minimize the minheap operations for fast connections.
@@ -188,10 +229,22 @@ func (server *Server) dispatchTCP(conn *net.TCPConn, wrp *sync.Pool, wr *bufio.W
 				// heartbeat
 				p.Body = nil
 				p.Operation = define.OP_HEARTBEAT_REPLY
+			} else if p.Operation == define.OP_ACK {
+				if err = server.operator.Ack(key, p); err != nil {
+					log.Error("operator.Ack() error(%v)", err)
+				}
+			} else if p.Operation == protoOpError {
+				// the reader failed, reply the error and close
+				p.Operation = define.OP_ERROR_REPLY
+				if err = server.writeTCPResponse(wr, pb, p); err != nil {
+					log.Error("server.writeTCPResponse() error(%v)", err)
+				}
+				goto failed
 			} else {
 				// process message
 				if err = server.operator.Operate(p); err != nil {
 					log.Error("operator.Operate() error(%v)", err)
+					server.writeTCPError(wr, pb, p, define.ERR_OPERATION, err)
 					goto failed
 				}
 			}
@@ -201,18 +254,26 @@ func (server *Server) dispatchTCP(conn *net.TCPConn, wrp *sync.Pool, wr *bufio.W
 			}
 			ch.CliProto.GetAdv()
 		}
-		// fetch message from svrbox(server send)
+		// fetch message from svrbox(server send), high priority first
 		for {
-			if p, err = ch.SvrProto.Get(); err != nil {
-				log.Warn("ch.SvrProto.Get() error(%v)", err)
+			if p, r, err = ch.SvrProtoGet(); err != nil {
+				log.Warn("ch.SvrProtoGet() error(%v)", err)
 				break
 			}
-			// just forward the message
+			// just forward the message, the old clients only read the raw
+			// header
+			if !ch.Ack {
+				p.MsgId = 0
+			}
 			if err = server.writeTCPResponse(wr, pb, p); err != nil {
 				log.Error("server.writeTCPResponse() error(%v)", err)
 				goto failed
 			}
-			ch.SvrProto.GetAdv()
+			r.GetAdv()
+		}
+		// kicked by the server after the error reply written
+		if ch.Closing() {
+			goto failed
 		}
 	}
 failed:
@@ -229,25 +290,42 @@ failed:
 
 // auth for goim handshake with client, use rsa & aes.
 func (server *Server) authTCP(rr *bufio.Reader, wr *bufio.Writer, pb []byte, ch *Channel) (subKey string, heartbeat time.Duration, err error) {
-	var p *Proto
+	var (
+		p         *Proto
+		appId     int32
+		headerLen int16
+	)
 	// WARN
 	// don't adv the cli proto, after auth simply discard it.
 	if p, err = ch.CliProto.Set(); err != nil {
 		return
 	}
-	if err = server.readTCPRequest(rr, pb, p); err != nil {
+	if headerLen, err = server.readTCPRequest(rr, pb, p); err != nil {
+		if err == ErrProtoPackLen {
+			server.writeTCPError(wr, pb, p, define.ERR_FRAME_LARGE, err)
+		}
 		return
 	}
 	if p.Operation != define.OP_AUTH {
 		log.Warn("auth operation not valid: %d", p.Operation)
 		err = ErrOperation
+		server.writeTCPError(wr, pb, p, define.ERR_OPERATION, err)
 		return
 	}
-	if subKey, heartbeat, err = server.operator.Connect(p); err != nil {
+	if server.Draining() {
+		err = ErrDraining
+		server.writeTCPError(wr, pb, p, define.ERR_DRAINING, err)
+		return
+	}
+	if subKey, appId, heartbeat, err = server.operator.Connect(p); err != nil {
 		log.Error("operator.Connect error(%v)", err)
+		server.writeTCPError(wr, pb, p, define.ERR_AUTH, err)
 		return
 	}
-	p.Body = nil
+	ch.AppId = appId
+	// the client auth with the extended header can read the message id
+	ch.Ack = headerLen > rawHeaderLen
+	p.Body = authReplyBody(heartbeat)
 	p.Operation = define.OP_AUTH_REPLY
 	if err = server.writeTCPResponse(wr, pb, p); err != nil {
 		log.Error("[%s] server.sendTCPResponse() error(%v)", subKey, err)
@@ -255,12 +333,12 @@ func (server *Server) authTCP(rr *bufio.Reader, wr *bufio.Writer, pb []byte, ch
 	return
 }
 
-// readRequest
-func (server *Server) readTCPRequest(rr *bufio.Reader, pb []byte, proto *Proto) (err error) {
+// readRequest, the header extended with the message id is accepted and its
+// length returned, the client negotiate the message id by it in auth.
+func (server *Server) readTCPRequest(rr *bufio.Reader, pb []byte, proto *Proto) (headerLen int16, err error) {
 	var (
-		packLen   int32
-		headerLen int16
-		bodyLen   int
+		packLen int32
+		bodyLen int
 	)
 	if err = ReadAll(rr, pb[:packLenSize]); err != nil {
 		return
@@ -268,15 +346,17 @@ func (server *Server) readTCPRequest(rr *bufio.Reader, pb []byte, proto *Proto)
 	packLen = BigEndian.Int32(pb[:packLenSize])
 	log.Debug("packLen: %d", packLen)
 	if packLen > maxPackLen {
-		return ErrProtoPackLen
+		err = ErrProtoPackLen
+		return
 	}
 	if err = ReadAll(rr, pb[:headerLenSize]); err != nil {
 		return
 	}
 	headerLen = BigEndian.Int16(pb[:headerLenSize])
 	log.Debug("headerLen: %d", headerLen)
-	if headerLen != rawHeaderLen {
-		return ErrProtoHeaderLen
+	if headerLen != rawHeaderLen && headerLen != rawHeaderLen+MsgIdSize {
+		err = ErrProtoHeaderLen
+		return
 	}
 	if err = ReadAll(rr, pb[:VerSize]); err != nil {
 		return
@@ -293,6 +373,13 @@ func (server *Server) readTCPRequest(rr *bufio.Reader, pb []byte, proto *Proto)
 	}
 	proto.SeqId = BigEndian.Int32(pb[:SeqIdSize])
 	log.Debug("seqId: %d", proto.SeqId)
+	proto.MsgId = 0
+	if headerLen > rawHeaderLen {
+		if err = ReadAll(rr, pb[:MsgIdSize]); err != nil {
+			return
+		}
+		proto.MsgId = BigEndian.Int64(pb[:MsgIdSize])
+	}
 	bodyLen = int(packLen - int32(headerLen))
 	log.Debug("read body len: %d", bodyLen)
 	if bodyLen > 0 {
@@ -308,14 +395,27 @@ func (server *Server) readTCPRequest(rr *bufio.Reader, pb []byte, proto *Proto)
 	return
 }
 
+// writeTCPError write the error reply before the conn closed, the write
+// error is only logged, the caller close the conn anyway.
+func (server *Server) writeTCPError(wr *bufio.Writer, pb []byte, proto *Proto, code int32, err error) {
+	errorReply(proto, code, err)
+	if err = server.writeTCPResponse(wr, pb, proto); err != nil {
+		log.Error("server.writeTCPResponse() error(%v)", err)
+	}
+}
+
 // sendResponse send resp to client, sendResponse must be goroutine safe.
 func (server *Server) writeTCPResponse(wr *bufio.Writer, pb []byte, proto *Proto) (err error) {
+	var headerLen = rawHeaderLen
 	log.Debug("write proto: %v", proto)
-	BigEndian.PutInt32(pb[:packLenSize], int32(rawHeaderLen)+int32(len(proto.Body)))
+	if proto.MsgId != 0 {
+		headerLen += MsgIdSize
+	}
+	BigEndian.PutInt32(pb[:packLenSize], int32(headerLen)+int32(len(proto.Body)))
 	if _, err = wr.Write(pb[:packLenSize]); err != nil {
 		return
 	}
-	BigEndian.PutInt16(pb[:headerLenSize], rawHeaderLen)
+	BigEndian.PutInt16(pb[:headerLenSize], headerLen)
 	if _, err = wr.Write(pb[:headerLenSize]); err != nil {
 		return
 	}
@@ -331,6 +431,12 @@ func (server *Server) writeTCPResponse(wr *bufio.Writer, pb []byte, proto *Proto
 	if _, err = wr.Write(pb[:SeqIdSize]); err != nil {
 		return
 	}
+	if proto.MsgId != 0 {
+		BigEndian.PutInt64(pb[:MsgIdSize], proto.MsgId)
+		if _, err = wr.Write(pb[:MsgIdSize]); err != nil {
+			return
+		}
+	}
 	if proto.Body != nil {
 		if _, err = wr.Write(proto.Body); err != nil {
 			return
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTCPMsgIdHeader(t *testing.T) {
+	Conf = NewConfig()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, ack := range []bool{false, true} {
+		var (
+			ch    = NewChannel(1, 1, 1)
+			round = NewRound(1, 1, 1, 10, timerTypeHeap)
+			s     = NewServer([]*Bucket{NewBucket(1, 1, 1)}, round, nil)
+			p     = new(Proto)
+			pb    = make([]byte, maxPackIntBuf)
+		)
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := l.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch.Ack = ack
+		wrp := round.Writer(0)
+		go s.dispatchTCP("1", conn.(*net.TCPConn), wrp, NewBufioWriterSize(wrp, conn, Conf.WriteBufSize), ch, time.Minute, round.Timer(0))
+		if err = ch.PushMsg(1, 5, 0, 100, []byte("msg")); err != nil {
+			t.Fatal(err)
+		}
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		headerLen, err := s.readTCPRequest(bufio.NewReader(c), pb, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// only the client negotiated at auth read the message id
+		if ack && (headerLen != rawHeaderLen+MsgIdSize || p.MsgId != 100) {
+			t.Fatalf("ack headerLen: %d, msgId: %d", headerLen, p.MsgId)
+		}
+		if !ack && (headerLen != rawHeaderLen || p.MsgId != 0) {
+			t.Fatalf("headerLen: %d, msgId: %d", headerLen, p.MsgId)
+		}
+		if string(p.Body) != "msg" {
+			t.Fatalf("body: %s", p.Body)
+		}
+		ch.Finish()
+		c.Close()
+	}
+}
@@ -14,13 +14,39 @@ const (
 	timerDelay       = 100 * time.Millisecond
 	maxTimerDelay    = 500 * time.Millisecond
 	timerLazyDelay   = 300 * time.Millisecond
+	// timer type
+	timerTypeHeap  = "heap"
+	timerTypeWheel = "wheel"
 )
 
+// Timer close the io.Closer when it's expired, the heartbeat of connection
+// add to a timer.
+type Timer interface {
+	// Add add a closer expire after the duration.
+	Add(expire time.Duration, closer io.Closer) (*TimerData, error)
+	// Del del the timer data and put it back to the free list.
+	Del(td *TimerData)
+	// Expire close all the expired closers.
+	Expire()
+	// Find get the duration until the next expire, infiniteDuration if no
+	// timer data.
+	Find() time.Duration
+}
+
+// NewTimer new a timer by the type, heap or wheel.
+func NewTimer(typ string, num int) Timer {
+	if typ == timerTypeWheel {
+		return NewWheelTimer(num)
+	}
+	return NewHeapTimer(num)
+}
+
 type TimerData struct {
 	key   time.Time
 	value io.Closer
 	index int
 	next  *TimerData
+	prev  *TimerData // only used by wheel timer
 }
 
 func (td *TimerData) Delay() time.Duration {
@@ -40,7 +66,8 @@ func (td *TimerData) String() string {
 	return td.key.Format(timerFormat)
 }
 
-type Timer struct {
+// HeapTimer is a min-heap timer.
+type HeapTimer struct {
 	cur    int
 	max    int
 	used   int
@@ -54,9 +81,9 @@ type Timer struct {
 // and may be called whenever the heap invariants may have been invalidated.
 // Its complexity is O(n) where n = h.Len().
 //
-func NewTimer(num int) *Timer {
+func NewHeapTimer(num int) *HeapTimer {
 	// heapify
-	t := new(Timer)
+	t := new(HeapTimer)
 	t.timers = make([]*TimerData, num, num)
 	t.cur = -1
 	t.max = num - 1
@@ -73,7 +100,7 @@ func NewTimer(num int) *Timer {
 // Push pushes the element x onto the heap. The complexity is
 // O(log(n)) where n = h.Len().
 //
-func (t *Timer) Add(expire time.Duration, closer io.Closer) (td *TimerData, err error) {
+func (t *HeapTimer) Add(expire time.Duration, closer io.Closer) (td *TimerData, err error) {
 	t.lock.Lock()
 	if t.cur >= t.max {
 		t.lock.Unlock()
@@ -97,7 +124,7 @@ func (t *Timer) Add(expire time.Duration, closer io.Closer) (td *TimerData, err
 // The complexity is O(log(n)) where n = max.
 // It is equivalent to Del(0).
 //
-func (t *Timer) Expire() {
+func (t *HeapTimer) Expire() {
 	var (
 		err error
 		d   time.Duration
@@ -129,7 +156,7 @@ func (t *Timer) Expire() {
 // Del removes the element at index i from the heap.
 // The complexity is O(log(n)) where n = h.Len().
 //
-func (t *Timer) Del(td *TimerData) {
+func (t *HeapTimer) Del(td *TimerData) {
 	if td == nil {
 		return
 	}
@@ -144,7 +171,7 @@ func (t *Timer) Del(td *TimerData) {
 	return
 }
 
-func (t *Timer) remove(i int) {
+func (t *HeapTimer) remove(i int) {
 	if t.cur != i {
 		t.swap(i, t.cur)
 		t.down(i, t.cur)
@@ -155,7 +182,7 @@ func (t *Timer) remove(i int) {
 	t.cur--
 }
 
-func (t *Timer) Find() (d time.Duration) {
+func (t *HeapTimer) Find() (d time.Duration) {
 	t.lock.Lock()
 	if t.cur < 0 {
 		d = infiniteDuration
@@ -167,7 +194,7 @@ func (t *Timer) Find() (d time.Duration) {
 	return
 }
 
-func (t *Timer) up(j int) {
+func (t *HeapTimer) up(j int) {
 	for {
 		i := (j - 1) / 2 // parent
 		if i == j || !t.less(j, i) {
@@ -178,7 +205,7 @@ func (t *Timer) up(j int) {
 	}
 }
 
-func (t *Timer) down(i, n int) {
+func (t *HeapTimer) down(i, n int) {
 	for {
 		j1 := 2*i + 1
 		if j1 >= n || j1 < 0 { // j1 < 0 after int overflow
@@ -196,18 +223,18 @@ func (t *Timer) down(i, n int) {
 	}
 }
 
-func (t *Timer) less(i, j int) bool {
+func (t *HeapTimer) less(i, j int) bool {
 	return t.timers[i].key.Before(t.timers[j].key)
 }
 
-func (t *Timer) swap(i, j int) {
+func (t *HeapTimer) swap(i, j int) {
 	//log.Debug("swap(%d, %d)", i, j)
 	t.timers[i], t.timers[j] = t.timers[j], t.timers[i]
 	t.timers[i].index = i
 	t.timers[j].index = j
 }
 
-func (t *Timer) get() *TimerData {
+func (t *HeapTimer) get() *TimerData {
 	td := t.free
 	if td != nil {
 		t.free = td.next
@@ -219,7 +246,7 @@ func (t *Timer) get() *TimerData {
 	return td
 }
 
-func (t *Timer) put(td *TimerData) {
+func (t *HeapTimer) put(td *TimerData) {
 	// if no used channel, free list full, discard it
 	if t.used == 0 {
 		// use gc free
@@ -235,9 +262,9 @@ func (t *Timer) put(td *TimerData) {
 // range all timers call find the time.Duration
 // sleep
 // range all timers call expire
-func TimerProcess(timers []*Timer) {
+func TimerProcess(timers []Timer) {
 	var (
-		t  *Timer
+		t  Timer
 		d  time.Duration
 		md = timerDelay
 	)
@@ -2,13 +2,15 @@ package main
 
 import (
 	log "code.google.com/p/log4go"
+	"math/rand"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 func TestTimer(t *testing.T) {
 	var err error
-	timer := NewTimer(100)
+	timer := NewHeapTimer(100)
 	tds := make([]*TimerData, 100)
 	for i := 0; i < 100; i++ {
 		if tds[i], err = timer.Add(time.Duration(i)*time.Second+5*time.Minute, nil); err != nil {
@@ -42,18 +44,115 @@ func TestTimer(t *testing.T) {
 
 func TestTimerProcess(t *testing.T) {
 	// process test
-	timer := NewTimer(3)
+	timer := NewHeapTimer(3)
 	timerd, err := timer.Add(5*time.Second, nil)
 	if err != nil {
 		t.Error(err)
 		t.FailNow()
 	}
-	go TimerProcess([]*Timer{timer})
+	go TimerProcess([]Timer{timer})
 	time.Sleep(10 * time.Second)
 	timer.Del(timerd)
 }
 
-func printTimer(timer *Timer) {
+type testCloser struct {
+	closed int32
+}
+
+func (c *testCloser) Close() error {
+	atomic.AddInt32(&c.closed, 1)
+	return nil
+}
+
+func TestWheelTimer(t *testing.T) {
+	var err error
+	timer := NewWheelTimer(100)
+	tds := make([]*TimerData, 100)
+	for i := 0; i < 100; i++ {
+		if tds[i], err = timer.Add(time.Duration(i)*time.Hour+5*time.Minute, nil); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	// overflow
+	if _, err = timer.Add(1*time.Second, nil); err != ErrTimerFull {
+		t.Error(err)
+		t.FailNow()
+	}
+	for i := 0; i < 100; i++ {
+		timer.Del(tds[i])
+	}
+	if d := timer.Find(); d != infiniteDuration {
+		t.Errorf("timer.Find() %s", d)
+		t.FailNow()
+	}
+	// expire order
+	c0, c1 := new(testCloser), new(testCloser)
+	td0, _ := timer.Add(200*time.Millisecond, c0)
+	td1, _ := timer.Add(30*time.Second, c1)
+	time.Sleep(500 * time.Millisecond)
+	timer.Expire()
+	if atomic.LoadInt32(&c0.closed) != 1 || atomic.LoadInt32(&c1.closed) != 0 {
+		t.Errorf("closed: %d, %d", c0.closed, c1.closed)
+		t.FailNow()
+	}
+	timer.Del(td0)
+	// pass 30s, cascade the upper wheel
+	timer.base = timer.base.Add(-30 * time.Second)
+	td1.key = td1.key.Add(-30 * time.Second)
+	timer.Expire()
+	if atomic.LoadInt32(&c1.closed) != 1 {
+		t.Errorf("closed: %d", c1.closed)
+		t.FailNow()
+	}
+	timer.Del(td1)
+	if timer.num != 0 {
+		t.Errorf("timer num: %d", timer.num)
+		t.FailNow()
+	}
+}
+
+func benchmarkTimer(b *testing.B, timer Timer, num int) {
+	var (
+		err error
+		i   int
+		tds = make([]*TimerData, num)
+	)
+	for i = 0; i < num; i++ {
+		if tds[i], err = timer.Add(time.Duration(rand.Intn(300))*time.Second+time.Minute, nil); err != nil {
+			b.Error(err)
+			b.FailNow()
+		}
+	}
+	b.ResetTimer()
+	// heartbeat: del then add again
+	for n := 0; n < b.N; n++ {
+		i = rand.Intn(num)
+		timer.Del(tds[i])
+		if tds[i], err = timer.Add(time.Duration(rand.Intn(300))*time.Second+time.Minute, nil); err != nil {
+			b.Error(err)
+			b.FailNow()
+		}
+	}
+}
+
+func BenchmarkHeapTimer1K(b *testing.B) {
+	benchmarkTimer(b, NewHeapTimer(1000), 1000)
+}
+
+func BenchmarkWheelTimer1K(b *testing.B) {
+	benchmarkTimer(b, NewWheelTimer(1000), 1000)
+}
+
+func BenchmarkHeapTimer100K(b *testing.B) {
+	benchmarkTimer(b, NewHeapTimer(100000), 100000)
+}
+
+func BenchmarkWheelTimer100K(b *testing.B) {
+	benchmarkTimer(b, NewWheelTimer(100000), 100000)
+}
+
+func printTimer(timer *HeapTimer) {
 	log.Debug("--------------------")
 	for i := 0; i <= timer.cur; i++ {
 		log.Debug("timer: %s, index: %d", timer.timers[i].String(), timer.timers[i].index)
@@ -0,0 +1,236 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// the first wheel has 256 slots, the others have 64 slots, with 100ms
+	// tick the wheels cover 25.6s, 27m, 29h and 77d.
+	wheelTick       = 100 * time.Millisecond
+	wheelRootBits   = 8
+	wheelRootSize   = 1 << wheelRootBits
+	wheelRootMask   = wheelRootSize - 1
+	wheelBits       = 6
+	wheelSize       = 1 << wheelBits
+	wheelMask       = wheelSize - 1
+	wheelLevel      = 4
+	wheelMaxTimeout = 1<<(wheelRootBits+(wheelLevel-1)*wheelBits) - 1
+)
+
+// WheelTimer is a hierarchical timing wheel timer, the timer data expired
+// in the same tick are linked in a slot, the far away slots are cascaded to
+// the near wheel when the near wheel turns a round.
+type WheelTimer struct {
+	lock   sync.Mutex
+	base   time.Time // the time of tick 0
+	ticks  int64     // the next tick to process
+	num    int       // the timer data in the wheels
+	max    int
+	used   int
+	free   *TimerData
+	wheels [wheelLevel][]TimerData // slot heads of circular lists
+}
+
+// NewWheelTimer new a timing wheel timer which hold num timer data at most.
+func NewWheelTimer(num int) *WheelTimer {
+	t := new(WheelTimer)
+	t.base = time.Now()
+	t.max = num
+	for i := 0; i < wheelLevel; i++ {
+		if i == 0 {
+			t.wheels[i] = make([]TimerData, wheelRootSize)
+		} else {
+			t.wheels[i] = make([]TimerData, wheelSize)
+		}
+		for j := 0; j < len(t.wheels[i]); j++ {
+			head := &t.wheels[i][j]
+			head.next = head
+			head.prev = head
+		}
+	}
+	td := new(TimerData)
+	t.free = td
+	for i := 1; i < num; i++ {
+		td.next = new(TimerData)
+		td = td.next
+	}
+	return t
+}
+
+// Add add a closer expire after the duration. The complexity is O(1).
+func (t *WheelTimer) Add(expire time.Duration, closer io.Closer) (td *TimerData, err error) {
+	t.lock.Lock()
+	if t.num >= t.max {
+		t.lock.Unlock()
+		err = ErrTimerFull
+		return
+	}
+	if t.num == 0 {
+		// all the wheels are empty, skip the idle ticks
+		t.ticks = t.tick(time.Now())
+	}
+	t.num++
+	td = t.get()
+	td.key = time.Now().Add(expire)
+	td.value = closer
+	t.link(td)
+	t.lock.Unlock()
+	log.Debug("timer: push item key: %s", td.String())
+	return
+}
+
+// Del removes the timer data from the wheel. The complexity is O(1).
+func (t *WheelTimer) Del(td *TimerData) {
+	if td == nil {
+		return
+	}
+	t.lock.Lock()
+	if td.index != -1 {
+		// already remove, usually by expire
+		t.unlink(td)
+		t.num--
+	}
+	t.put(td)
+	t.lock.Unlock()
+	log.Debug("timer: remove item key: %s", td.String())
+	return
+}
+
+// Expire turn the wheels to now and close the expired closers.
+func (t *WheelTimer) Expire() {
+	var (
+		err      error
+		idx      int
+		now      int64
+		head, td *TimerData
+	)
+	t.lock.Lock()
+	for now = t.tick(time.Now()); t.ticks <= now; t.ticks++ {
+		idx = int(t.ticks & wheelRootMask)
+		if idx == 0 {
+			// the root wheel turns a round, cascade the upper wheels
+			for i := 1; i < wheelLevel; i++ {
+				if t.cascade(i) != 0 {
+					break
+				}
+			}
+		}
+		head = &t.wheels[0][idx]
+		for head.next != head {
+			td = head.next
+			log.Debug("find a expire timer key: %s", td.String())
+			t.unlink(td)
+			t.num--
+			if td.value == nil {
+				log.Warn("expire timer no io.Closer")
+			} else {
+				if err = td.value.Close(); err != nil {
+					log.Error("timer close error(%v)", err)
+				}
+			}
+			// delay put back to free list, the caller call Del
+		}
+	}
+	t.lock.Unlock()
+	return
+}
+
+// Find get the duration until the next tick.
+func (t *WheelTimer) Find() (d time.Duration) {
+	t.lock.Lock()
+	if t.num == 0 {
+		d = infiniteDuration
+	} else {
+		d = t.base.Add(time.Duration(t.ticks) * wheelTick).Sub(time.Now())
+	}
+	t.lock.Unlock()
+	return
+}
+
+// tick get the tick of the time, a tick is processed after it's passed, so
+// the timer data never expire earlier than the key.
+func (t *WheelTimer) tick(now time.Time) int64 {
+	return int64(now.Sub(t.base) / wheelTick)
+}
+
+// link add the timer data to the slot of it's expire tick.
+func (t *WheelTimer) link(td *TimerData) {
+	var (
+		head    *TimerData
+		expires = int64((td.key.Sub(t.base) + wheelTick - 1) / wheelTick)
+		d       = expires - t.ticks
+	)
+	if d < 0 {
+		// already expired, process at the next tick
+		expires, d = t.ticks, 0
+	} else if d > wheelMaxTimeout {
+		expires, d = t.ticks+wheelMaxTimeout, wheelMaxTimeout
+	}
+	if d < wheelRootSize {
+		head = &t.wheels[0][expires&wheelRootMask]
+	} else {
+		for i := 1; i < wheelLevel; i++ {
+			if d < 1<<uint(wheelRootBits+i*wheelBits) {
+				head = &t.wheels[i][(expires>>uint(wheelRootBits+(i-1)*wheelBits))&wheelMask]
+				break
+			}
+		}
+	}
+	td.index = 0
+	td.prev = head.prev
+	td.next = head
+	head.prev.next = td
+	head.prev = td
+}
+
+func (t *WheelTimer) unlink(td *TimerData) {
+	td.prev.next = td.next
+	td.next.prev = td.prev
+	td.prev = nil
+	td.next = nil
+	td.index = -1
+}
+
+// cascade move the timer data of the current slot in the level wheel to
+// the lower wheels, return the slot index.
+func (t *WheelTimer) cascade(level int) int {
+	var (
+		td   *TimerData
+		idx  = int((t.ticks >> uint(wheelRootBits+(level-1)*wheelBits)) & wheelMask)
+		head = &t.wheels[level][idx]
+	)
+	for head.next != head {
+		td = head.next
+		t.unlink(td)
+		t.link(td)
+	}
+	return idx
+}
+
+func (t *WheelTimer) get() *TimerData {
+	td := t.free
+	if td != nil {
+		t.free = td.next
+		t.used++
+		log.Debug("get timerdata, used: %d", t.used)
+	} else {
+		td = new(TimerData)
+	}
+	return td
+}
+
+func (t *WheelTimer) put(td *TimerData) {
+	// if no used channel, free list full, discard it
+	if t.used == 0 {
+		// use gc free
+		return
+	}
+	t.used--
+	log.Debug("put timerdata, used: %d", t.used)
+	td.next = t.free
+	t.free = td
+}
@@ -2,21 +2,36 @@ package main
 
 import (
 	log "code.google.com/p/log4go"
+	"encoding/json"
 	"github.com/Terry-Mao/goim/define"
-	"golang.org/x/net/websocket"
+	"github.com/Terry-Mao/goim/libs/websocket"
 	"math/rand"
 	"net"
 	"net/http"
 	"time"
 )
 
+const (
+	// internal operation of the pong frame which refresh the heartbeat
+	websocketOpPong = int32(-3)
+)
+
+var (
+	wsUpgrader *websocket.Upgrader
+)
+
 func InitWebsocket() (err error) {
 	var (
 		listener     *net.TCPListener
 		addr         *net.TCPAddr
 		httpServeMux = http.NewServeMux()
 	)
-	httpServeMux.Handle("/sub", websocket.Handler(serveWebsocket))
+	wsUpgrader = &websocket.Upgrader{
+		Compress:      Conf.WebsocketCompress,
+		CompressLevel: Conf.WebsocketCompressLevel,
+		ReadLimit:     int64(Conf.WebsocketReadLimit),
+	}
+	httpServeMux.HandleFunc("/sub", serveWebsocket)
 	for _, bind := range Conf.WebsocketBind {
 		if addr, err = net.ResolveTCPAddr("tcp4", bind); err != nil {
 			log.Error("net.ResolveTCPAddr(\"tcp4\", \"%s\") error(%v)", bind, err)
@@ -38,33 +53,58 @@ func InitWebsocket() (err error) {
 	return
 }
 
-func serveWebsocket(conn *websocket.Conn) {
+func serveWebsocket(w http.ResponseWriter, r *http.Request) {
 	var (
-		// ip addr
-		lAddr = conn.LocalAddr()
-		rAddr = conn.RemoteAddr()
+		conn *websocket.Conn
+		err  error
 		// timer
 		tr = DefaultServer.round.Timer(rand.Int())
+		ip = remoteIP(r)
 	)
-	log.Debug("start websocket serve \"%s\" with \"%s\"", lAddr, rAddr)
-	DefaultServer.serveWebsocket(conn, tr)
+	// limit before the handshake timer armed
+	if err = connLimiter.Acquire(ip); err != nil {
+		log.Warn("connLimiter.Acquire(\"%s\") error(%v)", ip, err)
+		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer connLimiter.Release(ip)
+	if conn, err = wsUpgrader.Upgrade(w, r); err != nil {
+		log.Error("websocket upgrade \"%s\" error(%v)", r.RemoteAddr, err)
+		return
+	}
+	log.Debug("start websocket serve \"%s\" with \"%s\", compress: %t", conn.LocalAddr(), conn.RemoteAddr(), conn.Compress())
+	DefaultServer.serveWebsocket(conn, r, tr)
 }
 
-func (server *Server) serveWebsocket(conn *websocket.Conn, tr *Timer) {
+func (server *Server) serveWebsocket(conn *websocket.Conn, r *http.Request, tr Timer) {
 	var (
-		b   *Bucket
-		ch  *Channel
-		hb  time.Duration // heartbeat
-		key string
-		err error
-		trd *TimerData
-		p   = new(Proto)
+		b     *Bucket
+		ch    *Channel
+		hb    time.Duration // heartbeat
+		key   string
+		appId int32
+		err   error
+		trd   *TimerData
+		codec BodyCodec
+		ping  *time.Timer
+		data  []byte
+		p     = new(Proto)
 	)
+	// negotiate body codec, use the codec of proto ver if not set
+	if name := r.URL.Query().Get("codec"); name != "" {
+		if codec, err = GetCodec(name); err != nil {
+			log.Error("handshake: GetCodec(\"%s\") error(%v)", name, err)
+			if err = conn.Close(); err != nil {
+				log.Error("handshake: conn.Close() error(%v)", err)
+			}
+			return
+		}
+	}
 	// auth
 	if trd, err = tr.Add(Conf.HandshakeTimeout, conn); err != nil {
 		log.Error("handshake: timer.Add() error(%v)", err)
 	} else {
-		if key, hb, err = server.authWebsocket(conn, p); err != nil {
+		if key, appId, hb, err = server.authWebsocket(conn, codec, p); err != nil {
 			log.Error("handshake: server.auth error(%v)", err)
 		}
 		//deltimer
@@ -80,19 +120,48 @@ func (server *Server) serveWebsocket(conn *websocket.Conn, tr *Timer) {
 	// TODO how to reuse channel
 	// register key->channel
 	b = server.Bucket(key)
-	ch = NewChannel(Conf.CliProto, Conf.SvrProto)
+	ch = NewChannel(Conf.CliProto, Conf.SvrProto, Conf.SvrProtoHigh)
+	ch.AppId = appId
 	b.Put(key, ch)
+	if err := server.operator.Online(key); err != nil {
+		log.Error("%s operator do online error(%v)", key, err)
+	}
+	// the ping and pong frames are the heartbeat, handled in reader
+	conn.SetPingHandler(func(data []byte) error {
+		if err := conn.WriteControl(websocket.PongMessage, data); err != nil {
+			return err
+		}
+		return server.pongWebsocket(ch)
+	})
+	conn.SetPongHandler(func(data []byte) error {
+		return server.pongWebsocket(ch)
+	})
+	if Conf.WebsocketPing > 0 {
+		ping = time.AfterFunc(Conf.WebsocketPing, func() {
+			if err := conn.WriteControl(websocket.PingMessage, nil); err != nil {
+				log.Warn("conn.WriteControl(ping) error(%v)", err)
+				return
+			}
+			ping.Reset(Conf.WebsocketPing)
+		})
+	}
 	// hanshake ok start dispatch goroutine
-	go server.dispatchWebsocket(conn, ch, hb, tr)
+	go server.dispatchWebsocket(key, conn, codec, ch, hb, tr)
 	for {
+		// the ping and pong handlers run in ReadMessage and publish their
+		// own proto, fetch the proto after it returned
+		if data, err = server.readWebsocketMessage(conn); err != nil {
+			log.Error("%s read client request error(%v)", key, err)
+			break
+		}
 		// fetch a proto from channel free list
 		if p, err = ch.CliProto.Set(); err != nil {
 			log.Error("%s fetch client proto error(%v)", key, err)
 			break
 		}
 		// parse request protocol
-		if err = server.readWebsocketRequest(conn, p); err != nil {
-			log.Error("%s read client request error(%v)", key, err)
+		if err = server.decodeWebsocketRequest(data, codec, p); err != nil {
+			log.Error("%s decode client request error(%v)", key, err)
 			break
 		}
 		// send to writer
@@ -106,8 +175,11 @@ func (server *Server) serveWebsocket(conn *websocket.Conn, tr *Timer) {
 	// read & write goroutine
 	// return channel to bucket's free list
 	// may call twice
+	if ping != nil {
+		ping.Stop()
+	}
 	if err = conn.Close(); err != nil {
-		log.Error("reader: conn.Close() error(%v)")
+		log.Error("reader: conn.Close() error(%v)", err)
 	}
 	ch.Finish()
 	b.Del(key)
@@ -121,11 +193,13 @@ func (server *Server) serveWebsocket(conn *websocket.Conn, tr *Timer) {
 // dispatch accepts connections on the listener and serves requests
 // for each incoming connection.  dispatch blocks; the caller typically
 // invokes it in a go statement.
-func (server *Server) dispatchWebsocket(conn *websocket.Conn, ch *Channel, hb time.Duration, tr *Timer) {
+func (server *Server) dispatchWebsocket(key string, conn *websocket.Conn, codec BodyCodec, ch *Channel, hb time.Duration, tr Timer) {
 	var (
 		p   *Proto
 		err error
 		trd *TimerData
+		r   *Ring
+		lim = NewLimiter(Conf.LimitOps)
 	)
 	log.Debug("start dispatch goroutine")
 	if trd, err = tr.Add(hb, conn); err != nil {
@@ -141,7 +215,21 @@ func (server *Server) dispatchWebsocket(conn *websocket.Conn, ch *Channel, hb ti
 			if p, err = ch.CliProto.Get(); err != nil {
 				break
 			}
-			if p.Operation == define.OP_HEARTBEAT {
+			if !lim.Allow(p.Operation) {
+				log.Warn("operation: %d rate limited, action: %s", p.Operation, Conf.LimitAction)
+				if Conf.LimitAction == limitActionDisconnect {
+					err = ErrRateLimit
+					server.writeWebsocketError(conn, codec, p, define.ERR_RATE_LIMIT, err)
+					goto failed
+				}
+				if Conf.LimitAction == limitActionDrop {
+					p.Reset()
+					ch.CliProto.GetAdv()
+					continue
+				}
+				// reply the error and keep the conn
+				errorReply(p, define.ERR_RATE_LIMIT, ErrRateLimit)
+			} else if p.Operation == define.OP_HEARTBEAT || p.Operation == websocketOpPong {
 				// Use a previous timer value if difference between it and a new
 				// value is less than TIMER_LAZY_DELAY milliseconds: this allows
 				// to minimize the minheap operations for fast connections.
@@ -152,34 +240,49 @@ func (server *Server) dispatchWebsocket(conn *websocket.Conn, ch *Channel, hb ti
 						goto failed
 					}
 				}
+				if p.Operation == websocketOpPong {
+					// the pong frame already replied
+					p.Reset()
+					ch.CliProto.GetAdv()
+					continue
+				}
 				// heartbeat
 				p.Body = nil
 				p.Operation = define.OP_HEARTBEAT_REPLY
+			} else if p.Operation == define.OP_ACK {
+				if err = server.operator.Ack(key, p); err != nil {
+					log.Error("operator.Ack() error(%v)", err)
+				}
 			} else {
 				// process message
 				if err = server.operator.Operate(p); err != nil {
 					log.Error("operator.Operate() error(%v)", err)
+					server.writeWebsocketError(conn, codec, p, define.ERR_OPERATION, err)
 					goto failed
 				}
 			}
-			if err = server.writeWebsocketResponse(conn, p); err != nil {
+			if err = server.writeWebsocketResponse(conn, codec, p); err != nil {
 				log.Error("server.sendTCPResponse() error(%v)", err)
 				goto failed
 			}
 			ch.CliProto.GetAdv()
 		}
-		// fetch message from svrbox(server send)
+		// fetch message from svrbox(server send), high priority first
 		for {
-			if p, err = ch.SvrProto.Get(); err != nil {
-				log.Warn("ch.SvrProto.Get() error(%v)", err)
+			if p, r, err = ch.SvrProtoGet(); err != nil {
+				log.Warn("ch.SvrProtoGet() error(%v)", err)
 				break
 			}
 			// just forward the message
-			if err = server.writeWebsocketResponse(conn, p); err != nil {
+			if err = server.writeWebsocketResponse(conn, codec, p); err != nil {
 				log.Error("server.sendTCPResponse() error(%v)", err)
 				goto failed
 			}
-			ch.SvrProto.GetAdv()
+			r.GetAdv()
+		}
+		// kicked by the server after the error reply written
+		if ch.Closing() {
+			goto failed
 		}
 	}
 failed:
@@ -194,43 +297,100 @@ failed:
 }
 
 // auth for goim handshake with client, use rsa & aes.
-func (server *Server) authWebsocket(conn *websocket.Conn, p *Proto) (subKey string, heartbeat time.Duration, err error) {
-	if err = server.readWebsocketRequest(conn, p); err != nil {
+func (server *Server) authWebsocket(conn *websocket.Conn, codec BodyCodec, p *Proto) (subKey string, appId int32, heartbeat time.Duration, err error) {
+	if err = server.readWebsocketRequest(conn, codec, p); err != nil {
 		return
 	}
 	if p.Operation != define.OP_AUTH {
 		log.Warn("auth operation not valid: %d", p.Operation)
 		err = ErrOperation
+		server.writeWebsocketError(conn, codec, p, define.ERR_OPERATION, err)
 		return
 	}
-	if subKey, heartbeat, err = server.operator.Connect(p); err != nil {
+	if server.Draining() {
+		err = ErrDraining
+		server.writeWebsocketError(conn, codec, p, define.ERR_DRAINING, err)
+		return
+	}
+	if subKey, appId, heartbeat, err = server.operator.Connect(p); err != nil {
 		log.Error("operator.Connect error(%v)", err)
+		server.writeWebsocketError(conn, codec, p, define.ERR_AUTH, err)
 		return
 	}
-	p.Body = nil
+	p.Body = authReplyBody(heartbeat)
 	p.Operation = define.OP_AUTH_REPLY
-	if err = server.writeWebsocketResponse(conn, p); err != nil {
+	if err = server.writeWebsocketResponse(conn, codec, p); err != nil {
 		log.Error("[%s] server.sendTCPResponse() error(%v)", subKey, err)
 	}
 	return
 }
 
-// readRequest
-func (server *Server) readWebsocketRequest(conn *websocket.Conn, proto *Proto) (err error) {
-	if err = websocket.JSON.Receive(conn, proto); err != nil {
-		log.Error("websocket.JSON.Receive() error(%v)", err)
+// readRequest, a message exceeds the read limit is closed with the 1009
+// close code by the websocket conn.
+func (server *Server) readWebsocketRequest(conn *websocket.Conn, codec BodyCodec, proto *Proto) (err error) {
+	var data []byte
+	if data, err = server.readWebsocketMessage(conn); err != nil {
+		return
+	}
+	return server.decodeWebsocketRequest(data, codec, proto)
+}
+
+// readWebsocketMessage read a data message, the control frames are handled
+// by the handlers in it.
+func (server *Server) readWebsocketMessage(conn *websocket.Conn) (data []byte, err error) {
+	if _, data, err = conn.ReadMessage(); err != nil {
+		log.Error("conn.ReadMessage() error(%v)", err)
+	}
+	return
+}
+
+// decodeWebsocketRequest parse the json proto and decode the body.
+func (server *Server) decodeWebsocketRequest(data []byte, codec BodyCodec, proto *Proto) (err error) {
+	if err = json.Unmarshal(data, proto); err != nil {
+		log.Error("json.Unmarshal(\"%s\") error(%v)", data, err)
+		return
+	}
+	if proto.Body, err = bodyCodec(codec, proto.Ver).Decode(proto.Body); err != nil {
+		log.Error("codec.Decode() error(%v)", err)
 	}
 	return
 }
 
 // sendResponse send resp to client, sendResponse must be goroutine safe.
-func (server *Server) writeWebsocketResponse(conn *websocket.Conn, proto *Proto) (err error) {
-	if proto.Body == nil {
-		proto.Body = emptyJSONBody
+func (server *Server) writeWebsocketResponse(conn *websocket.Conn, codec BodyCodec, proto *Proto) (err error) {
+	var data []byte
+	if proto.Body, err = bodyCodec(codec, proto.Ver).Encode(proto.Body); err != nil {
+		log.Error("codec.Encode() error(%v)", err)
+		return
 	}
-	if err = websocket.JSON.Send(conn, proto); err != nil {
-		log.Error("websocket.JSON.Send() error(%v)", err)
+	if data, err = json.Marshal(proto); err != nil {
+		log.Error("json.Marshal() error(%v)", err)
+		return
+	}
+	if err = conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		log.Error("conn.WriteMessage() error(%v)", err)
 	}
 	proto.Reset()
 	return
 }
+
+// writeWebsocketError write the error reply before the conn closed.
+func (server *Server) writeWebsocketError(conn *websocket.Conn, codec BodyCodec, proto *Proto, code int32, err error) {
+	errorReply(proto, code, err)
+	if err = server.writeWebsocketResponse(conn, codec, proto); err != nil {
+		log.Error("server.writeWebsocketResponse() error(%v)", err)
+	}
+}
+
+// pongWebsocket send a pong proto to the writer to refresh the heartbeat, it
+// runs in ReadMessage before the reader fetch its proto.
+func (server *Server) pongWebsocket(ch *Channel) (err error) {
+	var p *Proto
+	if p, err = ch.CliProto.Set(); err != nil {
+		return
+	}
+	p.Operation = websocketOpPong
+	ch.CliProto.SetAdv()
+	ch.Signal()
+	return
+}
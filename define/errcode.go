@@ -0,0 +1,11 @@
+package define
+
+// the code of OP_ERROR_REPLY, body is {"code":1,"msg":"xxx"}
+const (
+	ERR_AUTH         = int32(1) // auth rejected
+	ERR_OPERATION    = int32(2) // unknown operation
+	ERR_RATE_LIMIT   = int32(3) // operation rate limited
+	ERR_DRAINING     = int32(4) // server draining, reconnect another one
+	ERR_FRAME_LARGE  = int32(5) // frame too large
+	ERR_SESSION_LOST = int32(6) // session purged by the router, reconnect
+)
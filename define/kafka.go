@@ -2,6 +2,10 @@ package define
 
 // Kafka message type Commands
 const (
-	KAFKA_MESSAGE_MULTI     = "multiple"  //multi-userid push
-	KAFKA_MESSAGE_BROADCAST = "broadcast" //broadcast push
+	KAFKA_MESSAGE_MULTI      = "multiple"      //multi-userid push
+	KAFKA_MESSAGE_MULTI_MSGS = "multiple_msgs" //multi-subkey push, every subkey has its own message
+	KAFKA_MESSAGE_BROADCAST  = "broadcast"     //broadcast push
+	// the app scoped broadcast push, the value is proto.BroadcastMsg, the
+	// raw broadcast push is the app 0
+	KAFKA_MESSAGE_BROADCAST_APP = "broadcast_app"
 )
@@ -18,6 +18,13 @@ const (
 	// handshake with sid
 	OP_HANDSHAKE_SID       = int32(9)
 	OP_HANDSHAKE_SID_REPLY = int32(10)
+	// 11 was the rate limit reply, reserved, use OP_ERROR_REPLY with
+	// ERR_RATE_LIMIT
+	// ack the pushed message, body is the message id
+	OP_ACK       = int32(12)
+	OP_ACK_REPLY = int32(13)
+	// error reply before the server close the connection
+	OP_ERROR_REPLY = int32(14)
 
 	// for test
 	OP_TEST       = int32(254)
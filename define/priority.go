@@ -0,0 +1,8 @@
+package define
+
+// Push message priority, the high priority messages are sent to client
+// before the normal ones, such as kick or auth refresh.
+const (
+	PRIORITY_NORMAL = int32(0)
+	PRIORITY_HIGH   = int32(1)
+)
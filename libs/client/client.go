@@ -0,0 +1,469 @@
+// Package client is the goim client, it connects the comet by tcp, websocket
+// or http long polling, keeps the heartbeat, reconnects with backoff and
+// matches the replies of the requests by the sequence id.
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Terry-Mao/goim/define"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultVer            = int16(1)
+	defaultDialTimeout    = 5 * time.Second
+	defaultRequestTimeout = 5 * time.Second
+	defaultHeartbeat      = 30 * time.Second
+	defaultMinBackoff     = 1 * time.Second
+	defaultMaxBackoff     = 30 * time.Second
+	defaultMessageBuffer  = 1024
+)
+
+var (
+	ErrNetwork      = errors.New("client: network not support")
+	ErrAuth         = errors.New("client: auth failed")
+	ErrClosed       = errors.New("client: closed")
+	ErrTimeout      = errors.New("client: request timeout")
+	ErrDisconnected = errors.New("client: disconnected")
+	ErrNotSupport   = errors.New("client: not support by the network")
+)
+
+// Error is the error reply of the comet, the comet close the connection
+// after it. The codes are define.ERR_*.
+type Error struct {
+	Code int32  `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func (e *Error) Error() string {
+	return "client: comet error(" + strconv.FormatInt(int64(e.Code), 10) + "): " + e.Msg
+}
+
+// replyError return the error of the OP_ERROR_REPLY proto.
+func replyError(p *Proto) error {
+	e := new(Error)
+	if err := json.Unmarshal(p.Body, e); err != nil {
+		return err
+	}
+	return e
+}
+
+// Options is the client options, the zero value use the default.
+type Options struct {
+	// Network is tcp, ws or http(long polling, receive only).
+	Network string
+	// Addr is the comet address, such as localhost:8080.
+	Addr string
+	// Token is the auth token.
+	Token string
+	// Ver is the protocol version, 1 by default.
+	Ver int16
+	// Heartbeat override the heartbeat interval, by default it's half of
+	// the heartbeat negotiated by auth.
+	Heartbeat time.Duration
+	// DialTimeout is the timeout of dial and auth.
+	DialTimeout time.Duration
+	// RequestTimeout is the timeout of waiting the reply of Request.
+	RequestTimeout time.Duration
+	// Reconnect reconnect with backoff when the connection broken.
+	Reconnect bool
+	// MinBackoff and MaxBackoff bound the backoff, it doubles every failed
+	// reconnect.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnMessage is called in the reader goroutine with the pushed messages,
+	// if nil the messages are sent to the Messages channel.
+	OnMessage func(p *Proto)
+	// MessageBuffer is the size of the Messages channel.
+	MessageBuffer int
+}
+
+// Client is a goim client, it's goroutine safe.
+type Client struct {
+	opts      Options
+	heartbeat time.Duration
+	msgs      chan *Proto
+	quit      chan struct{}
+	wLock     sync.Mutex // serialize the writes
+	lock      sync.Mutex // protect the fields below
+	conn      conn
+	seqId     int32
+	pending   map[int32]chan *Proto
+	closed    bool
+}
+
+// Dial connect and auth the comet, the client keeps the heartbeat and
+// reconnects if Options.Reconnect is set until Close.
+func Dial(opts Options) (c *Client, err error) {
+	var cn conn
+	if opts.Network != NetworkTCP && opts.Network != NetworkWebsocket && opts.Network != NetworkHTTP {
+		return nil, ErrNetwork
+	}
+	if opts.Ver == 0 {
+		opts.Ver = defaultVer
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = defaultDialTimeout
+	}
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = defaultRequestTimeout
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = defaultMaxBackoff
+		if opts.MaxBackoff < opts.MinBackoff {
+			opts.MaxBackoff = opts.MinBackoff
+		}
+	}
+	if opts.MessageBuffer <= 0 {
+		opts.MessageBuffer = defaultMessageBuffer
+	}
+	c = &Client{
+		opts:    opts,
+		quit:    make(chan struct{}),
+		pending: make(map[int32]chan *Proto),
+	}
+	if opts.OnMessage == nil {
+		c.msgs = make(chan *Proto, opts.MessageBuffer)
+	}
+	if cn, err = c.connect(); err != nil {
+		return nil, err
+	}
+	c.conn = cn
+	go c.serve(cn)
+	return
+}
+
+// Messages return the pushed messages, it's closed when the client closed
+// or disconnected without reconnect, nil if Options.OnMessage is set.
+func (c *Client) Messages() <-chan *Proto {
+	return c.msgs
+}
+
+// Heartbeat return the heartbeat interval of the current connection.
+func (c *Client) Heartbeat() time.Duration {
+	c.lock.Lock()
+	hb := c.heartbeat
+	c.lock.Unlock()
+	return hb
+}
+
+// Request send the operation and wait the reply with the same sequence id.
+func (c *Client) Request(operation int32, body []byte) (reply *Proto, err error) {
+	var (
+		cn    conn
+		seqId int32
+		ch    = make(chan *Proto, 1)
+	)
+	if cn, seqId, err = c.prepare(ch); err != nil {
+		return
+	}
+	if err = c.write(cn, &Proto{Ver: c.opts.Ver, Operation: operation, SeqId: seqId, Body: body}); err != nil {
+		c.cancel(seqId)
+		return
+	}
+	timer := time.NewTimer(c.opts.RequestTimeout)
+	select {
+	case reply = <-ch:
+		if reply == nil {
+			err = ErrDisconnected
+		} else if reply.Operation == define.OP_ERROR_REPLY {
+			err = replyError(reply)
+			reply = nil
+		}
+	case <-timer.C:
+		c.cancel(seqId)
+		err = ErrTimeout
+	}
+	timer.Stop()
+	return
+}
+
+// Send send the operation without waiting the reply.
+func (c *Client) Send(operation int32, body []byte) (err error) {
+	var (
+		cn    conn
+		seqId int32
+	)
+	if cn, seqId, err = c.prepare(nil); err != nil {
+		return
+	}
+	return c.write(cn, &Proto{Ver: c.opts.Ver, Operation: operation, SeqId: seqId, Body: body})
+}
+
+// Ack ack the pushed message by the message id, the ack reply is ignored.
+func (c *Client) Ack(msgId int64) error {
+	return c.Send(define.OP_ACK, []byte(strconv.FormatInt(msgId, 10)))
+}
+
+// Close close the client, the pending requests fail with ErrDisconnected.
+func (c *Client) Close() (err error) {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.quit)
+	if c.conn != nil {
+		err = c.conn.Close()
+	}
+	c.lock.Unlock()
+	return
+}
+
+// prepare alloc the sequence id, register the reply chan if not nil.
+func (c *Client) prepare(ch chan *Proto) (cn conn, seqId int32, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		err = ErrClosed
+		return
+	}
+	if c.conn == nil {
+		err = ErrDisconnected
+		return
+	}
+	// seqId 0 is used by auth and the pushed messages
+	if c.seqId++; c.seqId <= 0 {
+		c.seqId = 1
+	}
+	seqId = c.seqId
+	if ch != nil {
+		c.pending[seqId] = ch
+	}
+	cn = c.conn
+	return
+}
+
+func (c *Client) cancel(seqId int32) {
+	c.lock.Lock()
+	delete(c.pending, seqId)
+	c.lock.Unlock()
+}
+
+func (c *Client) write(cn conn, p *Proto) (err error) {
+	c.wLock.Lock()
+	err = cn.WriteProto(p)
+	c.wLock.Unlock()
+	return
+}
+
+// connect dial and auth, the heartbeat is negotiated by the auth reply.
+func (c *Client) connect() (cn conn, err error) {
+	var (
+		hb = c.opts.Heartbeat
+		p  = new(Proto)
+	)
+	switch c.opts.Network {
+	case NetworkHTTP:
+		// every poll auth itself, no heartbeat
+		if cn, err = dialHTTP(c.opts.Addr, c.opts.Ver, c.opts.Token, c.opts.DialTimeout); err != nil {
+			return
+		}
+		hb = 0
+		goto done
+	case NetworkWebsocket:
+		if cn, err = dialWebsocket(c.opts.Addr, c.opts.DialTimeout); err != nil {
+			return
+		}
+		// the body of websocket must be json
+		if p.Body, err = json.Marshal(c.opts.Token); err != nil {
+			goto failed
+		}
+	default:
+		if cn, err = dialTCP(c.opts.Addr, c.opts.DialTimeout); err != nil {
+			return
+		}
+		p.Body = []byte(c.opts.Token)
+	}
+	p.Ver = c.opts.Ver
+	p.Operation = define.OP_AUTH
+	if err = cn.SetReadDeadline(time.Now().Add(c.opts.DialTimeout)); err != nil {
+		goto failed
+	}
+	if err = cn.WriteProto(p); err != nil {
+		goto failed
+	}
+	if err = cn.ReadProto(p); err != nil {
+		goto failed
+	}
+	if p.Operation == define.OP_ERROR_REPLY {
+		err = replyError(p)
+		goto failed
+	}
+	if p.Operation != define.OP_AUTH_REPLY {
+		err = ErrAuth
+		goto failed
+	}
+	if hb <= 0 {
+		hb = authHeartbeat(p.Body)
+	}
+done:
+	c.lock.Lock()
+	c.heartbeat = hb
+	c.lock.Unlock()
+	return
+failed:
+	cn.Close()
+	return nil, err
+}
+
+// authHeartbeat return half of the heartbeat in the auth reply, the comet
+// close the connection if no heartbeat in it.
+func authHeartbeat(body []byte) time.Duration {
+	var reply struct {
+		Heartbeat int64 `json:"heartbeat"`
+	}
+	if err := json.Unmarshal(body, &reply); err != nil || reply.Heartbeat <= 0 {
+		return defaultHeartbeat
+	}
+	return time.Duration(reply.Heartbeat) * time.Second / 2
+}
+
+// serve read the connection, reconnect if broken.
+func (c *Client) serve(cn conn) {
+	var (
+		err  error
+		done chan struct{}
+	)
+	for {
+		done = make(chan struct{})
+		go c.keepalive(cn, c.Heartbeat(), done)
+		err = c.read(cn)
+		close(done)
+		cn.Close()
+		c.lock.Lock()
+		c.conn = nil
+		// fail the pending requests
+		for seqId, ch := range c.pending {
+			close(ch)
+			delete(c.pending, seqId)
+		}
+		c.lock.Unlock()
+		if err == ErrAuth || !c.opts.Reconnect {
+			break
+		}
+		if e, ok := err.(*Error); ok && e.Code == define.ERR_AUTH {
+			break
+		}
+		if cn = c.reconnect(); cn == nil {
+			break
+		}
+	}
+	c.Close()
+	if c.msgs != nil {
+		close(c.msgs)
+	}
+}
+
+// reconnect connect with backoff until succeed or closed.
+func (c *Client) reconnect() (cn conn) {
+	var (
+		err     error
+		backoff = c.opts.MinBackoff
+	)
+	for {
+		select {
+		case <-c.quit:
+			return nil
+		case <-time.After(backoff):
+		}
+		if cn, err = c.connect(); err == nil {
+			c.lock.Lock()
+			if c.closed {
+				c.lock.Unlock()
+				cn.Close()
+				return nil
+			}
+			c.conn = cn
+			c.lock.Unlock()
+			return
+		}
+		if backoff *= 2; backoff > c.opts.MaxBackoff {
+			backoff = c.opts.MaxBackoff
+		}
+	}
+}
+
+// keepalive send the heartbeat every interval until done.
+func (c *Client) keepalive(cn conn, hb time.Duration, done chan struct{}) {
+	if hb <= 0 {
+		return
+	}
+	ticker := time.NewTicker(hb)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		if err := c.write(cn, &Proto{Ver: c.opts.Ver, Operation: define.OP_HEARTBEAT}); err != nil {
+			cn.Close()
+			return
+		}
+	}
+}
+
+// read dispatch the replies and the pushed messages, the heartbeat reply
+// is expected in two heartbeat intervals.
+func (c *Client) read(cn conn) (err error) {
+	hb := c.Heartbeat()
+	for {
+		if hb > 0 {
+			if err = cn.SetReadDeadline(time.Now().Add(2 * hb)); err != nil {
+				return
+			}
+		} else {
+			cn.SetReadDeadline(time.Time{})
+		}
+		p := new(Proto)
+		if err = cn.ReadProto(p); err != nil {
+			return
+		}
+		if p.Operation == define.OP_HEARTBEAT_REPLY || p.Operation == define.OP_ACK_REPLY {
+			continue
+		}
+		if p.Operation == define.OP_ERROR_REPLY {
+			// the rate limited request fail only, the conn is kept unless
+			// the comet close it
+			if e, ok := replyError(p).(*Error); ok && e.Code == define.ERR_RATE_LIMIT && p.SeqId != 0 {
+				c.reply(p)
+				continue
+			}
+			return replyError(p)
+		}
+		if p.SeqId != 0 && c.reply(p) {
+			continue
+		}
+		if c.opts.OnMessage != nil {
+			c.opts.OnMessage(p)
+			continue
+		}
+		select {
+		case c.msgs <- p:
+		case <-c.quit:
+			return ErrClosed
+		}
+	}
+}
+
+// reply send the proto to the pending request, false if not found.
+func (c *Client) reply(p *Proto) (ok bool) {
+	var ch chan *Proto
+	c.lock.Lock()
+	if ch, ok = c.pending[p.SeqId]; ok {
+		delete(c.pending, p.SeqId)
+	}
+	c.lock.Unlock()
+	if ok {
+		ch <- p
+	}
+	return
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"bufio"
+	"github.com/Terry-Mao/goim/define"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveTest is a fake comet, it reply the auth with heartbeat, echo the
+// OP_TEST with a push before the reply, rate limit the OP_SEND_SMS and close
+// the first connection after the first request.
+func serveTest(t *testing.T, l net.Listener) {
+	for i := 0; ; i++ {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn, first bool) {
+			defer conn.Close()
+			rd, wr := bufio.NewReader(conn), bufio.NewWriter(conn)
+			p := new(Proto)
+			if err := readTCP(rd, p); err != nil || p.Operation != define.OP_AUTH || string(p.Body) != "test" {
+				t.Errorf("auth proto: %v, error(%v)", p, err)
+				return
+			}
+			p.Operation = define.OP_AUTH_REPLY
+			p.Body = []byte("{\"heartbeat\":60}")
+			writeTCP(wr, p)
+			wr.Flush()
+			for {
+				if err := readTCP(rd, p); err != nil {
+					return
+				}
+				if p.Operation == define.OP_SEND_SMS {
+					p.Operation = define.OP_ERROR_REPLY
+					p.Body = []byte("{\"code\":3,\"msg\":\"rate limit\"}")
+					writeTCP(wr, p)
+					wr.Flush()
+					continue
+				}
+				if p.Operation != define.OP_TEST {
+					continue
+				}
+				push := &Proto{Ver: 1, Operation: define.OP_SEND_SMS_REPLY, MsgId: 1<<40 + 1, Body: []byte("push")}
+				writeTCP(wr, push)
+				p.Operation = define.OP_TEST_REPLY
+				writeTCP(wr, p)
+				wr.Flush()
+				if first {
+					return
+				}
+			}
+		}(conn, i == 0)
+	}
+}
+
+func TestClient(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serveTest(t, l)
+	c, err := Dial(Options{Network: NetworkTCP, Addr: l.Addr().String(), Token: "test", Reconnect: true, MinBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if hb := c.Heartbeat(); hb != 30*time.Second {
+		t.Fatalf("heartbeat: %s", hb)
+	}
+	reply, err := c.Request(define.OP_TEST, []byte("hello"))
+	if err != nil || reply.Operation != define.OP_TEST_REPLY || string(reply.Body) != "hello" {
+		t.Fatalf("reply: %v, error(%v)", reply, err)
+	}
+	if p := <-c.Messages(); p.Operation != define.OP_SEND_SMS_REPLY || p.MsgId != 1<<40+1 || string(p.Body) != "push" {
+		t.Fatalf("push: %v", p)
+	}
+	// the first connection closed, wait reconnect
+	for i := 0; ; i++ {
+		if reply, err = c.Request(define.OP_TEST, []byte("again")); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("reconnect error(%v)", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if reply.SeqId == 0 || string(reply.Body) != "again" {
+		t.Fatalf("reply: %v", reply)
+	}
+	// the rate limited request fail and the conn is kept
+	if _, err = c.Request(define.OP_SEND_SMS, nil); err == nil || err.(*Error).Code != define.ERR_RATE_LIMIT {
+		t.Fatalf("rate limited request error(%v)", err)
+	}
+	if reply, err = c.Request(define.OP_TEST, []byte("kept")); err != nil || string(reply.Body) != "kept" {
+		t.Fatalf("reply: %v, error(%v)", reply, err)
+	}
+	c.Close()
+	if _, err = c.Request(define.OP_TEST, nil); err != ErrClosed {
+		t.Fatalf("closed request error(%v)", err)
+	}
+}
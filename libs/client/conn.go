@@ -0,0 +1,203 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Terry-Mao/goim/define"
+	"golang.org/x/net/websocket"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	NetworkTCP       = "tcp"
+	NetworkWebsocket = "ws"
+	NetworkHTTP      = "http"
+)
+
+// conn is the transport of the client, WriteProto is serialized by client.
+type conn interface {
+	ReadProto(p *Proto) error
+	WriteProto(p *Proto) error
+	SetReadDeadline(t time.Time) error
+	Close() error
+}
+
+// tcpConn is the binary framed tcp transport.
+type tcpConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+	wr   *bufio.Writer
+}
+
+func dialTCP(addr string, timeout time.Duration) (conn, error) {
+	c, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &tcpConn{conn: c, rd: bufio.NewReader(c), wr: bufio.NewWriter(c)}, nil
+}
+
+func (c *tcpConn) ReadProto(p *Proto) error {
+	return readTCP(c.rd, p)
+}
+
+func (c *tcpConn) WriteProto(p *Proto) (err error) {
+	if err = writeTCP(c.wr, p); err != nil {
+		return
+	}
+	return c.wr.Flush()
+}
+
+func (c *tcpConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *tcpConn) Close() error {
+	return c.conn.Close()
+}
+
+// wsConn is the websocket transport, the proto is a json text message.
+type wsConn struct {
+	conn *websocket.Conn
+}
+
+func dialWebsocket(addr string, timeout time.Duration) (conn, error) {
+	config, err := websocket.NewConfig("ws://"+addr+"/sub", "http://"+addr+"/sub")
+	if err != nil {
+		return nil, err
+	}
+	config.Dialer = &net.Dialer{Timeout: timeout}
+	c, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &wsConn{conn: c}, nil
+}
+
+func (c *wsConn) ReadProto(p *Proto) error {
+	p.Body = nil
+	return websocket.JSON.Receive(c.conn, p)
+}
+
+func (c *wsConn) WriteProto(p *Proto) error {
+	// the body of websocket must be json
+	if p.Body == nil {
+		p.Body = []byte("{}")
+	}
+	return websocket.JSON.Send(c.conn, p)
+}
+
+func (c *wsConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
+
+// httpConn is the http long polling transport, every poll auth again and
+// the comet reply a message then close the connection. The comet write the
+// raw json without the http response header, so the poll is a plain tcp
+// request. A message pushed between two polls is lost.
+type httpConn struct {
+	addr    string
+	query   string
+	timeout time.Duration
+	lock    sync.Mutex
+	conn    net.Conn
+	closed  bool
+}
+
+func dialHTTP(addr string, ver int16, token string, timeout time.Duration) (conn, error) {
+	params := url.Values{}
+	params.Set("ver", fmt.Sprint(ver))
+	params.Set("op", fmt.Sprint(define.OP_AUTH))
+	params.Set("seq", "0")
+	params.Set("t", token)
+	c := &httpConn{addr: addr, query: params.Encode(), timeout: timeout}
+	if err := c.poll(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// poll send the long polling request.
+func (c *httpConn) poll() (err error) {
+	var conn net.Conn
+	if conn, err = net.DialTimeout("tcp", c.addr, c.timeout); err != nil {
+		return
+	}
+	if _, err = fmt.Fprintf(conn, "GET /sub?%s HTTP/1.1\r\nHost: %s\r\n\r\n", c.query, c.addr); err != nil {
+		conn.Close()
+		return
+	}
+	c.lock.Lock()
+	if c.closed {
+		err = ErrClosed
+		conn.Close()
+	} else {
+		c.conn = conn
+	}
+	c.lock.Unlock()
+	return
+}
+
+func (c *httpConn) ReadProto(p *Proto) (err error) {
+	var b []byte
+	for {
+		b, err = ioutil.ReadAll(c.conn)
+		c.conn.Close()
+		if err != nil {
+			return
+		}
+		// the http error reply, such as auth failed
+		if bytes.HasPrefix(b, []byte("HTTP/")) {
+			return readHTTPError(b, p)
+		}
+		// poll again before parse, reduce the lost window
+		if err = c.poll(); err != nil {
+			return
+		}
+		// empty reply when the poll timeout
+		if len(b) == 0 {
+			continue
+		}
+		p.Body = nil
+		return json.Unmarshal(b, p)
+	}
+}
+
+// readHTTPError parse the error reply in the http response body.
+func readHTTPError(b []byte, p *Proto) error {
+	if i := bytes.Index(b, []byte("\r\n\r\n")); i >= 0 {
+		p.Body = nil
+		if json.Unmarshal(b[i+4:], p) == nil && p.Operation == define.OP_ERROR_REPLY {
+			return nil
+		}
+	}
+	return ErrAuth
+}
+
+func (c *httpConn) WriteProto(p *Proto) error {
+	return ErrNotSupport
+}
+
+func (c *httpConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *httpConn) Close() (err error) {
+	c.lock.Lock()
+	c.closed = true
+	if c.conn != nil {
+		err = c.conn.Close()
+	}
+	c.lock.Unlock()
+	return
+}
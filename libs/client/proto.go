@@ -0,0 +1,106 @@
+package client
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/Terry-Mao/goim/define"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	// the tcp frame: packLen(4) headerLen(2) ver(2) operation(4) seqId(4)
+	// [msgId(8)] body, the message id only in the pushed message
+	packLenSize   = 4
+	headerLenSize = 2
+	verSize       = 2
+	operationSize = 4
+	seqIdSize     = 4
+	msgIdSize     = 8
+	rawHeaderLen  = packLenSize + headerLenSize + verSize + operationSize + seqIdSize
+	maxBodySize   = 1 << 20
+)
+
+var (
+	ErrProtoPackLen   = errors.New("client: proto pack length error")
+	ErrProtoHeaderLen = errors.New("client: proto header length error")
+)
+
+// Proto is the goim protocol, same as the comet.
+type Proto struct {
+	Ver       int16           `json:"ver"`           // protocol version
+	Operation int32           `json:"op"`            // operation for request
+	SeqId     int32           `json:"seq"`           // sequence number chosen by client
+	MsgId     int64           `json:"mid,omitempty"` // message id, ack it by Client.Ack
+	Body      json.RawMessage `json:"body"`          // binary body bytes(json.RawMessage is []byte)
+}
+
+// writeTCP write the proto with the tcp frame, the auth is written with the
+// message id header to negotiate it.
+func writeTCP(wr io.Writer, p *Proto) (err error) {
+	var (
+		ext       = p.MsgId != 0 || p.Operation == define.OP_AUTH
+		headerLen = rawHeaderLen
+	)
+	if ext {
+		headerLen += msgIdSize
+	}
+	buf := make([]byte, headerLen+len(p.Body))
+	binary.BigEndian.PutUint32(buf[0:], uint32(headerLen+len(p.Body)))
+	binary.BigEndian.PutUint16(buf[4:], uint16(headerLen))
+	binary.BigEndian.PutUint16(buf[6:], uint16(p.Ver))
+	binary.BigEndian.PutUint32(buf[8:], uint32(p.Operation))
+	binary.BigEndian.PutUint32(buf[12:], uint32(p.SeqId))
+	if ext {
+		binary.BigEndian.PutUint64(buf[rawHeaderLen:], uint64(p.MsgId))
+	}
+	copy(buf[headerLen:], p.Body)
+	_, err = wr.Write(buf)
+	return
+}
+
+// readTCP read the proto with the tcp frame.
+func readTCP(rd io.Reader, p *Proto) (err error) {
+	var (
+		packLen   int
+		headerLen int
+		buf       = make([]byte, rawHeaderLen)
+	)
+	if _, err = io.ReadFull(rd, buf); err != nil {
+		return
+	}
+	packLen = int(binary.BigEndian.Uint32(buf[0:]))
+	headerLen = int(binary.BigEndian.Uint16(buf[4:]))
+	p.Ver = int16(binary.BigEndian.Uint16(buf[6:]))
+	p.Operation = int32(binary.BigEndian.Uint32(buf[8:]))
+	p.SeqId = int32(binary.BigEndian.Uint32(buf[12:]))
+	if headerLen < rawHeaderLen {
+		return ErrProtoHeaderLen
+	}
+	if packLen < headerLen || packLen-headerLen > maxBodySize {
+		return ErrProtoPackLen
+	}
+	p.MsgId = 0
+	skip := headerLen - rawHeaderLen
+	if skip >= msgIdSize {
+		if _, err = io.ReadFull(rd, buf[:msgIdSize]); err != nil {
+			return
+		}
+		p.MsgId = int64(binary.BigEndian.Uint64(buf))
+		skip -= msgIdSize
+	}
+	// skip the unknown header
+	if skip > 0 {
+		if _, err = io.CopyN(ioutil.Discard, rd, int64(skip)); err != nil {
+			return
+		}
+	}
+	if packLen > headerLen {
+		p.Body = make([]byte, packLen-headerLen)
+		_, err = io.ReadFull(rd, p.Body)
+	} else {
+		p.Body = nil
+	}
+	return
+}
@@ -0,0 +1,144 @@
+// Package comets is the rpc clients of the comets by server id, shared by
+// the job and the logic direct queue to push the messages to the comets.
+package comets
+
+import (
+	log "code.google.com/p/log4go"
+	"errors"
+	"github.com/Terry-Mao/goim/define"
+	inet "github.com/Terry-Mao/goim/libs/net"
+	"github.com/Terry-Mao/goim/libs/registry"
+	"github.com/Terry-Mao/protorpc"
+	"sync"
+)
+
+const (
+	ServicePing      = "PushRPC.Ping"
+	ServicePushMsg   = "PushRPC.PushMsg"
+	ServicePushMsgs  = "PushRPC.PushMsgs"
+	ServiceMPushMsg  = "PushRPC.MPushMsg"
+	ServiceMPushMsgs = "PushRPC.MPushMsgs"
+	ServiceBroadcast = "PushRPC.Broadcast"
+)
+
+var (
+	ErrComet = errors.New("comet rpc is not available")
+)
+
+// comet is the rpc client of a comet, reconnected in background until quit
+// closed.
+type comet struct {
+	addr   string
+	client *protorpc.Client
+	quit   chan struct{}
+}
+
+// Comets is the comet clients, the comets join and leave at runtime by Add,
+// Del or Sync.
+type Comets struct {
+	lock   sync.RWMutex
+	comets map[int32]*comet
+}
+
+func New() *Comets {
+	return &Comets{comets: make(map[int32]*comet)}
+}
+
+// Sync connect the new or changed comets of the registry and close the left
+// ones.
+func (c *Comets) Sync(nodes []*registry.Node) {
+	alive := make(map[int32]bool, len(nodes))
+	for _, n := range nodes {
+		alive[n.ServerId] = true
+		if err := c.Add(n.ServerId, n.Addr); err != nil {
+			log.Error("comets.Add(%d, \"%s\") error(%v)", n.ServerId, n.Addr, err)
+		}
+	}
+	for _, serverId := range c.Servers() {
+		if !alive[serverId] {
+			c.Del(serverId)
+		}
+	}
+}
+
+// Add connect the comet, the existing one is replaced if the addr changed,
+// the comet not available now is reconnected in background.
+func (c *Comets) Add(serverId int32, addrs string) (err error) {
+	var (
+		network, addr string
+		cm            *comet
+		ok            bool
+	)
+	c.lock.RLock()
+	cm, ok = c.comets[serverId]
+	c.lock.RUnlock()
+	if ok && cm.addr == addrs {
+		return
+	}
+	if network, addr, err = inet.ParseNetwork(addrs); err != nil {
+		log.Error("inet.ParseNetwork() error(%v)", err)
+		return
+	}
+	if ok {
+		c.Del(serverId)
+	}
+	cm = &comet{addr: addrs, quit: make(chan struct{}, 1)}
+	if cm.client, err = protorpc.Dial(network, addr); err != nil {
+		log.Error("protorpc.Dial(\"%s\", \"%s\") error(%s)", network, addr, err)
+		err = nil
+	} else {
+		log.Info("comet rpc addr:%s connected", addr)
+	}
+	go protorpc.Reconnect(&cm.client, cm.quit, network, addr)
+	c.lock.Lock()
+	c.comets[serverId] = cm
+	c.lock.Unlock()
+	return
+}
+
+// Del stop the reconnect and close the comet client.
+func (c *Comets) Del(serverId int32) {
+	c.lock.Lock()
+	cm, ok := c.comets[serverId]
+	delete(c.comets, serverId)
+	c.lock.Unlock()
+	if !ok {
+		return
+	}
+	close(cm.quit)
+	if cm.client != nil {
+		cm.client.Close()
+	}
+	log.Info("comet server:%d addr:%s removed", serverId, cm.addr)
+}
+
+// Servers return the server ids of the comets.
+func (c *Comets) Servers() (serverIds []int32) {
+	c.lock.RLock()
+	for serverId := range c.comets {
+		serverIds = append(serverIds, serverId)
+	}
+	c.lock.RUnlock()
+	return
+}
+
+// Get return the client of the comet, get it every time for the
+// reconnected one.
+func (c *Comets) Get(serverId int32) (*protorpc.Client, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if cm, ok := c.comets[serverId]; !ok || cm.client == nil {
+		return nil, ErrComet
+	} else {
+		return cm.client, nil
+	}
+}
+
+// Operation return the operation of the pushed message, default
+// OP_SEND_SMS_REPLY.
+func Operation(operation int32) int32 {
+	if operation == 0 {
+		return define.OP_SEND_SMS_REPLY
+	}
+	return operation
+}
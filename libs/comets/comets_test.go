@@ -0,0 +1,32 @@
+package comets
+
+import (
+	"github.com/Terry-Mao/goim/libs/registry"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	c := New()
+	// the comets are down, reconnected in background
+	c.Sync([]*registry.Node{{ServerId: 1, Addr: "tcp@127.0.0.1:1"}, {ServerId: 2, Addr: "tcp@127.0.0.1:2"}})
+	if servers := c.Servers(); len(servers) != 2 {
+		t.Fatalf("servers: %v", servers)
+	}
+	if _, err := c.Get(1); err != ErrComet {
+		t.Fatalf("Get(1) error(%v)", err)
+	}
+	c.Sync([]*registry.Node{{ServerId: 2, Addr: "tcp@127.0.0.1:3"}})
+	if servers := c.Servers(); len(servers) != 1 || servers[0] != 2 {
+		t.Fatalf("servers: %v", servers)
+	}
+	c.lock.RLock()
+	addr := c.comets[2].addr
+	c.lock.RUnlock()
+	if addr != "tcp@127.0.0.1:3" {
+		t.Fatalf("addr: %s", addr)
+	}
+	c.Sync(nil)
+	if servers := c.Servers(); len(servers) != 0 {
+		t.Fatalf("servers: %v", servers)
+	}
+}
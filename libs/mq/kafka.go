@@ -0,0 +1,91 @@
+package mq
+
+import (
+	log "code.google.com/p/log4go"
+	"github.com/Shopify/sarama"
+	"github.com/wvanbergen/kafka/consumergroup"
+	"time"
+)
+
+const (
+	kafkaOffsetsProcessingTimeout = 10 * time.Second
+	kafkaOffsetsCommitInterval    = 10 * time.Second
+)
+
+// KafkaProducer send the messages to kafka, wait for all the replicas.
+type KafkaProducer struct {
+	producer sarama.SyncProducer
+}
+
+func NewKafkaProducer(addrs []string) (*KafkaProducer, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Partitioner = sarama.NewRandomPartitioner
+	producer, err := sarama.NewSyncProducer(addrs, config)
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaProducer{producer: producer}, nil
+}
+
+func (p *KafkaProducer) Send(topic, key string, value []byte) (err error) {
+	message := &sarama.ProducerMessage{Topic: topic, Key: sarama.StringEncoder(key), Value: sarama.ByteEncoder(value)}
+	_, _, err = p.producer.SendMessage(message)
+	return
+}
+
+func (p *KafkaProducer) Close() error {
+	return p.producer.Close()
+}
+
+// KafkaConsumer join the zookeeper consumer group, consume from the newest
+// offset.
+type KafkaConsumer struct {
+	cg   *consumergroup.ConsumerGroup
+	msgs chan *Message
+}
+
+func NewKafkaConsumer(group string, topics, zkAddrs []string, zkRoot string) (*KafkaConsumer, error) {
+	config := consumergroup.NewConfig()
+	config.Offsets.Initial = sarama.OffsetNewest
+	config.Offsets.ProcessingTimeout = kafkaOffsetsProcessingTimeout
+	config.Offsets.CommitInterval = kafkaOffsetsCommitInterval
+	config.Zookeeper.Chroot = zkRoot
+	cg, err := consumergroup.JoinConsumerGroup(group, topics, zkAddrs, config)
+	if err != nil {
+		return nil, err
+	}
+	c := &KafkaConsumer{cg: cg, msgs: make(chan *Message)}
+	go c.errors()
+	go c.consume()
+	return c, nil
+}
+
+func (c *KafkaConsumer) errors() {
+	for err := range c.cg.Errors() {
+		log.Error("consumer error(%v)", err)
+	}
+}
+
+func (c *KafkaConsumer) consume() {
+	for msg := range c.cg.Messages() {
+		log.Info("deal with topic:%s, partitionId:%d, Offset:%d, Key:%s msg:%s", msg.Topic, msg.Partition, msg.Offset, msg.Key, msg.Value)
+		c.msgs <- &Message{Topic: msg.Topic, Key: string(msg.Key), Value: msg.Value, raw: msg}
+	}
+	close(c.msgs)
+}
+
+func (c *KafkaConsumer) Messages() <-chan *Message {
+	return c.msgs
+}
+
+func (c *KafkaConsumer) Commit(m *Message) error {
+	if msg, ok := m.raw.(*sarama.ConsumerMessage); ok {
+		return c.cg.CommitUpto(msg)
+	}
+	return nil
+}
+
+func (c *KafkaConsumer) Close() error {
+	return c.cg.Close()
+}
@@ -0,0 +1,131 @@
+package mq
+
+import (
+	"sync"
+)
+
+// Memory is the in-process loopback queue, the messages sent by the
+// producer are received by the consumer of the topic, for the tests without
+// kafka.
+type Memory struct {
+	lock   sync.RWMutex
+	size   int
+	topics map[string]chan *Message
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewMemory new a memory queue, the size is the buffered messages of a
+// topic, the send block when the buffer is full until received or closed.
+func NewMemory(size int) *Memory {
+	return &Memory{size: size, topics: make(map[string]chan *Message), done: make(chan struct{})}
+}
+
+// topic get the channel of the topic, the write lock is only taken to create
+// it.
+func (q *Memory) topic(topic string) (ch chan *Message, err error) {
+	var ok bool
+	select {
+	case <-q.done:
+		return nil, ErrClosed
+	default:
+	}
+	q.lock.RLock()
+	ch, ok = q.topics[topic]
+	q.lock.RUnlock()
+	if ok {
+		return
+	}
+	q.lock.Lock()
+	if ch, ok = q.topics[topic]; !ok {
+		ch = make(chan *Message, q.size)
+		q.topics[topic] = ch
+	}
+	q.lock.Unlock()
+	return
+}
+
+func (q *Memory) Send(topic, key string, value []byte) (err error) {
+	var ch chan *Message
+	if ch, err = q.topic(topic); err != nil {
+		return
+	}
+	select {
+	case ch <- &Message{Topic: topic, Key: key, Value: value}:
+	case <-q.done:
+		err = ErrClosed
+	}
+	return
+}
+
+// Consumer return the consumer of the topic, the messages are shared by the
+// consumers of the same topic.
+func (q *Memory) Consumer(topic string) (Consumer, error) {
+	ch, err := q.topic(topic)
+	if err != nil {
+		return nil, err
+	}
+	c := &memoryConsumer{q: q, topic: ch, msgs: make(chan *Message), done: make(chan struct{})}
+	go c.proc()
+	return c, nil
+}
+
+// Close stop the blocked senders and the consumers, the consumers stop after
+// the buffered messages are received.
+func (q *Memory) Close() error {
+	q.once.Do(func() {
+		close(q.done)
+	})
+	return nil
+}
+
+type memoryConsumer struct {
+	q     *Memory
+	topic chan *Message
+	msgs  chan *Message
+	done  chan struct{}
+	once  sync.Once
+}
+
+// proc forward the messages of the topic until the consumer or the queue
+// closed.
+func (c *memoryConsumer) proc() {
+	var m *Message
+	defer close(c.msgs)
+	for {
+		select {
+		case m = <-c.topic:
+		case <-c.done:
+			return
+		case <-c.q.done:
+			// receive the buffered messages
+			select {
+			case m = <-c.topic:
+			default:
+				return
+			}
+		}
+		select {
+		case c.msgs <- m:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *memoryConsumer) Messages() <-chan *Message {
+	return c.msgs
+}
+
+func (c *memoryConsumer) Commit(m *Message) error {
+	return nil
+}
+
+// Close stop the consumer, the queue and the other consumers are not
+// affected.
+func (c *memoryConsumer) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	return nil
+}
@@ -0,0 +1,70 @@
+package mq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	q := NewMemory(2)
+	c, err := q.Consumer("push")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Send("push", "multiple", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Send("other", "multiple", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	m := <-c.Messages()
+	if m.Topic != "push" || m.Key != "multiple" || string(m.Value) != "1" {
+		t.Fatalf("message: %v", m)
+	}
+	if err = c.Commit(m); err != nil {
+		t.Fatal(err)
+	}
+	// close the consumer only
+	c.Close()
+	if _, ok := <-c.Messages(); ok {
+		t.Fatal("consumer not closed")
+	}
+	if err = q.Send("push", "multiple", []byte("3")); err != nil {
+		t.Fatalf("Send() error(%v)", err)
+	}
+	q.Close()
+	if err = q.Send("push", "multiple", []byte("4")); err != ErrClosed {
+		t.Fatalf("Send() error(%v)", err)
+	}
+}
+
+func TestMemoryBlockedSend(t *testing.T) {
+	var (
+		q    = NewMemory(1)
+		errs = make(chan error, 1)
+	)
+	q.Send("push", "multiple", []byte("1"))
+	go func() {
+		errs <- q.Send("push", "multiple", []byte("2"))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	// the full topic not block the new consumer and topic
+	c, err := q.Consumer("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-errs:
+		if err != ErrClosed {
+			t.Fatalf("Send() error(%v)", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Send() blocked after closed")
+	}
+	if _, ok := <-c.Messages(); ok {
+		t.Fatal("consumer not closed")
+	}
+}
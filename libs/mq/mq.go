@@ -0,0 +1,39 @@
+// Package mq is the message queue between the logic and the job, kafka in
+// production and the in-process memory queue for the tests.
+package mq
+
+import (
+	"errors"
+)
+
+var (
+	ErrClosed = errors.New("mq closed")
+)
+
+// Message is a message of the topic, the key is the type of the value.
+type Message struct {
+	Topic string
+	Key   string
+	Value []byte
+	raw   interface{} // the message of the backend, used by commit
+}
+
+// Producer send the messages to the topics.
+type Producer interface {
+	// Send send the message to the topic, return after the message is
+	// stored by the backend.
+	Send(topic, key string, value []byte) error
+	// Close release the producer.
+	Close() error
+}
+
+// Consumer receive the messages of the subscribed topics.
+type Consumer interface {
+	// Messages return the channel of the received messages, closed when
+	// the consumer closed.
+	Messages() <-chan *Message
+	// Commit mark the message and the messages before it processed.
+	Commit(m *Message) error
+	// Close release the consumer.
+	Close() error
+}
@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	fileExt = ".json"
+)
+
+// File store the nodes in the shared directory, every node is a json file
+// named by the server id, the modify time is the last register.
+type File struct {
+	dir    string
+	ttl    time.Duration
+	lock   sync.RWMutex
+	closed bool
+}
+
+func NewFile(dir string, ttl time.Duration) (*File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &File{dir: dir, ttl: ttl}, nil
+}
+
+func (r *File) file(serverId int32) string {
+	return filepath.Join(r.dir, strconv.FormatInt(int64(serverId), 10)+fileExt)
+}
+
+// Register write the node to a temp file then rename it, the watchers never
+// read a partial file.
+func (r *File) Register(n *Node) (err error) {
+	var (
+		b   []byte
+		tmp string
+	)
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.closed {
+		return ErrClosed
+	}
+	if b, err = json.Marshal(n); err != nil {
+		return
+	}
+	tmp = r.file(n.ServerId) + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return
+	}
+	return os.Rename(tmp, r.file(n.ServerId))
+}
+
+func (r *File) Deregister(serverId int32) (err error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.closed {
+		return ErrClosed
+	}
+	if err = os.Remove(r.file(serverId)); os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+// Nodes read the node files registered in the ttl, the broken file is
+// skipped.
+func (r *File) Nodes() (ns []*Node, err error) {
+	var (
+		b     []byte
+		infos []os.FileInfo
+		now   = time.Now()
+	)
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.closed {
+		return nil, ErrClosed
+	}
+	if infos, err = ioutil.ReadDir(r.dir); err != nil {
+		return
+	}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), fileExt) {
+			continue
+		}
+		if r.ttl > 0 && now.Sub(info.ModTime()) > r.ttl {
+			continue
+		}
+		if b, err = ioutil.ReadFile(filepath.Join(r.dir, info.Name())); err != nil {
+			continue
+		}
+		n := new(Node)
+		if err = json.Unmarshal(b, n); err != nil {
+			continue
+		}
+		ns = append(ns, n)
+	}
+	return sortNodes(ns), nil
+}
+
+func (r *File) Close() error {
+	r.lock.Lock()
+	r.closed = true
+	r.lock.Unlock()
+	return nil
+}
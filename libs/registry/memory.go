@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryNode struct {
+	node   Node
+	expire time.Time
+}
+
+// Memory is the in-process registry, for the tests and the components run
+// in one process.
+type Memory struct {
+	ttl    time.Duration
+	lock   sync.RWMutex
+	nodes  map[int32]*memoryNode
+	closed bool
+}
+
+func NewMemory(ttl time.Duration) *Memory {
+	return &Memory{ttl: ttl, nodes: make(map[int32]*memoryNode)}
+}
+
+func (r *Memory) Register(n *Node) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	r.nodes[n.ServerId] = &memoryNode{node: *n, expire: time.Now().Add(r.ttl)}
+	return nil
+}
+
+func (r *Memory) Deregister(serverId int32) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	delete(r.nodes, serverId)
+	return nil
+}
+
+func (r *Memory) Nodes() (ns []*Node, err error) {
+	now := time.Now()
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.closed {
+		return nil, ErrClosed
+	}
+	for _, mn := range r.nodes {
+		if r.ttl > 0 && now.After(mn.expire) {
+			continue
+		}
+		n := mn.node
+		ns = append(ns, &n)
+	}
+	return sortNodes(ns), nil
+}
+
+func (r *Memory) Close() error {
+	r.lock.Lock()
+	r.closed = true
+	r.lock.Unlock()
+	return nil
+}
@@ -0,0 +1,126 @@
+// Package registry is the registry of the comets, the comet register itself
+// and the job and logic watch the alive comets, so the comets could join and
+// leave at runtime.
+package registry
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	TypeFile = "file"
+)
+
+var (
+	ErrType   = errors.New("registry type error, must file")
+	ErrClosed = errors.New("registry closed")
+)
+
+// Node is a registered comet.
+type Node struct {
+	ServerId int32  `json:"server"`
+	Addr     string `json:"addr"`     // the push rpc addr, network@address
+	Capacity int    `json:"capacity"` // the max connections, 0 unlimited
+}
+
+// Registry store the alive nodes, a node is alive until deregistered or not
+// registered again in the ttl.
+type Registry interface {
+	// Register register the node or renew it.
+	Register(n *Node) error
+	// Deregister remove the node.
+	Deregister(serverId int32) error
+	// Nodes return the alive nodes order by the server id.
+	Nodes() ([]*Node, error)
+	// Close release the registry, the watchers are stopped.
+	Close() error
+}
+
+// New create the registry by the type, the ttl is the node expire.
+func New(typ, dir string, ttl time.Duration) (Registry, error) {
+	switch typ {
+	case TypeFile:
+		return NewFile(dir, ttl)
+	default:
+		return nil, ErrType
+	}
+}
+
+type nodes []*Node
+
+func (ns nodes) Len() int           { return len(ns) }
+func (ns nodes) Less(i, j int) bool { return ns[i].ServerId < ns[j].ServerId }
+func (ns nodes) Swap(i, j int)      { ns[i], ns[j] = ns[j], ns[i] }
+
+func sortNodes(ns []*Node) []*Node {
+	sort.Sort(nodes(ns))
+	return ns
+}
+
+func equalNodes(a, b []*Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if *a[i] != *b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Watch poll the nodes every interval, the alive nodes are sent at first
+// and every time changed, the channel is closed after quit closed or the
+// registry closed.
+func Watch(r Registry, interval time.Duration, quit chan struct{}) <-chan []*Node {
+	ch := make(chan []*Node, 1)
+	go func() {
+		var (
+			err      error
+			cur, old []*Node
+			first    = true
+			ticker   = time.NewTicker(interval)
+		)
+		defer ticker.Stop()
+		defer close(ch)
+		for {
+			if cur, err = r.Nodes(); err == ErrClosed {
+				return
+			}
+			if err == nil && (first || !equalNodes(cur, old)) {
+				select {
+				case ch <- cur:
+				case <-quit:
+					return
+				}
+				old, first = cur, false
+			}
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Keepalive register the node every interval until quit closed, then
+// deregister it.
+func Keepalive(r Registry, n *Node, interval time.Duration, quit chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Register(n); err == ErrClosed {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-quit:
+			r.Deregister(n.ServerId)
+			return
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func testRegistry(t *testing.T, r Registry) {
+	var (
+		quit = make(chan struct{})
+		w    = Watch(r, 10*time.Millisecond, quit)
+	)
+	defer close(quit)
+	if ns := <-w; len(ns) != 0 {
+		t.Fatalf("nodes: %v", ns)
+	}
+	if err := r.Register(&Node{ServerId: 2, Addr: "tcp@localhost:8093"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(&Node{ServerId: 1, Addr: "tcp@localhost:8092", Capacity: 100}); err != nil {
+		t.Fatal(err)
+	}
+	ns := <-w
+	if len(ns) == 1 {
+		// the watcher may poll between the registers
+		ns = <-w
+	}
+	if len(ns) != 2 || ns[0].ServerId != 1 || ns[0].Capacity != 100 || ns[1].Addr != "tcp@localhost:8093" {
+		t.Fatalf("nodes: %v", ns)
+	}
+	if err := r.Deregister(2); err != nil {
+		t.Fatal(err)
+	}
+	if ns = <-w; len(ns) != 1 || ns[0].ServerId != 1 {
+		t.Fatalf("nodes: %v", ns)
+	}
+	// expired without renew
+	time.Sleep(1100 * time.Millisecond)
+	if ns = <-w; len(ns) != 0 {
+		t.Fatalf("nodes: %v", ns)
+	}
+	r.Close()
+	if err := r.Register(&Node{ServerId: 1}); err != ErrClosed {
+		t.Fatalf("Register() error(%v)", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testRegistry(t, NewMemory(time.Second))
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r, err := NewFile(dir, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testRegistry(t, r)
+}
@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"sync"
+)
+
+var (
+	// the deflate tail removed from the compressed message, RFC 7692 7.2.1
+	deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+	// the final empty stored block appended to the compressed message
+	inflateTail      = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+	flateReaderPool  sync.Pool
+	flateWriterPools [flate.BestCompression - flate.BestSpeed + 1]sync.Pool
+)
+
+// compress compress the message without context takeover.
+func compress(data []byte, level int) (out []byte, err error) {
+	var (
+		fw  *flate.Writer
+		buf = new(bytes.Buffer)
+		p   = &flateWriterPools[level-flate.BestSpeed]
+	)
+	if v := p.Get(); v != nil {
+		fw = v.(*flate.Writer)
+		fw.Reset(buf)
+	} else if fw, err = flate.NewWriter(buf, level); err != nil {
+		return
+	}
+	if _, err = fw.Write(data); err == nil {
+		err = fw.Flush()
+	}
+	p.Put(fw)
+	if err != nil {
+		return
+	}
+	out = buf.Bytes()
+	if bytes.HasSuffix(out, deflateTail) {
+		out = out[:len(out)-len(deflateTail)]
+	}
+	return
+}
+
+// decompress decompress the message, the uncompressed size is limited.
+func decompress(data []byte, limit int64) (out []byte, err error) {
+	var (
+		fr  io.ReadCloser
+		buf = new(bytes.Buffer)
+		in  = io.MultiReader(bytes.NewReader(data), bytes.NewReader(inflateTail))
+	)
+	if v := flateReaderPool.Get(); v != nil {
+		fr = v.(io.ReadCloser)
+		fr.(flate.Resetter).Reset(in, nil)
+	} else {
+		fr = flate.NewReader(in)
+	}
+	_, err = io.Copy(buf, io.LimitReader(fr, limit+1))
+	flateReaderPool.Put(fr)
+	if err != nil {
+		return
+	}
+	if int64(buf.Len()) > limit {
+		err = ErrReadLimit
+		return
+	}
+	out = buf.Bytes()
+	return
+}
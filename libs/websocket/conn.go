@@ -0,0 +1,309 @@
+// Package websocket implements the server side of the RFC 6455 websocket
+// protocol with the control frames and the RFC 7692 permessage-deflate
+// extension.
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// frame opcode
+	ContinuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+const (
+	finBit  = byte(0x80)
+	rsv1Bit = byte(0x40)
+	rsv2Bit = byte(0x20)
+	rsv3Bit = byte(0x10)
+	maskBit = byte(0x80)
+
+	maxControlPayload = 125
+	maxFrameHeader    = 14
+
+	// close code
+	CloseNormalClosure    = 1000
+	CloseProtocolError    = 1002
+	CloseMessageTooBig    = 1009
+	closeCodeSize         = 2
+	defaultReadLimit      = 1 << 20
+	defaultControlTimeout = 1 * time.Second
+)
+
+var (
+	ErrProtocol  = errors.New("websocket: protocol error")
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+	ErrClosed    = errors.New("websocket: close frame received")
+)
+
+// Conn is a websocket connection, the reader and the writer can be used in
+// different goroutines, the writes are serialized.
+type Conn struct {
+	conn      net.Conn
+	rr        *bufio.Reader
+	wr        *bufio.Writer
+	wLock     sync.Mutex
+	readLimit int64
+	compress  bool
+	level     int
+	// control frame handler
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+	header      []byte // read frame header
+	wheader     []byte // write frame header, protected by wLock
+}
+
+func newConn(conn net.Conn, rr *bufio.Reader, wr *bufio.Writer, compress bool, level int) *Conn {
+	c := new(Conn)
+	c.conn = conn
+	c.rr = rr
+	c.wr = wr
+	c.compress = compress
+	c.level = level
+	c.readLimit = defaultReadLimit
+	c.header = make([]byte, maxFrameHeader)
+	c.wheader = make([]byte, maxFrameHeader)
+	c.pingHandler = func(data []byte) error {
+		return c.WriteControl(PongMessage, data)
+	}
+	return c
+}
+
+// SetReadLimit set the max size of a message, the uncompressed size if
+// the message is compressed.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetPingHandler set the handler of ping frame, the default handler reply
+// the pong frame.
+func (c *Conn) SetPingHandler(h func(data []byte) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler set the handler of pong frame, the default handler do
+// nothing.
+func (c *Conn) SetPongHandler(h func(data []byte) error) {
+	c.pongHandler = h
+}
+
+// Compress return whether the permessage-deflate is negotiated.
+func (c *Conn) Compress() bool {
+	return c.compress
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Close close the underlying connection without the close frame.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// ReadMessage read a data message, the control frames are handled by the
+// handlers while reading.
+func (c *Conn) ReadMessage() (op int, data []byte, err error) {
+	var (
+		fin, rsv1  bool
+		compressed bool
+		fop        int
+		payload    []byte
+	)
+	op = -1
+	for {
+		if fin, rsv1, fop, payload, err = c.readFrame(); err != nil {
+			return
+		}
+		switch fop {
+		case PingMessage:
+			if c.pingHandler != nil {
+				if err = c.pingHandler(payload); err != nil {
+					return
+				}
+			}
+			continue
+		case PongMessage:
+			if c.pongHandler != nil {
+				if err = c.pongHandler(payload); err != nil {
+					return
+				}
+			}
+			continue
+		case CloseMessage:
+			c.writeClose(CloseNormalClosure)
+			err = ErrClosed
+			return
+		case ContinuationFrame:
+			if op == -1 || rsv1 {
+				err = c.fail(ErrProtocol, CloseProtocolError)
+				return
+			}
+		case TextMessage, BinaryMessage:
+			if op != -1 || (rsv1 && !c.compress) {
+				err = c.fail(ErrProtocol, CloseProtocolError)
+				return
+			}
+			op = fop
+			compressed = rsv1
+		default:
+			err = c.fail(ErrProtocol, CloseProtocolError)
+			return
+		}
+		if int64(len(data)+len(payload)) > c.readLimit {
+			err = c.fail(ErrReadLimit, CloseMessageTooBig)
+			return
+		}
+		data = append(data, payload...)
+		if fin {
+			break
+		}
+	}
+	if compressed {
+		if data, err = decompress(data, c.readLimit); err == ErrReadLimit {
+			err = c.fail(ErrReadLimit, CloseMessageTooBig)
+		}
+	}
+	return
+}
+
+// readFrame read a frame and unmask the payload.
+func (c *Conn) readFrame() (fin, rsv1 bool, op int, payload []byte, err error) {
+	var (
+		n    uint64
+		mask []byte
+		h    = c.header
+	)
+	if _, err = io.ReadFull(c.rr, h[:2]); err != nil {
+		return
+	}
+	fin = h[0]&finBit != 0
+	rsv1 = h[0]&rsv1Bit != 0
+	op = int(h[0] & 0x0f)
+	if h[0]&(rsv2Bit|rsv3Bit) != 0 || h[1]&maskBit == 0 {
+		// client frame must be masked
+		err = c.fail(ErrProtocol, CloseProtocolError)
+		return
+	}
+	switch n = uint64(h[1] & 0x7f); n {
+	case 126:
+		if _, err = io.ReadFull(c.rr, h[:2]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(h[:2]))
+	case 127:
+		if _, err = io.ReadFull(c.rr, h[:8]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(h[:8])
+	}
+	if op >= CloseMessage && (!fin || rsv1 || n > maxControlPayload) {
+		err = c.fail(ErrProtocol, CloseProtocolError)
+		return
+	}
+	if n > uint64(c.readLimit) {
+		err = c.fail(ErrReadLimit, CloseMessageTooBig)
+		return
+	}
+	mask = h[10:14]
+	if _, err = io.ReadFull(c.rr, mask); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.rr, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i&3]
+	}
+	return
+}
+
+// WriteMessage write a text or binary message, compressed if negotiated.
+func (c *Conn) WriteMessage(op int, data []byte) (err error) {
+	var rsv1 bool
+	if c.compress {
+		if data, err = compress(data, c.level); err != nil {
+			return
+		}
+		rsv1 = true
+	}
+	c.wLock.Lock()
+	if err = c.writeFrame(op, rsv1, data); err == nil {
+		err = c.wr.Flush()
+	}
+	c.wLock.Unlock()
+	return
+}
+
+// WriteControl write a ping, pong or close frame.
+func (c *Conn) WriteControl(op int, data []byte) (err error) {
+	if len(data) > maxControlPayload {
+		return ErrProtocol
+	}
+	c.wLock.Lock()
+	c.conn.SetWriteDeadline(time.Now().Add(defaultControlTimeout))
+	if err = c.writeFrame(op, false, data); err == nil {
+		err = c.wr.Flush()
+	}
+	c.conn.SetWriteDeadline(time.Time{})
+	c.wLock.Unlock()
+	return
+}
+
+func (c *Conn) writeFrame(op int, rsv1 bool, data []byte) (err error) {
+	var (
+		h = c.wheader[:2]
+		n = len(data)
+	)
+	h[0] = finBit | byte(op)
+	if rsv1 {
+		h[0] |= rsv1Bit
+	}
+	// server frame is not masked
+	switch {
+	case n <= maxControlPayload:
+		h[1] = byte(n)
+	case n <= 0xffff:
+		h[1] = 126
+		h = h[:4]
+		binary.BigEndian.PutUint16(h[2:], uint16(n))
+	default:
+		h[1] = 127
+		h = h[:10]
+		binary.BigEndian.PutUint64(h[2:], uint64(n))
+	}
+	if _, err = c.wr.Write(h); err != nil {
+		return
+	}
+	_, err = c.wr.Write(data)
+	return
+}
+
+func (c *Conn) writeClose(code int) error {
+	data := make([]byte, closeCodeSize)
+	binary.BigEndian.PutUint16(data, uint16(code))
+	return c.WriteControl(CloseMessage, data)
+}
+
+// fail send the close frame and return the error.
+func (c *Conn) fail(err error, code int) error {
+	c.writeClose(code)
+	return err
+}
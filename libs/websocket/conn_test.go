@@ -0,0 +1,164 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testClient is a minimal client which write masked frames.
+type testClient struct {
+	conn net.Conn
+	rr   *bufio.Reader
+}
+
+func dialTest(t *testing.T, url, extensions string) (c *testClient, resp *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", url+"/sub", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if extensions != "" {
+		req.Header.Set("Sec-WebSocket-Extensions", extensions)
+	}
+	if err = req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	c = &testClient{conn: conn, rr: bufio.NewReader(conn)}
+	if resp, err = http.ReadResponse(c.rr, req); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func (c *testClient) writeFrame(b0 byte, payload []byte) error {
+	var (
+		mask = []byte{1, 2, 3, 4}
+		h    = []byte{b0, maskBit}
+	)
+	if len(payload) <= maxControlPayload {
+		h[1] |= byte(len(payload))
+	} else {
+		h[1] |= 126
+		h = append(h, byte(len(payload)>>8), byte(len(payload)))
+	}
+	h = append(h, mask...)
+	data := make([]byte, len(payload))
+	for i := range payload {
+		data[i] = payload[i] ^ mask[i&3]
+	}
+	_, err := c.conn.Write(append(h, data...))
+	return err
+}
+
+func (c *testClient) readFrame() (b0 byte, payload []byte, err error) {
+	h := make([]byte, 2)
+	if _, err = io.ReadFull(c.rr, h); err != nil {
+		return
+	}
+	n := int(h[1] & 0x7f)
+	if n == 126 {
+		if _, err = io.ReadFull(c.rr, h); err != nil {
+			return
+		}
+		n = int(binary.BigEndian.Uint16(h))
+	}
+	b0 = h[0]
+	payload = make([]byte, n)
+	_, err = io.ReadFull(c.rr, payload)
+	return
+}
+
+func TestConn(t *testing.T) {
+	var (
+		pongs = make(chan []byte, 1)
+		u     = &Upgrader{Compress: true, ReadLimit: 512}
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := u.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.SetPongHandler(func(data []byte) error {
+			pongs <- data
+			return nil
+		})
+		for {
+			op, data, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err = c.WriteMessage(op, data); err != nil {
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+	c, resp := dialTest(t, ts.URL, "permessage-deflate; client_max_window_bits")
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-Websocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake status: %d, header: %v", resp.StatusCode, resp.Header)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Sec-Websocket-Extensions"), deflateExtension) {
+		t.Fatalf("deflate not negotiated: %v", resp.Header)
+	}
+	// ping -> pong
+	if err := c.writeFrame(finBit|PingMessage, []byte("hb")); err != nil {
+		t.Fatal(err)
+	}
+	if b0, payload, err := c.readFrame(); err != nil || b0 != finBit|PongMessage || string(payload) != "hb" {
+		t.Fatalf("pong frame: %x, %s, error(%v)", b0, payload, err)
+	}
+	// pong handler
+	if err := c.writeFrame(finBit|PongMessage, []byte("ok")); err != nil {
+		t.Fatal(err)
+	}
+	if data := <-pongs; string(data) != "ok" {
+		t.Fatalf("pong handler data: %s", data)
+	}
+	// compressed echo
+	msg := bytes.Repeat([]byte("{\"test\":1}"), 20)
+	compressed, err := compress(msg, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.writeFrame(finBit|rsv1Bit|TextMessage, compressed); err != nil {
+		t.Fatal(err)
+	}
+	b0, payload, err := c.readFrame()
+	if err != nil || b0 != finBit|rsv1Bit|TextMessage {
+		t.Fatalf("echo frame: %x, error(%v)", b0, err)
+	}
+	if payload, err = decompress(payload, 1024); err != nil || !bytes.Equal(payload, msg) {
+		t.Fatalf("echo payload: %s, error(%v)", payload, err)
+	}
+	// read limit
+	if err = c.writeFrame(finBit|TextMessage, make([]byte, 513)); err != nil {
+		t.Fatal(err)
+	}
+	if b0, payload, err = c.readFrame(); err != nil || b0 != finBit|CloseMessage || binary.BigEndian.Uint16(payload) != CloseMessageTooBig {
+		t.Fatalf("close frame: %x, %v, error(%v)", b0, payload, err)
+	}
+}
+
+func TestNegotiateDeflate(t *testing.T) {
+	h := http.Header{}
+	h.Set("Sec-WebSocket-Extensions", "permessage-deflate; server_max_window_bits=10")
+	if negotiateDeflate(h) {
+		t.Error("server_max_window_bits=10 negotiated")
+	}
+	h.Set("Sec-WebSocket-Extensions", "x-webkit-deflate-frame, permessage-deflate; server_max_window_bits=15")
+	if !negotiateDeflate(h) {
+		t.Error("permessage-deflate not negotiated")
+	}
+}
@@ -0,0 +1,137 @@
+package websocket
+
+import (
+	"bufio"
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	acceptGUID        = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	deflateExtension  = "permessage-deflate"
+	deflateResponse   = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+	serverWindowBits  = "server_max_window_bits"
+	supportWindowBits = "15"
+)
+
+var (
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	ErrNotHijacker  = errors.New("websocket: response not support hijack")
+)
+
+// Upgrader upgrade the http request to the websocket connection.
+type Upgrader struct {
+	// Compress enable negotiate the permessage-deflate, the compressor
+	// don't take over the context, so the memory is not held by idle
+	// connections.
+	Compress bool
+	// CompressLevel is the flate level, flate.BestSpeed by default.
+	CompressLevel int
+	// ReadLimit is the max message size, 1MB by default.
+	ReadLimit int64
+}
+
+// Upgrade upgrade the http request to the websocket connection, the http
+// error is replied if the handshake failed.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (c *Conn, err error) {
+	var (
+		ok       bool
+		compress bool
+		key      string
+		hj       http.Hijacker
+		conn     net.Conn
+		rw       *bufio.ReadWriter
+		level    = u.CompressLevel
+	)
+	if r.Method != "GET" ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-Websocket-Version") != "13" {
+		w.Header().Set("Sec-Websocket-Version", "13")
+		http.Error(w, "bad handshake", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if key = r.Header.Get("Sec-Websocket-Key"); key == "" {
+		http.Error(w, "bad handshake", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if hj, ok = w.(http.Hijacker); !ok {
+		http.Error(w, "not support", http.StatusInternalServerError)
+		return nil, ErrNotHijacker
+	}
+	if u.Compress {
+		compress = negotiateDeflate(r.Header)
+	}
+	if level < flate.BestSpeed || level > flate.BestCompression {
+		level = flate.BestSpeed
+	}
+	if conn, rw, err = hj.Hijack(); err != nil {
+		return
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	rw.WriteString(acceptKey(key))
+	if compress {
+		rw.WriteString("\r\nSec-WebSocket-Extensions: ")
+		rw.WriteString(deflateResponse)
+	}
+	rw.WriteString("\r\n\r\n")
+	if err = rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+	c = newConn(conn, rw.Reader, rw.Writer, compress, level)
+	if u.ReadLimit > 0 {
+		c.SetReadLimit(u.ReadLimit)
+	}
+	return
+}
+
+// acceptKey compute the Sec-WebSocket-Accept of the key.
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key))
+	h.Write([]byte(acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// negotiateDeflate accept the permessage-deflate offer if the server window
+// bits is not limited, the context takeover is always disabled.
+func negotiateDeflate(header http.Header) bool {
+	for _, v := range header["Sec-Websocket-Extensions"] {
+		for _, ext := range strings.Split(v, ",") {
+			params := strings.Split(ext, ";")
+			if strings.TrimSpace(params[0]) != deflateExtension {
+				continue
+			}
+			ok := true
+			for _, param := range params[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if kv[0] == serverWindowBits && (len(kv) != 2 || strings.Trim(kv[1], "\"") != supportWindowBits) {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headerContains check the comma separated header tokens contain the value.
+func headerContains(header http.Header, name, value string) bool {
+	for _, v := range header[http.CanonicalHeaderKey(name)] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
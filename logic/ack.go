@@ -0,0 +1,145 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// delivery status of a user
+	msgStatusOffline = 0 // no session when pushed
+	msgStatusSent    = 1 // pushed to comet, wait the ack
+	msgStatusAcked   = 2 // acked by at least one device
+
+	ackCleanInterval = 1 * time.Minute
+)
+
+var (
+	lastMsgId int64
+	ackStore  *AckStore
+)
+
+// newMsgId return a unique increasing message id based on the unix nano.
+func newMsgId() int64 {
+	for {
+		last := atomic.LoadInt64(&lastMsgId)
+		id := time.Now().UnixNano()
+		if id <= last {
+			id = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&lastMsgId, last, id) {
+			return id
+		}
+	}
+}
+
+type msgStatus struct {
+	appId  int32
+	expire time.Time
+	users  map[int64]int
+}
+
+// AckStore record the delivery status of the pushed messages per user, the
+// message expired after Conf.AckExpire. It is in the memory of this logic
+// only, the status is lost on restart and the acks reported to the other
+// logics of the message are dropped.
+type AckStore struct {
+	lock   sync.RWMutex
+	msgs   map[int64]*msgStatus
+	expire time.Duration
+}
+
+func InitAck() {
+	ackStore = NewAckStore(Conf.AckExpire)
+	go ackStore.clean()
+}
+
+func NewAckStore(expire time.Duration) *AckStore {
+	return &AckStore{msgs: make(map[int64]*msgStatus), expire: expire}
+}
+
+// Push record the users of the app of the message, the online users are sent.
+func (s *AckStore) Push(msgId int64, appId int32, userIds, onlines []int64) {
+	m := &msgStatus{appId: appId, expire: time.Now().Add(s.expire), users: make(map[int64]int, len(userIds))}
+	for _, uid := range userIds {
+		m.users[uid] = msgStatusOffline
+	}
+	for _, uid := range onlines {
+		m.users[uid] = msgStatusSent
+	}
+	s.lock.Lock()
+	s.msgs[msgId] = m
+	s.lock.Unlock()
+}
+
+// Ack mark the message acked by the user, false if the message not exists
+// or the user is not the receiver.
+func (s *AckStore) Ack(msgId int64, appId int32, userId int64) (ok bool) {
+	var m *msgStatus
+	s.lock.Lock()
+	if m, ok = s.msgs[msgId]; ok && m.appId == appId {
+		if _, ok = m.users[userId]; ok {
+			m.users[userId] = msgStatusAcked
+		}
+	} else {
+		ok = false
+	}
+	s.lock.Unlock()
+	return
+}
+
+// Sent mark the offline message sent to the user when replayed.
+func (s *AckStore) Sent(msgId int64, appId int32, userId int64) {
+	s.lock.Lock()
+	if m, ok := s.msgs[msgId]; ok && m.appId == appId {
+		if status, ok := m.users[userId]; ok && status == msgStatusOffline {
+			m.users[userId] = msgStatusSent
+		}
+	}
+	s.lock.Unlock()
+}
+
+// Status return the delivery status of all the users of the message.
+func (s *AckStore) Status(msgId int64) (users map[int64]int, ok bool) {
+	var m *msgStatus
+	s.lock.RLock()
+	if m, ok = s.msgs[msgId]; ok {
+		users = make(map[int64]int, len(m.users))
+		for uid, status := range m.users {
+			users[uid] = status
+		}
+	}
+	s.lock.RUnlock()
+	return
+}
+
+// UserStatus return the delivery status of the user of the message.
+func (s *AckStore) UserStatus(msgId, userId int64) (status int, ok bool) {
+	var m *msgStatus
+	s.lock.RLock()
+	if m, ok = s.msgs[msgId]; ok {
+		status, ok = m.users[userId]
+	}
+	s.lock.RUnlock()
+	return
+}
+
+// clean delete the expired messages periodically.
+func (s *AckStore) clean() {
+	for {
+		time.Sleep(ackCleanInterval)
+		now := time.Now()
+		n := 0
+		s.lock.Lock()
+		for msgId, m := range s.msgs {
+			if now.After(m.expire) {
+				delete(s.msgs, msgId)
+				n++
+			}
+		}
+		s.lock.Unlock()
+		log.Debug("ack store clean %d expired messages", n)
+	}
+}
@@ -1,10 +1,53 @@
 package main
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	autherDefault = ""
+	autherJWT     = "jwt"
+	autherHTTP    = "http"
+)
+
+// Identity is the user of the token, the user ids are scoped by the app, use
+// app 0 if there is only one app.
+type Identity struct {
+	AppId  int32
+	UserId int64
+	Device string
+}
+
 // developer could implement "ThirdAuth" interface for decide how get userID
 type Auther interface {
-	Auth(token string) int64
+	// Auth return the identity of the token, error if the token is invalid.
+	Auth(token string) (*Identity, error)
+}
+
+// tokenDigest return a short hash of the token for the logs, the token is a
+// credential never logged.
+func tokenDigest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:4])
+}
+
+// InitAuther create the auther by Conf.AuthType, empty use the default.
+func InitAuther() (a Auther, err error) {
+	switch Conf.AuthType {
+	case autherDefault:
+		a = NewDefaultAuther()
+	case autherJWT:
+		a, err = NewJWTAuther(Conf.AuthJWTSecret)
+	case autherHTTP:
+		a, err = NewHTTPAuther(Conf.AuthHTTPURL, Conf.AuthHTTPTimeout)
+	default:
+		err = ErrAuthType
+	}
+	return
 }
 
+// DefaultAuther accept all the tokens as the user 0, only for testing.
 type DefaultAuther struct {
 }
 
@@ -12,6 +55,6 @@ func NewDefaultAuther() *DefaultAuther {
 	return &DefaultAuther{}
 }
 
-func (a *DefaultAuther) Auth(token string) (userID int64) {
-	return 0
+func (a *DefaultAuther) Auth(token string) (*Identity, error) {
+	return &Identity{}, nil
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// httpAuthReply is the reply of the account service, ret 1 is ok.
+type httpAuthReply struct {
+	Ret  int `json:"ret"`
+	Data struct {
+		UserId int64  `json:"uid"`
+		AppId  int32  `json:"app"`
+		Device string `json:"device"`
+	} `json:"data"`
+}
+
+// HTTPAuther ask the account service by GET url?token=xxx, the reply is
+// {"ret":1,"data":{"uid":1,"app":0,"device":"xxx"}}.
+type HTTPAuther struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPAuther(addr string, timeout time.Duration) (*HTTPAuther, error) {
+	if _, err := url.Parse(addr); err != nil || addr == "" {
+		return nil, ErrAuthURL
+	}
+	return &HTTPAuther{url: addr, client: &http.Client{Timeout: timeout}}, nil
+}
+
+func (a *HTTPAuther) Auth(token string) (id *Identity, err error) {
+	var (
+		resp  *http.Response
+		reply httpAuthReply
+		sep   = "?"
+	)
+	if strings.IndexByte(a.url, '?') != -1 {
+		sep = "&"
+	}
+	uri := a.url + sep + "token=" + url.QueryEscape(token)
+	if resp, err = a.client.Get(uri); err != nil {
+		// the url error contains the token
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		log.Error("http.Get(\"%s\") error(%v)", a.url, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Error("http.Get(\"%s\") status: %d", a.url, resp.StatusCode)
+		return nil, ErrAuth
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		log.Error("json.Decode() error(%v)", err)
+		return
+	}
+	if reply.Ret != OK {
+		return nil, ErrAuth
+	}
+	return &Identity{AppId: reply.Data.AppId, UserId: reply.Data.UserId, Device: reply.Data.Device}, nil
+}
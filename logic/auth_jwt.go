@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// jwtClaims is the payload of the token, uid is required.
+type jwtClaims struct {
+	UserId *int64 `json:"uid"`
+	AppId  int32  `json:"app"`
+	Device string `json:"device"`
+	Expire int64  `json:"exp"` // unix seconds, 0 never expire
+}
+
+// JWTAuther verify the HMAC-SHA256 signed JWT, the token is issued by the
+// account service with the shared secret.
+type JWTAuther struct {
+	secret []byte
+}
+
+func NewJWTAuther(secret string) (*JWTAuther, error) {
+	if secret == "" {
+		return nil, ErrAuthSecret
+	}
+	return &JWTAuther{secret: []byte(secret)}, nil
+}
+
+func (a *JWTAuther) Auth(token string) (id *Identity, err error) {
+	var (
+		header jwtHeader
+		claims jwtClaims
+		b, sig []byte
+		parts  = strings.Split(token, ".")
+	)
+	if len(parts) != 3 {
+		return nil, ErrTokenInvalid
+	}
+	if sig, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return nil, ErrTokenInvalid
+	}
+	if !hmac.Equal(sig, a.sign(parts[0]+"."+parts[1])) {
+		return nil, ErrTokenInvalid
+	}
+	if b, err = base64.RawURLEncoding.DecodeString(parts[0]); err != nil {
+		return nil, ErrTokenInvalid
+	}
+	if err = json.Unmarshal(b, &header); err != nil || header.Alg != "HS256" {
+		return nil, ErrTokenInvalid
+	}
+	if b, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, ErrTokenInvalid
+	}
+	if err = json.Unmarshal(b, &claims); err != nil || claims.UserId == nil {
+		return nil, ErrTokenInvalid
+	}
+	if claims.Expire != 0 && claims.Expire < time.Now().Unix() {
+		return nil, ErrTokenExpired
+	}
+	return &Identity{AppId: claims.AppId, UserId: *claims.UserId, Device: claims.Device}, nil
+}
+
+func (a *JWTAuther) sign(data string) []byte {
+	h := hmac.New(sha256.New, a.secret)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func jwtToken(a *JWTAuther, payload string) string {
+	data := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return data + "." + base64.RawURLEncoding.EncodeToString(a.sign(data))
+}
+
+func TestJWTAuther(t *testing.T) {
+	a, err := NewJWTAuther("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewJWTAuther("other")
+	id, err := a.Auth(jwtToken(a, `{"uid":1,"app":2,"device":"ios"}`))
+	if err != nil || id.UserId != 1 || id.AppId != 2 || id.Device != "ios" {
+		t.Fatalf("Auth() = %v, error(%v)", id, err)
+	}
+	for token, expect := range map[string]error{
+		jwtToken(b, `{"uid":1}`):         ErrTokenInvalid,
+		jwtToken(a, `{"app":1}`):         ErrTokenInvalid,
+		jwtToken(a, `{"uid":1,"exp":1}`): ErrTokenExpired,
+		"xxx":                            ErrTokenInvalid,
+	} {
+		if _, err = a.Auth(token); err != expect {
+			t.Fatalf("Auth(\"%s\") error(%v), expect %v", token, err, expect)
+		}
+	}
+}
+
+func TestHTTPAuther(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") == "ok" {
+			w.Write([]byte(`{"ret":1,"data":{"uid":1,"app":2,"device":"ios"}}`))
+			return
+		}
+		w.Write([]byte(`{"ret":65535}`))
+	}))
+	defer s.Close()
+	a, err := NewHTTPAuther(s.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := a.Auth("ok")
+	if err != nil || id.UserId != 1 || id.AppId != 2 || id.Device != "ios" {
+		t.Fatalf("Auth() = %v, error(%v)", id, err)
+	}
+	if _, err = a.Auth("bad"); err != ErrAuth {
+		t.Fatalf("Auth(\"bad\") error(%v)", err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"flag"
 	"github.com/Terry-Mao/goconf"
 	"runtime"
+	"strconv"
 	"time"
 )
 
@@ -45,20 +46,58 @@ type Config struct {
 	HTTPReadTimeout  time.Duration `goconf:"base:http.read.timeout:time"`
 	HTTPWriteTimeout time.Duration `goconf:"base:http.write.timeout:time"`
 	// router RPC
-	RouterRPCAddrs map[string]string `-`
+	RouterRPCAddrs   map[string]string `-`
+	RouterRPCTimeout time.Duration     `goconf:"router:timeout:time"`
+	// queue
+	QueueType string           `goconf:"queue:type"`
+	Comets    map[int32]string `-`
+	// comet
+	CometTimeout time.Duration `goconf:"comet:heartbeat.timeout:time"`
+	// registry
+	RegistryType  string        `goconf:"registry:type"`
+	RegistryDir   string        `goconf:"registry:dir"`
+	RegistryTTL   time.Duration `goconf:"registry:ttl:time"`
+	RegistryWatch time.Duration `goconf:"registry:watch:time"`
 	// kafka
 	KafkaAddrs []string `goconf:"kafka:addrs"`
+	// ack
+	AckExpire time.Duration `goconf:"ack:expire:time"`
+	// offline
+	OfflineStore string        `goconf:"offline:store"`
+	OfflineDir   string        `goconf:"offline:dir"`
+	OfflineTTL   time.Duration `goconf:"offline:ttl:time"`
+	OfflineMax   int           `goconf:"offline:max"`
+	// app
+	AppQuota map[int32]int `-`
+	// auth
+	AuthType        string        `goconf:"auth:type"`
+	AuthJWTSecret   string        `goconf:"auth:jwt.secret"`
+	AuthHTTPURL     string        `goconf:"auth:http.url"`
+	AuthHTTPTimeout time.Duration `goconf:"auth:http.timeout:time"`
 }
 
 func NewConfig() *Config {
 	return &Config{
 		// base section
-		PidFile:        "/tmp/gopush-cluster-logic.pid",
-		Dir:            "./",
-		Log:            "./log/xml",
-		MaxProc:        runtime.NumCPU(),
-		PprofAddrs:     []string{"localhost:6971"},
-		RouterRPCAddrs: make(map[string]string),
+		PidFile:          "/tmp/gopush-cluster-logic.pid",
+		Dir:              "./",
+		Log:              "./log/xml",
+		MaxProc:          runtime.NumCPU(),
+		PprofAddrs:       []string{"localhost:6971"},
+		RouterRPCAddrs:   make(map[string]string),
+		RouterRPCTimeout: 1 * time.Second,
+		Comets:           make(map[int32]string),
+		CometTimeout:     30 * time.Second,
+		RegistryDir:      "./registry",
+		RegistryTTL:      30 * time.Second,
+		RegistryWatch:    1 * time.Second,
+		AckExpire:        1 * time.Hour,
+		OfflineStore:     "",
+		OfflineDir:       "./offline",
+		OfflineTTL:       24 * time.Hour,
+		OfflineMax:       100,
+		AppQuota:         make(map[int32]int),
+		AuthHTTPTimeout:  1 * time.Second,
 	}
 }
 
@@ -79,6 +118,39 @@ func InitConfig() (err error) {
 		}
 		Conf.RouterRPCAddrs[serverID] = addr
 	}
+	if section := gconf.Get("comets"); section != nil {
+		for _, serverID := range section.Keys() {
+			addr, err := section.String(serverID)
+			if err != nil {
+				return err
+			}
+			serverIDi, err := strconv.ParseInt(serverID, 10, 32)
+			if err != nil {
+				return err
+			}
+			Conf.Comets[int32(serverIDi)] = addr
+		}
+	}
+	return parseAppQuota(gconf, Conf)
+}
+
+// parseAppQuota parse the push quota per second of the apps.
+func parseAppQuota(gconf *goconf.Config, conf *Config) error {
+	section := gconf.Get("app.quota")
+	if section == nil {
+		return nil
+	}
+	for _, key := range section.Keys() {
+		appId, err := strconv.ParseInt(key, 10, 32)
+		if err != nil {
+			return err
+		}
+		quota, err := section.Int(key)
+		if err != nil {
+			return err
+		}
+		conf.AppQuota[int32(appId)] = int(quota)
+	}
 	return nil
 }
 
@@ -91,6 +163,9 @@ func ReloadConfig() (*Config, error) {
 	if err := ngconf.Unmarshal(conf); err != nil {
 		return nil, err
 	}
+	if err := parseAppQuota(ngconf, conf); err != nil {
+		return nil, err
+	}
 	gconf = ngconf
 	return conf, nil
 }
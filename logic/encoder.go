@@ -1,25 +1,50 @@
 package main
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
 )
 
-func encode(userId int64, seq int32) string {
-	return fmt.Sprintf("%d_%d", userId, seq)
+// userKey return "appId_userId", the default app 0 is omitted as "userId"
+// for compatible with the keys before the app namespace.
+func userKey(appId int32, userId int64) string {
+	if appId == 0 {
+		return strconv.FormatInt(userId, 10)
+	}
+	return strconv.FormatInt(int64(appId), 10) + "_" + strconv.FormatInt(userId, 10)
+}
+
+// decodeUserKey parse the key returned by userKey.
+func decodeUserKey(key string) (appId int32, userId int64, err error) {
+	var (
+		idx int
+		t   int64
+	)
+	if idx = strings.IndexByte(key, '_'); idx != -1 {
+		if t, err = strconv.ParseInt(key[:idx], 10, 32); err != nil {
+			return
+		}
+		appId = int32(t)
+		key = key[idx+1:]
+	}
+	userId, err = strconv.ParseInt(key, 10, 64)
+	return
+}
+
+func encode(appId int32, userId int64, seq int32) string {
+	return userKey(appId, userId) + "_" + strconv.FormatInt(int64(seq), 10)
 }
 
-func decode(key string) (userId int64, seq int32, err error) {
+func decode(key string) (appId int32, userId int64, seq int32, err error) {
 	var (
 		idx int
 		t   int64
 	)
-	if idx = strings.IndexByte(key, '_'); idx == -1 {
+	if idx = strings.LastIndexByte(key, '_'); idx == -1 {
 		err = ErrDecodeKey
 		return
 	}
-	if userId, err = strconv.ParseInt(key[:idx], 10, 64); err != nil {
+	if appId, userId, err = decodeUserKey(key[:idx]); err != nil {
 		return
 	}
 	if t, err = strconv.ParseInt(key[idx+1:], 10, 32); err != nil {
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	for _, c := range []struct {
+		appId  int32
+		userId int64
+		seq    int32
+		key    string
+	}{
+		{0, 1, 2, "1_2"},
+		{3, 1, 2, "3_1_2"},
+	} {
+		if key := encode(c.appId, c.userId, c.seq); key != c.key {
+			t.Errorf("encode(%d, %d, %d) = \"%s\"", c.appId, c.userId, c.seq, key)
+			t.FailNow()
+		}
+		appId, userId, seq, err := decode(c.key)
+		if err != nil || appId != c.appId || userId != c.userId || seq != c.seq {
+			t.Errorf("decode(\"%s\") = %d, %d, %d, error(%v)", c.key, appId, userId, seq, err)
+			t.FailNow()
+		}
+	}
+	if _, _, _, err := decode("1"); err == nil {
+		t.Error("decode(\"1\") expect error")
+		t.FailNow()
+	}
+}
@@ -2,12 +2,28 @@ package main
 
 import (
 	"errors"
+	"github.com/Terry-Mao/goim/libs/comets"
 )
 
 var (
 	ErrRouter         = errors.New("router rpc is not available")
+	ErrRouterTimeout  = errors.New("router rpc timeout")
 	ErrDecodeKey      = errors.New("decode key error")
 	ErrNetworkAddr    = errors.New("network addrs error, must network@address")
 	ErrConnectArgs    = errors.New("connect rpc args error")
 	ErrDisconnectArgs = errors.New("disconnect rpc args error")
+	ErrAckArgs        = errors.New("ack rpc args error")
+	ErrHeartbeatArgs  = errors.New("heartbeat rpc args error")
+	ErrRenewArgs      = errors.New("renew rpc args error")
+	ErrOnlineArgs     = errors.New("online rpc args error")
+	ErrOfflineStore   = errors.New("offline store type error, must file or empty")
+	ErrAuth           = errors.New("auth failed")
+	ErrAuthType       = errors.New("auth type error, must jwt, http or empty")
+	ErrAuthSecret     = errors.New("auth jwt secret empty")
+	ErrAuthURL        = errors.New("auth http url error")
+	ErrTokenInvalid   = errors.New("token invalid")
+	ErrTokenExpired   = errors.New("token expired")
+	ErrQueueType      = errors.New("queue type error, must kafka, direct or empty")
+	ErrComet          = comets.ErrComet
+	ErrPushMsgs       = errors.New("sub keys and messages not match")
 )
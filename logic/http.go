@@ -4,9 +4,13 @@ import (
 	log "code.google.com/p/log4go"
 	"encoding/json"
 	inet "github.com/Terry-Mao/goim/libs/net"
+	rproto "github.com/Terry-Mao/goim/proto/router"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,8 +19,16 @@ func InitHTTP() (err error) {
 	var network, addr string
 	for i := 0; i < len(Conf.HTTPAddrs); i++ {
 		httpServeMux := http.NewServeMux()
+		httpServeMux.HandleFunc("/1/push", Push)
+		httpServeMux.HandleFunc("/1/push/key", PushKey)
+		httpServeMux.HandleFunc("/1/push/device", PushDevice)
 		httpServeMux.HandleFunc("/1/pushs", Pushs)
+		httpServeMux.HandleFunc("/1/pushs/msgs", PushsMsgs)
 		httpServeMux.HandleFunc("/1/push/all", PushAll)
+		httpServeMux.HandleFunc("/1/msg/status", MsgStatus)
+		httpServeMux.HandleFunc("/1/online", Online)
+		httpServeMux.HandleFunc("/1/online/sessions", OnlineSessions)
+		httpServeMux.HandleFunc("/1/online/count", OnlineCount)
 		log.Info("start http listen:\"%s\"", Conf.HTTPAddrs[i])
 		if network, addr, err = inet.ParseNetwork(Conf.HTTPAddrs[i]); err != nil {
 			log.Error("inet.ParseNetwork() error(%v)", err)
@@ -41,6 +53,20 @@ func httpListen(mux *http.ServeMux, network, addr string) {
 	}
 }
 
+// retWrite marshal the result and write to client(get).
+func retWrite(w http.ResponseWriter, r *http.Request, res map[string]interface{}, start time.Time) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		log.Error("json.Marshal(\"%v\") error(%v)", res, err)
+		return
+	}
+	dataStr := string(data)
+	if _, err := w.Write([]byte(dataStr)); err != nil {
+		log.Error("w.Write(\"%s\") error(%v)", dataStr, err)
+	}
+	log.Info("req: \"%s\", get: res:\"%s\", ip:\"%s\", time:\"%fs\"", r.URL.String(), dataStr, r.RemoteAddr, time.Now().Sub(start).Seconds())
+}
+
 // retPWrite marshal the result and write to client(post).
 func retPWrite(w http.ResponseWriter, r *http.Request, res map[string]interface{}, body *string, start time.Time) {
 	data, err := json.Marshal(res)
@@ -56,21 +82,26 @@ func retPWrite(w http.ResponseWriter, r *http.Request, res map[string]interface{
 }
 
 type pushsBodyMsg struct {
-	Msg     json.RawMessage `json:"m"`
-	UserIds []int64         `json:"u"`
+	Msg      json.RawMessage `json:"m"`
+	UserIds  []int64         `json:"u"`
+	Priority int32           `json:"p"`
+	AppId    int32           `json:"a"`
 }
 
-func parsePushsBody(body []byte) (msg []byte, userIds []int64, err error) {
+func parsePushsBody(body []byte) (msg []byte, appId int32, userIds []int64, priority int32, err error) {
 	tmp := pushsBodyMsg{}
 	if err = json.Unmarshal(body, &tmp); err != nil {
 		return
 	}
 	msg = tmp.Msg
+	appId = tmp.AppId
 	userIds = tmp.UserIds
+	priority = tmp.Priority
 	return
 }
 
-// {"m":{"test":1},"u":"1,2,3"}
+// {"m":{"test":1},"u":"1,2,3","p":1,"a":1}, p is optional, 1 is high
+// priority, a is optional, the app of the users, default 0
 func Pushs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method Not Allowed", 405)
@@ -89,34 +120,577 @@ func Pushs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	body = string(bodyBytes)
-	msg, userIds, err := parsePushsBody(bodyBytes)
+	msg, appId, userIds, priority, err := parsePushsBody(bodyBytes)
 	if err != nil {
 		log.Error("parsePushsBody(\"%s\") error(%s)", body, err)
 		res["ret"] = InternalErr
 		return
 	}
-	// TODO
-	divide, err := divideToRouter(userIds) // divide: map[comet.serverId][]subkey
+	if !appQuota.Allow(Conf.AppQuota, appId) {
+		log.Warn("app: %d push quota exceeded", appId)
+		res["ret"] = QuotaErr
+		return
+	}
+	msgId, failed, err := pushs(appId, userIds, msg, 0, priority)
 	if err != nil {
-		log.Error("divideToComet() error(%v)", err)
 		res["ret"] = InternalErr
 		return
 	}
+	res["mid"] = msgId
+	if len(failed) > 0 {
+		res["failed"] = failed
+	}
+	res["ret"] = OK
+	return
+}
+
+// pushs push the message to the users of the app, the offline users are
+// saved to the inbox, return the message id. The users of the failed
+// routers are not pushed, saved or tracked, the caller could retry them.
+func pushs(appId int32, userIds []int64, msg []byte, operation, priority int32) (msgId int64, failed []int64, err error) {
+	var divide map[int32][]string // divide: map[comet.serverId][]subkey
+	if divide, failed, err = divideToRouter(appId, userIds); err != nil {
+		log.Error("divideToComet() error(%v)", err)
+		return
+	}
+	if len(failed) > 0 {
+		log.Warn("app: %d users: %v router failed", appId, failed)
+		userIds = offlineUsers(userIds, failed)
+	}
+	// record the receivers before push, the ack may come back quickly
+	msgId = newMsgId()
+	onlines := onlineUsers(divide)
+	ackStore.Push(msgId, appId, userIds, onlines)
+	saveOffline(appId, offlineUsers(userIds, onlines), msgId, msg, operation, priority)
 	if len(divide) == 0 {
 		log.Debug("no online users")
-		res["ret"] = OK
 		return
 	}
 	for server, subkeys := range divide {
-		if err := multiPushTokafka(server, subkeys, msg); err != nil {
+		if err = pushQueue.MPush(server, subkeys, msg, operation, priority, msgId); err != nil {
+			log.Error("pushQueue.MPush(%d) error(%v)", server, err)
+			return
+		}
+	}
+	return
+}
+
+// parsePushParams parse the optional appid, operation and priority of the
+// query, the operation 0 is OP_SEND_SMS_REPLY.
+func parsePushParams(params url.Values) (appId, operation, priority int32, err error) {
+	var i int64
+	for name, v := range map[string]*int32{"appid": &appId, "operation": &operation, "priority": &priority} {
+		if s := params.Get(name); s != "" {
+			if i, err = strconv.ParseInt(s, 10, 32); err != nil {
+				return
+			}
+			*v = int32(i)
+		}
+	}
+	return
+}
+
+// Push push the message to a user.
+// POST /1/push?uid=1&appid=0&operation=5&priority=0, body is the message,
+// appid, operation and priority are optional.
+func Push(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		bodyBytes                  []byte
+		body                       string
+		uid                        int64
+		appId, operation, priority int32
+		err                        error
+		params                     = r.URL.Query()
+		res                        = map[string]interface{}{"ret": OK}
+	)
+	defer retPWrite(w, r, res, &body, time.Now())
+	if uid, err = strconv.ParseInt(params.Get("uid"), 10, 64); err != nil {
+		log.Error("strconv.ParseInt(\"%s\") error(%v)", params.Get("uid"), err)
+		res["ret"] = ParamErr
+		return
+	}
+	if appId, operation, priority, err = parsePushParams(params); err != nil {
+		log.Error("parsePushParams(\"%s\") error(%v)", r.URL.String(), err)
+		res["ret"] = ParamErr
+		return
+	}
+	if bodyBytes, err = ioutil.ReadAll(r.Body); err != nil {
+		log.Error("ioutil.ReadAll() failed (%v)", err)
+		res["ret"] = InternalErr
+		return
+	}
+	body = string(bodyBytes)
+	if !appQuota.Allow(Conf.AppQuota, appId) {
+		log.Warn("app: %d push quota exceeded", appId)
+		res["ret"] = QuotaErr
+		return
+	}
+	if res["mid"], _, err = pushs(appId, []int64{uid}, bodyBytes, operation, priority); err != nil {
+		res["ret"] = InternalErr
+	}
+	return
+}
+
+// PushKey push the message to a sub key, the sub key is a connection of the
+// user.
+// POST /1/push/key?key=1_1&operation=5, body is the message, the offline
+// key is not saved, return the message id.
+func PushKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		bodyBytes        []byte
+		body             string
+		uid              int64
+		seq              int32
+		appId, operation int32
+		msgId            int64
+		err              error
+		reply            *rproto.GetReply
+		params           = r.URL.Query()
+		key              = params.Get("key")
+		res              = map[string]interface{}{"ret": OK}
+	)
+	defer retPWrite(w, r, res, &body, time.Now())
+	if appId, uid, seq, err = decode(key); err != nil {
+		log.Error("decode(\"%s\") error(%v)", key, err)
+		res["ret"] = ParamErr
+		return
+	}
+	if _, operation, _, err = parsePushParams(params); err != nil {
+		log.Error("parsePushParams(\"%s\") error(%v)", r.URL.String(), err)
+		res["ret"] = ParamErr
+		return
+	}
+	if bodyBytes, err = ioutil.ReadAll(r.Body); err != nil {
+		log.Error("ioutil.ReadAll() failed (%v)", err)
+		res["ret"] = InternalErr
+		return
+	}
+	body = string(bodyBytes)
+	if !appQuota.Allow(Conf.AppQuota, appId) {
+		log.Warn("app: %d push quota exceeded", appId)
+		res["ret"] = QuotaErr
+		return
+	}
+	if reply, err = getSessions(appId, uid); err != nil {
+		res["ret"] = InternalErr
+		return
+	}
+	for i := 0; i < len(reply.Seqs); i++ {
+		if reply.Seqs[i] == seq {
+			msgId = newMsgId()
+			ackStore.Push(msgId, appId, []int64{uid}, []int64{uid})
+			if err = pushQueue.MPushs(reply.Servers[i], []string{key}, [][]byte{bodyBytes}, []int64{msgId}, operation); err != nil {
+				log.Error("pushQueue.MPushs(%d, \"%s\") error(%v)", reply.Servers[i], key, err)
+				res["ret"] = InternalErr
+				return
+			}
+			res["mid"] = msgId
+			return
+		}
+	}
+	res["ret"] = NotFoundErr
+	return
+}
+
+// PushDevice push the message to the connections of the device of a user,
+// the device is reported by the auther.
+// POST /1/push/device?uid=1&device=ios&appid=0&operation=5, body is the
+// message, the offline device is not saved, return the message id.
+func PushDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		bodyBytes        []byte
+		body             string
+		uid, msgId       int64
+		appId, operation int32
+		err              error
+		reply            *rproto.GetReply
+		params           = r.URL.Query()
+		device           = params.Get("device")
+		divide           = make(map[int32][]string)
+		res              = map[string]interface{}{"ret": OK}
+	)
+	defer retPWrite(w, r, res, &body, time.Now())
+	if uid, err = strconv.ParseInt(params.Get("uid"), 10, 64); err != nil || device == "" {
+		log.Error("strconv.ParseInt(\"%s\") device: \"%s\" error(%v)", params.Get("uid"), device, err)
+		res["ret"] = ParamErr
+		return
+	}
+	if appId, operation, _, err = parsePushParams(params); err != nil {
+		log.Error("parsePushParams(\"%s\") error(%v)", r.URL.String(), err)
+		res["ret"] = ParamErr
+		return
+	}
+	if bodyBytes, err = ioutil.ReadAll(r.Body); err != nil {
+		log.Error("ioutil.ReadAll() failed (%v)", err)
+		res["ret"] = InternalErr
+		return
+	}
+	body = string(bodyBytes)
+	if !appQuota.Allow(Conf.AppQuota, appId) {
+		log.Warn("app: %d push quota exceeded", appId)
+		res["ret"] = QuotaErr
+		return
+	}
+	if reply, err = getSessions(appId, uid); err != nil {
+		res["ret"] = InternalErr
+		return
+	}
+	for i := 0; i < len(reply.Seqs); i++ {
+		if i < len(reply.Devices) && reply.Devices[i] == device {
+			divide[reply.Servers[i]] = append(divide[reply.Servers[i]], encode(appId, uid, reply.Seqs[i]))
+		}
+	}
+	if len(divide) == 0 {
+		res["ret"] = NotFoundErr
+		return
+	}
+	msgId = newMsgId()
+	ackStore.Push(msgId, appId, []int64{uid}, []int64{uid})
+	for server, subkeys := range divide {
+		msgs := make([][]byte, len(subkeys))
+		msgIds := make([]int64, len(subkeys))
+		for i := 0; i < len(subkeys); i++ {
+			msgs[i] = bodyBytes
+			msgIds[i] = msgId
+		}
+		if err = pushQueue.MPushs(server, subkeys, msgs, msgIds, operation); err != nil {
+			log.Error("pushQueue.MPushs(%d) error(%v)", server, err)
 			res["ret"] = InternalErr
 			return
 		}
 	}
-	res["ret"] = OK
+	res["mid"] = msgId
 	return
 }
 
+type pushsMsgsBody struct {
+	AppId     int32                      `json:"a"`
+	Operation int32                      `json:"o"`
+	Msgs      map[string]json.RawMessage `json:"m"`
+}
+
+// PushsMsgs push the different messages to the users of the app.
+// POST /1/pushs/msgs, body is {"a":0,"o":5,"m":{"1":{"test":1},"2":{"test":2}}},
+// a and o are optional, m is the message of the user id, the offline users
+// are not saved, return the message id of every user.
+func PushsMsgs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		bodyBytes []byte
+		body      string
+		uid       int64
+		err       error
+		divide    map[int32][]string
+		tmp       pushsMsgsBody
+		userIds   []int64
+		failed    []int64
+		onlines   []int64
+		msgs      = make(map[int64][]byte)
+		msgIds    = make(map[int64]int64)
+		online    = make(map[int64]bool)
+		mids      = make(map[string]int64)
+		res       = map[string]interface{}{"ret": OK}
+	)
+	defer retPWrite(w, r, res, &body, time.Now())
+	if bodyBytes, err = ioutil.ReadAll(r.Body); err != nil {
+		log.Error("ioutil.ReadAll() failed (%v)", err)
+		res["ret"] = InternalErr
+		return
+	}
+	body = string(bodyBytes)
+	if err = json.Unmarshal(bodyBytes, &tmp); err != nil {
+		log.Error("json.Unmarshal(\"%s\") error(%v)", body, err)
+		res["ret"] = ParamErr
+		return
+	}
+	for uidStr, msg := range tmp.Msgs {
+		if uid, err = strconv.ParseInt(uidStr, 10, 64); err != nil {
+			log.Error("strconv.ParseInt(\"%s\") error(%v)", uidStr, err)
+			res["ret"] = ParamErr
+			return
+		}
+		userIds = append(userIds, uid)
+		msgs[uid] = msg
+	}
+	if !appQuota.Allow(Conf.AppQuota, tmp.AppId) {
+		log.Warn("app: %d push quota exceeded", tmp.AppId)
+		res["ret"] = QuotaErr
+		return
+	}
+	if divide, failed, err = divideToRouter(tmp.AppId, userIds); err != nil {
+		log.Error("divideToComet() error(%v)", err)
+		res["ret"] = InternalErr
+		return
+	}
+	if len(failed) > 0 {
+		res["failed"] = failed
+		userIds = offlineUsers(userIds, failed)
+	}
+	// record the receivers before push, the ack may come back quickly
+	for _, uid = range onlineUsers(divide) {
+		online[uid] = true
+	}
+	for _, uid = range userIds {
+		if onlines = nil; online[uid] {
+			onlines = []int64{uid}
+		}
+		msgIds[uid] = newMsgId()
+		ackStore.Push(msgIds[uid], tmp.AppId, []int64{uid}, onlines)
+		mids[strconv.FormatInt(uid, 10)] = msgIds[uid]
+	}
+	res["mids"] = mids
+	for server, subkeys := range divide {
+		serverMsgs := make([][]byte, len(subkeys))
+		serverMsgIds := make([]int64, len(subkeys))
+		for i, subkey := range subkeys {
+			if _, uid, _, err = decode(subkey); err == nil {
+				serverMsgs[i] = msgs[uid]
+				serverMsgIds[i] = msgIds[uid]
+			}
+		}
+		if err = pushQueue.MPushs(server, subkeys, serverMsgs, serverMsgIds, tmp.Operation); err != nil {
+			log.Error("pushQueue.MPushs(%d) error(%v)", server, err)
+			res["ret"] = InternalErr
+			return
+		}
+	}
+	return
+}
+
+// onlineUsers return the user ids of the subkeys.
+func onlineUsers(divide map[int32][]string) (userIds []int64) {
+	for _, subkeys := range divide {
+		for _, subkey := range subkeys {
+			if _, uid, _, err := decode(subkey); err == nil {
+				userIds = append(userIds, uid)
+			}
+		}
+	}
+	return
+}
+
+// offlineUsers return the user ids not in the onlines.
+func offlineUsers(userIds, onlines []int64) (offlines []int64) {
+	online := make(map[int64]struct{}, len(onlines))
+	for _, uid := range onlines {
+		online[uid] = struct{}{}
+	}
+	for _, uid := range userIds {
+		if _, ok := online[uid]; !ok {
+			offlines = append(offlines, uid)
+		}
+	}
+	return
+}
+
+// MsgStatus get the delivery status of the message, 0: offline, 1: sent,
+// 2: acked. /1/msg/status?mid=xxx&uid=xxx, uid is optional, all the users
+// of the message are returned if not set.
+func MsgStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		msgId, uid int64
+		status     int
+		users      map[int64]int
+		ok         bool
+		err        error
+		params     = r.URL.Query()
+		res        = map[string]interface{}{"ret": OK}
+	)
+	defer retWrite(w, r, res, time.Now())
+	if msgId, err = strconv.ParseInt(params.Get("mid"), 10, 64); err != nil {
+		log.Error("strconv.ParseInt(\"%s\") error(%v)", params.Get("mid"), err)
+		res["ret"] = ParamErr
+		return
+	}
+	if uidStr := params.Get("uid"); uidStr != "" {
+		if uid, err = strconv.ParseInt(uidStr, 10, 64); err != nil {
+			log.Error("strconv.ParseInt(\"%s\") error(%v)", uidStr, err)
+			res["ret"] = ParamErr
+			return
+		}
+		if status, ok = ackStore.UserStatus(msgId, uid); !ok {
+			res["ret"] = NotFoundErr
+			return
+		}
+		res["data"] = map[string]interface{}{"uid": uid, "status": status}
+		return
+	}
+	if users, ok = ackStore.Status(msgId); !ok {
+		res["ret"] = NotFoundErr
+		return
+	}
+	data := make(map[string]int, len(users))
+	for uid, status = range users {
+		data[strconv.FormatInt(uid, 10)] = status
+	}
+	res["data"] = data
+	return
+}
+
+// parseAppId parse the optional appid of the query, has is false if not set.
+func parseAppId(params url.Values) (appId int32, has bool, err error) {
+	var i int64
+	if s := params.Get("appid"); s != "" {
+		if i, err = strconv.ParseInt(s, 10, 32); err != nil {
+			return
+		}
+		appId, has = int32(i), true
+	}
+	return
+}
+
+// Online get the online users of the list.
+// GET /1/online?uids=1,2,3&appid=0, appid is optional, default 0, data is
+// the online user ids.
+func Online(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		uid      int64
+		appId    int32
+		err      error
+		userIds  []int64
+		failed   []int64
+		sessions map[int64]*rproto.GetReply
+		params   = r.URL.Query()
+		uidsStr  = params.Get("uids")
+		res      = map[string]interface{}{"ret": OK}
+	)
+	defer retWrite(w, r, res, time.Now())
+	if uidsStr == "" {
+		res["ret"] = ParamErr
+		return
+	}
+	for _, uidStr := range strings.Split(uidsStr, ",") {
+		if uid, err = strconv.ParseInt(uidStr, 10, 64); err != nil {
+			log.Error("strconv.ParseInt(\"%s\") error(%v)", uidStr, err)
+			res["ret"] = ParamErr
+			return
+		}
+		userIds = append(userIds, uid)
+	}
+	if appId, _, err = parseAppId(params); err != nil {
+		log.Error("parseAppId(\"%s\") error(%v)", params.Get("appid"), err)
+		res["ret"] = ParamErr
+		return
+	}
+	if sessions, failed, err = mgetSessions(appId, userIds); err != nil {
+		res["ret"] = InternalErr
+		return
+	}
+	onlines := make([]int64, 0, len(sessions))
+	for i := 0; i < len(userIds); i++ {
+		if _, ok := sessions[userIds[i]]; ok {
+			onlines = append(onlines, userIds[i])
+			delete(sessions, userIds[i])
+		}
+	}
+	res["data"] = onlines
+	if len(failed) > 0 {
+		res["failed"] = failed
+	}
+	return
+}
+
+// OnlineSessions get the active sessions of a user.
+// GET /1/online/sessions?uid=1&appid=0, appid is optional, default 0, data
+// is [{"key":"1_1","server":1,"device":"ios"}].
+func OnlineSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		uid    int64
+		appId  int32
+		err    error
+		reply  *rproto.GetReply
+		params = r.URL.Query()
+		res    = map[string]interface{}{"ret": OK}
+	)
+	defer retWrite(w, r, res, time.Now())
+	if uid, err = strconv.ParseInt(params.Get("uid"), 10, 64); err != nil {
+		log.Error("strconv.ParseInt(\"%s\") error(%v)", params.Get("uid"), err)
+		res["ret"] = ParamErr
+		return
+	}
+	if appId, _, err = parseAppId(params); err != nil {
+		log.Error("parseAppId(\"%s\") error(%v)", params.Get("appid"), err)
+		res["ret"] = ParamErr
+		return
+	}
+	if reply, err = getSessions(appId, uid); err != nil {
+		res["ret"] = InternalErr
+		return
+	}
+	data := make([]map[string]interface{}, len(reply.Seqs))
+	for i := 0; i < len(reply.Seqs); i++ {
+		data[i] = map[string]interface{}{"key": encode(appId, uid, reply.Seqs[i]), "server": reply.Servers[i]}
+		if i < len(reply.Devices) && reply.Devices[i] != "" {
+			data[i]["device"] = reply.Devices[i]
+		}
+	}
+	res["data"] = data
+	return
+}
+
+// OnlineCount get the online users and sessions count.
+// GET /1/online/count?appid=0, appid is optional, count all the apps if not
+// set, data is {"users":1,"sessions":2}.
+func OnlineCount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", 405)
+		return
+	}
+	var (
+		appId           int32
+		has             bool
+		users, sessions int32
+		failed          []string
+		err             error
+		params          = r.URL.Query()
+		res             = map[string]interface{}{"ret": OK}
+	)
+	defer retWrite(w, r, res, time.Now())
+	if appId, has, err = parseAppId(params); err != nil {
+		log.Error("parseAppId(\"%s\") error(%v)", params.Get("appid"), err)
+		res["ret"] = ParamErr
+		return
+	}
+	if users, sessions, failed, err = countOnline(appId, !has); err != nil {
+		res["ret"] = InternalErr
+		return
+	}
+	res["data"] = map[string]interface{}{"users": users, "sessions": sessions}
+	if len(failed) > 0 {
+		res["failed"] = failed
+	}
+	return
+}
+
+// PushAll broadcast the message to all the users of the app.
+// POST /1/push/all?appid=1, body is the message, appid is optional, default 0.
 func PushAll(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method Not Allowed", 405)
@@ -125,18 +699,31 @@ func PushAll(w http.ResponseWriter, r *http.Request) {
 	var (
 		bodyBytes []byte
 		body      string
+		appId     int64
 		err       error
 		ret       = OK
 		res       = map[string]interface{}{"ret": ret}
 	)
 	defer retPWrite(w, r, res, &body, time.Now())
+	if appIdStr := r.URL.Query().Get("appid"); appIdStr != "" {
+		if appId, err = strconv.ParseInt(appIdStr, 10, 32); err != nil {
+			log.Error("strconv.ParseInt(\"%s\") error(%v)", appIdStr, err)
+			res["ret"] = ParamErr
+			return
+		}
+	}
+	if !appQuota.Allow(Conf.AppQuota, int32(appId)) {
+		log.Warn("app: %d push quota exceeded", appId)
+		res["ret"] = QuotaErr
+		return
+	}
 	if bodyBytes, err = ioutil.ReadAll(r.Body); err != nil {
 		log.Error("ioutil.ReadAll() failed (%v)", err)
 		ret = InternalErr
 	} else {
 		body = string(bodyBytes)
-		if err := broadcastTokafka(bodyBytes); err != nil {
-			log.Error("broadcastTokafka(\"%s\") error(%s)", body, err)
+		if err := pushQueue.Broadcast(int32(appId), bodyBytes); err != nil {
+			log.Error("pushQueue.Broadcast(\"%s\") error(%s)", body, err)
 			ret = InternalErr
 		}
 	}
@@ -3,80 +3,106 @@ package main
 import (
 	log "code.google.com/p/log4go"
 	"github.com/Terry-Mao/goim/define"
-	inet "github.com/Terry-Mao/goim/libs/net"
+	"github.com/Terry-Mao/goim/libs/comets"
+	"github.com/Terry-Mao/goim/libs/registry"
 	cproto "github.com/Terry-Mao/goim/proto/comet"
 	"github.com/Terry-Mao/protorpc"
 	"time"
 )
 
 var (
-	cometServiceMap = make(map[int32]**protorpc.Client)
-)
-
-const (
-	CometService          = "PushRPC"
-	CometServicePing      = "PushRPC.Ping"
-	CometServicePushMsg   = "PushRPC.PushMsg"
-	CometServicePushMsgs  = "PushRPC.PushMsgs"
-	CometServiceMPushMsg  = "PushRPC.MPushMsg"
-	CometServiceMPushMsgs = "PushRPC.MPushMsgs"
-	CometServiceBroadcast = "PushRPC.Broadcast"
+	cometClients = comets.New()
 )
 
+// InitComet connect the comets of the [comets] section, the comet not
+// available now is reconnected in background.
 func InitComet(addrs map[int32]string) (err error) {
-	for serverID, addrs := range addrs {
-		var (
-			rpcClient     *protorpc.Client
-			quit          chan struct{}
-			network, addr string
-		)
-		if network, addr, err = inet.ParseNetwork(addrs); err != nil {
-			log.Error("inet.ParseNetwork() error(%v)", err)
-			return
-		}
-		if rpcClient, err = protorpc.Dial(network, addr); err != nil {
-			log.Error("protorpc.Dial(\"%s\") error(%s)", addr, err)
+	for serverID, addr := range addrs {
+		if err = cometClients.Add(serverID, addr); err != nil {
 			return
 		}
-		go protorpc.Reconnect(&rpcClient, quit, network, addr)
-		log.Info("rpc addr:%s connected", addr)
-		cometServiceMap[serverID] = &rpcClient
 	}
 	return
 }
 
-// get comet server client by server id
-func getCometByServerId(serverID int32) (*protorpc.Client, error) {
-	if client, ok := cometServiceMap[serverID]; !ok || *client == nil {
-		return nil, ErrComet
-	} else {
-		return *client, nil
+// InitCometRegistry watch the comets of the registry, the comets join and
+// leave at runtime, the [comets] section is ignored.
+func InitCometRegistry() (err error) {
+	var r registry.Registry
+	if Conf.RegistryType == "" {
+		return
+	}
+	if r, err = registry.New(Conf.RegistryType, Conf.RegistryDir, Conf.RegistryTTL); err != nil {
+		log.Error("registry.New(\"%s\") error(%v)", Conf.RegistryType, err)
+		return
 	}
+	go func() {
+		for nodes := range registry.Watch(r, Conf.RegistryWatch, nil) {
+			cometClients.Sync(nodes)
+		}
+	}()
+	return
 }
 
-func mpushComet(c *protorpc.Client, serverId int32, subkeys []string, body []byte) {
+func mpushComet(c *protorpc.Client, serverId int32, subkeys []string, body []byte, operation, priority int32, msgId int64) (err error) {
 	var (
 		now  = time.Now()
-		args = &cproto.MPushMsgArg{Keys: subkeys, Operation: define.OP_SEND_SMS_REPLY, Msg: body}
+		args = &cproto.MPushMsgArg{Keys: subkeys, Operation: comets.Operation(operation), Msg: body, Priority: priority, MsgId: msgId}
 		rep  = &cproto.MPushMsgReply{}
-		err  error
 	)
-	if err = c.Call(CometServiceMPushMsg, args, rep); err != nil {
-		log.Error("c.Call(\"%s\", %v, reply) error(%v)", CometServiceMPushMsg, *args, err)
+	if err = c.Call(comets.ServiceMPushMsg, args, rep); err != nil {
+		log.Error("c.Call(\"%s\", %v, reply) error(%v)", comets.ServiceMPushMsg, *args, err)
 	} else {
 		log.Info("push msg to serverId:%d index:%d(%f)", serverId, rep.Index, time.Now().Sub(now).Seconds())
 	}
+	return
+}
+
+// pushMsgsComet push the messages to a sub key.
+func pushMsgsComet(c *protorpc.Client, serverId int32, subkey string, msgs [][]byte, msgIds []int64, operation int32) (err error) {
+	var (
+		now  = time.Now()
+		args = &cproto.PushMsgsArg{Key: subkey, Vers: make([]int32, len(msgs)), Operations: make([]int32, len(msgs)), Msgs: msgs, MsgIds: msgIds}
+		rep  = &cproto.PushMsgsReply{}
+	)
+	for i := 0; i < len(msgs); i++ {
+		args.Operations[i] = comets.Operation(operation)
+	}
+	if err = c.Call(comets.ServicePushMsgs, args, rep); err != nil {
+		log.Error("c.Call(\"%s\", %v, reply) error(%v)", comets.ServicePushMsgs, *args, err)
+	} else {
+		log.Info("push msgs to serverId:%d index:%d(%f)", serverId, rep.Index, time.Now().Sub(now).Seconds())
+	}
+	return
+}
+
+// mpushMsgsComet push the message to the sub key of the same index.
+func mpushMsgsComet(c *protorpc.Client, serverId int32, subkeys []string, msgs [][]byte, msgIds []int64, operation int32) (err error) {
+	var (
+		now  = time.Now()
+		args = &cproto.MPushMsgsArg{Keys: subkeys, Vers: make([]int32, len(subkeys)), Operations: make([]int32, len(subkeys)), Msgs: msgs, MsgIds: msgIds}
+		rep  = &cproto.MPushMsgsReply{}
+	)
+	for i := 0; i < len(subkeys); i++ {
+		args.Operations[i] = comets.Operation(operation)
+	}
+	if err = c.Call(comets.ServiceMPushMsgs, args, rep); err != nil {
+		log.Error("c.Call(\"%s\", %v, reply) error(%v)", comets.ServiceMPushMsgs, *args, err)
+	} else {
+		log.Info("mpush msgs to serverId:%d index:%d(%f)", serverId, rep.Index, time.Now().Sub(now).Seconds())
+	}
+	return
 }
 
-func broadcastComet(c *protorpc.Client, serverId int32, msg []byte) {
+func broadcastComet(c *protorpc.Client, serverId int32, appId int32, msg []byte) (err error) {
 	var (
 		now  = time.Now()
-		args = &cproto.BoardcastArg{Ver: 0, Operation: define.OP_SEND_SMS_REPLY, Msg: msg}
-		err  error
+		args = &cproto.BoardcastArg{Ver: 0, Operation: define.OP_SEND_SMS_REPLY, Msg: msg, AppId: appId}
 	)
-	if err = c.Call(CometServiceBroadcast, args, nil); err != nil {
-		log.Error("c.Call(\"%s\", %v, reply) error(%v)", CometServiceBroadcast, *args, err)
+	if err = c.Call(comets.ServiceBroadcast, args, nil); err != nil {
+		log.Error("c.Call(\"%s\", %v, reply) error(%v)", comets.ServiceBroadcast, *args, err)
 	} else {
-		log.Info("broadcast msg to serverId:%d msg:%s(%f)", serverId, msg, time.Now().Sub(now).Seconds())
+		log.Info("broadcast msg to serverId:%d appId:%d msg:%s(%f)", serverId, appId, msg, time.Now().Sub(now).Seconds())
 	}
+	return
 }
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"github.com/Terry-Mao/goconf"
 	"strconv"
+	"time"
 )
 
 var (
@@ -22,21 +23,51 @@ type Config struct {
 	ZKAddrs           []string          `goconf:"kafka:zookeeper.list:,"`
 	ZKRoot            string            `goconf:"kafka:zkroot"`
 	KafkaTopic        string            `goconf:"kafka:topic"`
+	ConsumeInflight   int               `goconf:"kafka:inflight"`
 	Comets            map[int32]string  `goconf:"-"`
 	RouterRPCNetworks []string          `goconf:"router:networks:,"`
 	PushChan          int               `goconf:"push:chan"`
 	PushChanSize      int               `goconf:"push:chan.size"`
 	RouterRPCAddrs    map[string]string `-`
+	// retry
+	RetryMax        int           `goconf:"retry:max"`
+	RetryBackoff    time.Duration `goconf:"retry:backoff:time"`
+	RetryMaxBackoff time.Duration `goconf:"retry:backoff.max:time"`
+	RetryChanSize   int           `goconf:"retry:chan.size"`
+	// dead letter
+	DeadLetterType       string   `goconf:"deadletter:type"`
+	DeadLetterFile       string   `goconf:"deadletter:file"`
+	DeadLetterKafkaAddrs []string `goconf:"deadletter:kafka.addrs:,"`
+	DeadLetterTopic      string   `goconf:"deadletter:topic"`
+	// registry
+	RegistryType  string        `goconf:"registry:type"`
+	RegistryDir   string        `goconf:"registry:dir"`
+	RegistryTTL   time.Duration `goconf:"registry:ttl:time"`
+	RegistryWatch time.Duration `goconf:"registry:watch:time"`
 }
 
 func NewConfig() *Config {
 	return &Config{
-		Comets:         make(map[int32]string),
-		ZKRoot:         "",
-		KafkaTopic:     "kafka_topic_push",
-		RouterRPCAddrs: make(map[string]string),
-		PushChan:       4,
-		PushChanSize:   100,
+		Comets:          make(map[int32]string),
+		ZKRoot:          "",
+		KafkaTopic:      "kafka_topic_push",
+		ConsumeInflight: 1024,
+		RouterRPCAddrs:  make(map[string]string),
+		PushChan:        4,
+		PushChanSize:    100,
+		// retry
+		RetryMax:        3,
+		RetryBackoff:    100 * time.Millisecond,
+		RetryMaxBackoff: 5 * time.Second,
+		RetryChanSize:   1024,
+		// dead letter
+		DeadLetterType:  deadLetterFile,
+		DeadLetterFile:  "./deadletter.log",
+		DeadLetterTopic: "KafkaPushsDeadTopic",
+		// registry
+		RegistryDir:   "./registry",
+		RegistryTTL:   30 * time.Second,
+		RegistryWatch: 1 * time.Second,
 	}
 }
 
@@ -0,0 +1,126 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"github.com/Terry-Mao/goim/define"
+	"github.com/Terry-Mao/goim/libs/mq"
+	lproto "github.com/Terry-Mao/goim/proto/logic"
+	"github.com/gogo/protobuf/proto"
+	"sync"
+)
+
+const (
+	KAFKA_GROUP_NAME = "kafka_topic_push_group"
+)
+
+func InitKafka() error {
+	log.Info("start topic:%s consumer", Conf.KafkaTopic)
+	log.Info("consumer group name:%s", KAFKA_GROUP_NAME)
+	c, err := mq.NewKafkaConsumer(KAFKA_GROUP_NAME, []string{Conf.KafkaTopic}, Conf.ZKAddrs, Conf.ZKRoot)
+	if err != nil {
+		return err
+	}
+	go consume(c)
+	return nil
+}
+
+// consume push the messages of the consumer until it closed, the messages
+// are pushed asynchronously and the offset is committed after all the
+// pushes of it and the messages before it are accepted by the comets or
+// dead-lettered.
+func consume(c mq.Consumer) {
+	var (
+		m  *consumeMsg
+		cm = newCommitter(c, Conf.ConsumeInflight)
+	)
+	for msg := range c.Messages() {
+		m = cm.add(msg)
+		push(msg.Key, msg.Value, &m.done)
+		go func(m *consumeMsg) {
+			m.done.Wait()
+			cm.finish(m)
+		}(m)
+	}
+}
+
+// consumeMsg is a message being pushed, done when all the pushes of it
+// finished.
+type consumeMsg struct {
+	msg      *mq.Message
+	done     sync.WaitGroup
+	finished bool
+}
+
+// committer commit the contiguous finished messages in the consumed order,
+// a message not finished block the commit of the ones after it, the
+// inflight limit the consumed messages not committed.
+type committer struct {
+	c        mq.Consumer
+	lock     sync.Mutex
+	pending  []*consumeMsg
+	inflight chan struct{}
+}
+
+func newCommitter(c mq.Consumer, inflight int) *committer {
+	if inflight <= 0 {
+		inflight = 1
+	}
+	return &committer{c: c, inflight: make(chan struct{}, inflight)}
+}
+
+// add track the consumed message, block if the inflight is full.
+func (cm *committer) add(msg *mq.Message) (m *consumeMsg) {
+	cm.inflight <- struct{}{}
+	m = &consumeMsg{msg: msg}
+	cm.lock.Lock()
+	cm.pending = append(cm.pending, m)
+	cm.lock.Unlock()
+	return
+}
+
+// finish mark the message finished and commit the finished prefix, every
+// message is committed for the offsets of the partitions.
+func (cm *committer) finish(m *consumeMsg) {
+	cm.lock.Lock()
+	m.finished = true
+	for len(cm.pending) > 0 && cm.pending[0].finished {
+		m = cm.pending[0]
+		cm.pending[0] = nil
+		cm.pending = cm.pending[1:]
+		if err := cm.c.Commit(m.msg); err != nil {
+			log.Error("c.Commit(\"%s\") error(%v)", m.msg.Key, err)
+		}
+		<-cm.inflight
+	}
+	cm.lock.Unlock()
+}
+
+func push(op string, msg []byte, done *sync.WaitGroup) (err error) {
+	if op == define.KAFKA_MESSAGE_MULTI {
+		m := &lproto.PushsMsg{}
+		if err = proto.Unmarshal(msg, m); err != nil {
+			log.Error("proto.Unmarshal(%s) serverId:%d error(%s)", msg, err)
+			return
+		}
+		mpush(m.Server, m.SubKeys, m.Msg, m.Operation, m.Priority, m.MsgId, done)
+	} else if op == define.KAFKA_MESSAGE_MULTI_MSGS {
+		m := &lproto.MPushsMsg{}
+		if err = proto.Unmarshal(msg, m); err != nil {
+			log.Error("proto.Unmarshal(%s) error(%s)", msg, err)
+			return
+		}
+		mpushs(m.Server, m.SubKeys, m.Msgs, m.MsgIds, m.Operation, done)
+	} else if op == define.KAFKA_MESSAGE_BROADCAST {
+		broadcast(0, msg, done)
+	} else if op == define.KAFKA_MESSAGE_BROADCAST_APP {
+		m := &lproto.BroadcastMsg{}
+		if err = proto.Unmarshal(msg, m); err != nil {
+			log.Error("proto.Unmarshal(%s) error(%s)", msg, err)
+			return
+		}
+		broadcast(m.AppId, m.Msg, done)
+	} else {
+		log.Error("unknown message type:%s", op)
+	}
+	return
+}
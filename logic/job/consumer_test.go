@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/Terry-Mao/goim/define"
+	"github.com/Terry-Mao/goim/libs/mq"
+	lproto "github.com/Terry-Mao/goim/proto/logic"
+	"github.com/gogo/protobuf/proto"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConsume(t *testing.T) {
+	var (
+		mem  = mq.NewMemory(1)
+		c, _ = mem.Consumer("push")
+		ch   = make(chan *pushArg, 1)
+	)
+	Conf = NewConfig()
+	Conf.PushChan = 1
+	pushChs = []chan *pushArg{ch}
+	go consume(c)
+	defer mem.Close()
+	b, _ := proto.Marshal(&lproto.PushsMsg{Server: 1, SubKeys: []string{"1_1"}, Msg: []byte("{}"), Operation: 5, MsgId: 10})
+	if err := mem.Send("push", define.KAFKA_MESSAGE_MULTI, b); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case arg := <-ch:
+		if arg.Server != 1 || arg.SubKeys[0] != "1_1" || arg.Operation != 5 || arg.MsgId != 10 {
+			t.Fatalf("push arg: %v", arg)
+		}
+		arg.finish()
+	case <-time.After(time.Second):
+		t.Fatal("push timeout")
+	}
+}
+
+// testConsumer record the committed messages.
+type testConsumer struct {
+	commits []string
+}
+
+func (c *testConsumer) Messages() <-chan *mq.Message { return nil }
+func (c *testConsumer) Close() error                 { return nil }
+
+func (c *testConsumer) Commit(m *mq.Message) error {
+	c.commits = append(c.commits, m.Key)
+	return nil
+}
+
+func TestCommitter(t *testing.T) {
+	var (
+		c  = &testConsumer{}
+		cm = newCommitter(c, 3)
+		ms []*consumeMsg
+	)
+	for _, key := range []string{"1", "2", "3"} {
+		ms = append(ms, cm.add(&mq.Message{Key: key}))
+	}
+	// the slow first message hold the commit
+	cm.finish(ms[2])
+	cm.finish(ms[1])
+	if len(c.commits) != 0 {
+		t.Fatalf("commits: %v", c.commits)
+	}
+	cm.finish(ms[0])
+	if len(c.commits) != 3 || c.commits[0] != "1" || c.commits[2] != "3" {
+		t.Fatalf("commits: %v", c.commits)
+	}
+	// the inflight released
+	for i := 0; i < 3; i++ {
+		cm.add(&mq.Message{})
+	}
+}
+
+func TestRetryDeadLetter(t *testing.T) {
+	var (
+		done sync.WaitGroup
+		d    deadLetterMsg
+		m    lproto.PushsMsg
+		mem  = mq.NewMemory(1)
+		c, _ = mem.Consumer("dead")
+	)
+	Conf = NewConfig()
+	Conf.PushChan = 1
+	Conf.RetryMax = 2
+	Conf.RetryBackoff = time.Millisecond
+	InitPush()
+	InitRetry()
+	deadLetters = NewMQDeadLetter(mem, "dead")
+	defer func() { deadLetters = nil }()
+	// no comet 99
+	mpush(99, []string{"1_1"}, []byte("{}"), 5, 0, 10, &done)
+	done.Wait()
+	msg := <-c.Messages()
+	if err := json.Unmarshal(msg.Value, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Server != 99 || d.Key != define.KAFKA_MESSAGE_MULTI || d.Retries != 2 || d.Error != ErrComet.Error() {
+		t.Fatalf("dead letter: %v", d)
+	}
+	if err := proto.Unmarshal(d.Value, &m); err != nil || m.MsgId != 10 || m.SubKeys[0] != "1_1" {
+		t.Fatalf("dead letter msg: %v error(%v)", m, err)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	Conf = NewConfig()
+	for retries, d := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 10: 5 * time.Second} {
+		if b := retryBackoff(retries); b != d {
+			t.Fatalf("retryBackoff(%d) = %v, expect %v", retries, b, d)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"encoding/json"
+	"github.com/Terry-Mao/goim/define"
+	"github.com/Terry-Mao/goim/libs/mq"
+	lproto "github.com/Terry-Mao/goim/proto/logic"
+	"github.com/gogo/protobuf/proto"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	deadLetterFile  = "file"
+	deadLetterKafka = "kafka"
+)
+
+var (
+	deadLetters DeadLetter
+)
+
+// deadLetterMsg is the push exhausted the retries, the key and value are
+// the same as the push topic message for replay, the broadcast is replayed
+// to all the comets.
+type deadLetterMsg struct {
+	Server  int32  `json:"server"`
+	Key     string `json:"key"`
+	Value   []byte `json:"value"`
+	Retries int    `json:"retries"`
+	Error   string `json:"error"`
+	Time    int64  `json:"time"`
+}
+
+// DeadLetter store the pushes exhausted the retries.
+type DeadLetter interface {
+	// Write store the json encoded deadLetterMsg.
+	Write(key string, value []byte) error
+}
+
+// InitDeadLetter init the dead letter by Conf.DeadLetterType.
+func InitDeadLetter() (err error) {
+	switch Conf.DeadLetterType {
+	case deadLetterFile:
+		deadLetters, err = NewFileDeadLetter(Conf.DeadLetterFile)
+	case deadLetterKafka:
+		var producer *mq.KafkaProducer
+		if producer, err = mq.NewKafkaProducer(Conf.DeadLetterKafkaAddrs); err == nil {
+			deadLetters = NewMQDeadLetter(producer, Conf.DeadLetterTopic)
+		}
+	default:
+		err = ErrDeadLetterType
+	}
+	return
+}
+
+// deadLetter write the push to the dead letter and finish it, the offset is
+// committed even if the write failed.
+func deadLetter(arg *pushArg, err error) {
+	var (
+		v proto.Message
+		d = &deadLetterMsg{Server: arg.Server, Retries: arg.Retries, Error: err.Error(), Time: time.Now().Unix()}
+	)
+	defer arg.finish()
+	if arg.Broadcast {
+		d.Key, v = define.KAFKA_MESSAGE_BROADCAST_APP, &lproto.BroadcastMsg{AppId: arg.AppId, Msg: arg.Msg}
+	} else if arg.Msgs != nil {
+		d.Key, v = define.KAFKA_MESSAGE_MULTI_MSGS, &lproto.MPushsMsg{Server: arg.Server, SubKeys: arg.SubKeys, Msgs: arg.Msgs, Operation: arg.Operation, MsgIds: arg.MsgIds}
+	} else {
+		d.Key, v = define.KAFKA_MESSAGE_MULTI, &lproto.PushsMsg{Server: arg.Server, SubKeys: arg.SubKeys, Msg: arg.Msg, Operation: arg.Operation, Priority: arg.Priority, MsgId: arg.MsgId}
+	}
+	if d.Value, err = proto.Marshal(v); err != nil {
+		log.Error("proto.Marshal() error(%v)", err)
+		return
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		log.Error("json.Marshal() error(%v)", err)
+		return
+	}
+	log.Warn("dead letter server:%d key:%s retries:%d error:%s", d.Server, d.Key, d.Retries, d.Error)
+	if deadLetters == nil {
+		return
+	}
+	if err = deadLetters.Write(d.Key, b); err != nil {
+		log.Error("deadLetters.Write(\"%s\") error(%v)", b, err)
+	}
+}
+
+// FileDeadLetter append the dead letters to the file, one json a line.
+type FileDeadLetter struct {
+	lock sync.Mutex
+	f    *os.File
+}
+
+func NewFileDeadLetter(file string) (*FileDeadLetter, error) {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileDeadLetter{f: f}, nil
+}
+
+func (d *FileDeadLetter) Write(key string, value []byte) (err error) {
+	d.lock.Lock()
+	_, err = d.f.Write(append(value, '\n'))
+	d.lock.Unlock()
+	return
+}
+
+// MQDeadLetter send the dead letters to the topic.
+type MQDeadLetter struct {
+	producer mq.Producer
+	topic    string
+}
+
+func NewMQDeadLetter(producer mq.Producer, topic string) *MQDeadLetter {
+	return &MQDeadLetter{producer: producer, topic: topic}
+}
+
+func (d *MQDeadLetter) Write(key string, value []byte) error {
+	return d.producer.Send(d.topic, key, value)
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"errors"
+	"github.com/Terry-Mao/goim/libs/comets"
 )
 
 var (
-	ErrComet = errors.New("comet rpc is not available")
+	ErrComet          = comets.ErrComet
+	ErrRetryFull      = errors.New("retry queue full")
+	ErrDeadLetterType = errors.New("dead letter type error, must file or kafka")
 )
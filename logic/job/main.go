@@ -13,10 +13,18 @@ func main() {
 	}
 	log.LoadConfiguration(Conf.Log)
 	runtime.GOMAXPROCS(runtime.NumCPU())
-	if err := InitComet(Conf.Comets); err != nil {
+	if Conf.RegistryType == "" {
+		if err := InitComet(Conf.Comets); err != nil {
+			panic(err)
+		}
+	} else if err := InitCometRegistry(); err != nil {
 		panic(err)
 	}
 	InitPush()
+	InitRetry()
+	if err := InitDeadLetter(); err != nil {
+		panic(err)
+	}
 	if err := InitKafka(); err != nil {
 		panic(err)
 	}
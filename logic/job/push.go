@@ -2,8 +2,8 @@ package main
 
 import (
 	log "code.google.com/p/log4go"
-	"github.com/Terry-Mao/protorpc"
 	"math/rand"
+	"sync"
 )
 
 const (
@@ -11,10 +11,25 @@ const (
 )
 
 type pushArg struct {
-	C       *protorpc.Client
-	Server  int32
-	SubKeys []string
-	Msg     []byte
+	Server    int32
+	SubKeys   []string
+	Msg       []byte
+	Msgs      [][]byte // the message of the sub key, Msg is ignored if set
+	MsgIds    []int64  // the ids of the Msgs
+	Operation int32
+	Priority  int32
+	MsgId     int64
+	Broadcast bool // broadcast Msg to the channels of AppId
+	AppId     int32
+	Retries   int
+	done      *sync.WaitGroup // done when pushed or dead-lettered
+}
+
+// finish mark the push accepted by the comet or dead-lettered.
+func (arg *pushArg) finish() {
+	if arg.done != nil {
+		arg.done.Done()
+	}
 }
 
 var (
@@ -30,39 +45,103 @@ func InitPush() {
 }
 
 func processPush(ch chan *pushArg) {
-	var arg *pushArg
+	var (
+		arg *pushArg
+		err error
+	)
 	for {
 		arg = <-ch
-		mpushComet(arg.C, arg.Server, arg.SubKeys, arg.Msg)
+		if err = pushComet(arg); err != nil {
+			retry(arg, err)
+			continue
+		}
+		arg.finish()
+	}
+}
+
+// pushComet push the arg to the comet, the comet client is got every time
+// for the reconnected one.
+func pushComet(arg *pushArg) (err error) {
+	c, err := cometClients.Get(arg.Server)
+	if err != nil {
+		log.Error("cometClients.Get(%d) error(%v)", arg.Server, err)
+		return
 	}
+	if arg.Broadcast {
+		err = broadcastComet(c, arg.Server, arg.AppId, arg.Msg)
+	} else if arg.Msgs == nil {
+		err = mpushComet(c, arg.Server, arg.SubKeys, arg.Msg, arg.Operation, arg.Priority, arg.MsgId)
+	} else if len(arg.SubKeys) == 1 {
+		err = pushMsgsComet(c, arg.Server, arg.SubKeys[0], arg.Msgs, arg.MsgIds, arg.Operation)
+	} else {
+		err = mpushMsgsComet(c, arg.Server, arg.SubKeys, arg.Msgs, arg.MsgIds, arg.Operation)
+	}
+	return
 }
 
 func getPushCh() chan *pushArg {
 	return pushChs[rand.Int()%Conf.PushChan]
 }
 
+// dispatch queue the arg to the push workers, done is added for it.
+func dispatch(arg *pushArg, done *sync.WaitGroup) {
+	if done != nil {
+		done.Add(1)
+		arg.done = done
+	}
+	getPushCh() <- arg
+}
+
 // multi-userids push
-func mpush(server int32, subkeys []string, msg []byte) {
-	c, err := getCometByServerId(server)
-	if err != nil {
-		log.Error("getCometByServerId(\"%d\") error(%v)", server, err)
+func mpush(server int32, subkeys []string, msg []byte, operation, priority int32, msgId int64, done *sync.WaitGroup) {
+	i := 0
+	for i = 0; i < len(subkeys)/PUSH_MAX_BLOCK; i++ {
+		dispatch(&pushArg{Server: server, SubKeys: subkeys[i*PUSH_MAX_BLOCK : (i+1)*PUSH_MAX_BLOCK], Msg: msg, Operation: operation, Priority: priority, MsgId: msgId}, done)
+	}
+	dispatch(&pushArg{Server: server, SubKeys: subkeys[i*PUSH_MAX_BLOCK:], Msg: msg, Operation: operation, Priority: priority, MsgId: msgId}, done)
+}
+
+// multi-subkeys push, every subkey has its own message, a single subkey
+// receive all the messages.
+func mpushs(server int32, subkeys []string, msgs [][]byte, msgIds []int64, operation int32, done *sync.WaitGroup) {
+	if len(msgIds) != len(msgs) {
+		// the message of the old logic has no id
+		msgIds = make([]int64, len(msgs))
+	}
+	if len(subkeys) == 1 {
+		dispatch(&pushArg{Server: server, SubKeys: subkeys, Msgs: msgs, MsgIds: msgIds, Operation: operation}, done)
+		return
+	}
+	if len(subkeys) != len(msgs) {
+		log.Error("mpushs server:%d subkeys:%d msgs:%d not match", server, len(subkeys), len(msgs))
 		return
 	}
 	i := 0
 	for i = 0; i < len(subkeys)/PUSH_MAX_BLOCK; i++ {
-		getPushCh() <- &pushArg{C: c, Server: server, SubKeys: subkeys[i*PUSH_MAX_BLOCK : (i+1)*PUSH_MAX_BLOCK], Msg: msg}
+		dispatch(&pushArg{Server: server, SubKeys: subkeys[i*PUSH_MAX_BLOCK : (i+1)*PUSH_MAX_BLOCK], Msgs: msgs[i*PUSH_MAX_BLOCK : (i+1)*PUSH_MAX_BLOCK], MsgIds: msgIds[i*PUSH_MAX_BLOCK : (i+1)*PUSH_MAX_BLOCK], Operation: operation}, done)
+	}
+	if i*PUSH_MAX_BLOCK < len(subkeys) {
+		dispatch(&pushArg{Server: server, SubKeys: subkeys[i*PUSH_MAX_BLOCK:], Msgs: msgs[i*PUSH_MAX_BLOCK:], MsgIds: msgIds[i*PUSH_MAX_BLOCK:], Operation: operation}, done)
 	}
-	getPushCh() <- &pushArg{C: c, Server: server, SubKeys: subkeys[i*PUSH_MAX_BLOCK:], Msg: msg}
 }
 
-// mssage broadcast
-func broadcast(msg []byte) {
-	for serverId, c := range cometServiceMap {
-		if *c == nil {
-			log.Error("broadcast error(%v)", ErrComet)
-			return
+// mssage broadcast to the channels of the app
+func broadcast(appId int32, msg []byte, done *sync.WaitGroup) {
+	for _, serverId := range cometClients.Servers() {
+		arg := &pushArg{Server: serverId, Msg: msg, Broadcast: true, AppId: appId}
+		if done != nil {
+			done.Add(1)
+			arg.done = done
 		}
 		// WARN: broadcast called less than mpush, no need a ch for queue
-		go broadcastComet(*c, serverId, msg)
+		go pushBroadcast(arg)
+	}
+}
+
+func pushBroadcast(arg *pushArg) {
+	if err := pushComet(arg); err != nil {
+		retry(arg, err)
+		return
 	}
+	arg.finish()
 }
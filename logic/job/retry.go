@@ -0,0 +1,71 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"time"
+)
+
+var (
+	retryCh chan *retryArg
+)
+
+type retryArg struct {
+	arg *pushArg
+	at  time.Time
+}
+
+// InitRetry start the bounded retry queue of the failed comet pushes.
+func InitRetry() {
+	retryCh = make(chan *retryArg, Conf.RetryChanSize)
+	go processRetry()
+}
+
+// retryBackoff return the delay of the retries, doubled every retry and
+// limited by Conf.RetryMaxBackoff.
+func retryBackoff(retries int) time.Duration {
+	d := Conf.RetryBackoff
+	for i := 1; i < retries && d < Conf.RetryMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > Conf.RetryMaxBackoff {
+		d = Conf.RetryMaxBackoff
+	}
+	return d
+}
+
+// retry queue the failed push, the push exhausted the retries or not queued
+// for the full queue is dead-lettered.
+func retry(arg *pushArg, err error) {
+	if arg.Retries >= Conf.RetryMax {
+		deadLetter(arg, err)
+		return
+	}
+	arg.Retries++
+	select {
+	case retryCh <- &retryArg{arg: arg, at: time.Now().Add(retryBackoff(arg.Retries))}:
+	default:
+		log.Error("retry queue full, server:%d retries:%d", arg.Server, arg.Retries)
+		deadLetter(arg, ErrRetryFull)
+	}
+}
+
+// processRetry requeue the push to the workers after the backoff, the queue
+// is FIFO so a short backoff may wait for the longer one before it.
+func processRetry() {
+	var (
+		r *retryArg
+		d time.Duration
+	)
+	for {
+		r = <-retryCh
+		if d = r.at.Sub(time.Now()); d > 0 {
+			time.Sleep(d)
+		}
+		log.Info("retry push server:%d retries:%d", r.arg.Server, r.arg.Retries)
+		if r.arg.Broadcast {
+			go pushBroadcast(r.arg)
+			continue
+		}
+		getPushCh() <- r.arg
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"sync"
+	"time"
+)
+
+var (
+	cometLiveness *CometLiveness
+)
+
+type cometBeat struct {
+	startTime int64
+	last      time.Time
+	dead      bool
+}
+
+// CometLiveness track the heartbeats of the comets, the sessions of the
+// comet timeout or restarted are purged from the routers, the comet never
+// disconnect them after crashed.
+type CometLiveness struct {
+	lock    sync.Mutex
+	timeout time.Duration
+	comets  map[int32]*cometBeat
+}
+
+func NewCometLiveness(timeout time.Duration) *CometLiveness {
+	return &CometLiveness{timeout: timeout, comets: make(map[int32]*cometBeat)}
+}
+
+// InitLiveness start the check of the comet heartbeats, 0 timeout disable
+// it.
+func InitLiveness() {
+	cometLiveness = NewCometLiveness(Conf.CometTimeout)
+	if Conf.CometTimeout > 0 {
+		go cometLiveness.check()
+	}
+}
+
+// Heartbeat renew the comet, lost is true if the comet restarted or was
+// purged for timeout, the sessions of it on the routers must be purged. The
+// first heartbeat of a comet run is always lost, the logic may be restarted
+// and never saw the last run of the comet.
+func (l *CometLiveness) Heartbeat(server int32, startTime int64, first bool, now time.Time) (lost bool) {
+	l.lock.Lock()
+	if c, ok := l.comets[server]; !ok {
+		lost = first
+		l.comets[server] = &cometBeat{startTime: startTime, last: now}
+	} else {
+		lost = c.dead || c.startTime != startTime
+		c.startTime, c.last, c.dead = startTime, now, false
+	}
+	l.lock.Unlock()
+	return
+}
+
+// Expired mark the comets without heartbeat in the timeout dead and return
+// them.
+func (l *CometLiveness) Expired(now time.Time) (servers []int32) {
+	l.lock.Lock()
+	for server, c := range l.comets {
+		if !c.dead && now.Sub(c.last) > l.timeout {
+			c.dead = true
+			servers = append(servers, server)
+		}
+	}
+	l.lock.Unlock()
+	return
+}
+
+func (l *CometLiveness) check() {
+	for {
+		time.Sleep(l.timeout / 2)
+		for _, server := range l.Expired(time.Now()) {
+			log.Warn("comet server: %d heartbeat timeout", server)
+			purgeServer(server)
+		}
+	}
+}
+
+// purgeServer delete the sessions of the comet from the routers.
+func purgeServer(server int32) {
+	count, err := delServer(server)
+	if err != nil {
+		log.Error("delServer(%d) error(%v)", server, err)
+	}
+	log.Info("purge comet server: %d sessions: %d", server, count)
+}
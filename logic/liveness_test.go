@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCometLiveness(t *testing.T) {
+	var (
+		l   = NewCometLiveness(time.Second)
+		now = time.Now()
+	)
+	if l.Heartbeat(1, 1, false, now) || l.Heartbeat(2, 1, false, now) {
+		t.Fatal("comet of the last logic lost")
+	}
+	if l.Heartbeat(1, 1, false, now.Add(time.Second)) {
+		t.Fatal("renewed comet lost")
+	}
+	if servers := l.Expired(now.Add(1500 * time.Millisecond)); len(servers) != 1 || servers[0] != 2 {
+		t.Fatalf("expired: %v", servers)
+	}
+	// purged once
+	if servers := l.Expired(now.Add(3 * time.Second)); len(servers) != 1 || servers[0] != 1 {
+		t.Fatalf("expired: %v", servers)
+	}
+	if !l.Heartbeat(2, 1, false, now.Add(3*time.Second)) {
+		t.Fatal("timeout comet not lost")
+	}
+	if !l.Heartbeat(2, 2, false, now.Add(3*time.Second)) {
+		t.Fatal("restarted comet not lost")
+	}
+	if l.Heartbeat(2, 2, false, now.Add(4*time.Second)) {
+		t.Fatal("comet lost")
+	}
+	if !l.Heartbeat(3, 1, true, now) {
+		t.Fatal("started comet not lost")
+	}
+}
@@ -22,11 +22,24 @@ func main() {
 	if err := InitRouter(); err != nil {
 		log.Warn("router rpc current can't connect, retry")
 	}
+	// delivery status
+	InitAck()
+	// comet heartbeats
+	InitLiveness()
+	// offline inbox
+	if err := InitOffline(); err != nil {
+		panic(err)
+	}
+	// push queue, before rpc for the offline replay
+	if err := InitQueue(); err != nil {
+		panic(err)
+	}
 	// start rpc
-	if err := InitRPC(NewDefaultAuther()); err != nil {
+	auther, err := InitAuther()
+	if err != nil {
 		panic(err)
 	}
-	if err := InitKafka(Conf.KafkaAddrs); err != nil {
+	if err := InitRPC(auther); err != nil {
 		panic(err)
 	}
 	// init http
@@ -0,0 +1,87 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"time"
+)
+
+const (
+	offlineStoreFile = "file"
+)
+
+var (
+	offlineStore OfflineStore
+)
+
+// OfflineMsg is a message pushed when the user has no session.
+type OfflineMsg struct {
+	MsgId     int64
+	Expire    int64 // unix seconds
+	Priority  int32
+	Operation int32
+	Msg       []byte
+}
+
+// OfflineStore is the per-user offline inbox, the users are scoped by the
+// app.
+type OfflineStore interface {
+	// Save append the message to the inbox of the user.
+	Save(appId int32, userId int64, m *OfflineMsg) error
+	// Fetch return the unexpired messages of the user in push order and
+	// clear the inbox.
+	Fetch(appId int32, userId int64) ([]*OfflineMsg, error)
+	// Close release the store.
+	Close() error
+}
+
+// InitOffline init the offline store by Conf.OfflineStore, empty disable it.
+func InitOffline() (err error) {
+	switch Conf.OfflineStore {
+	case "":
+		return
+	case offlineStoreFile:
+		offlineStore, err = NewFileStore(Conf.OfflineDir, Conf.OfflineMax)
+	default:
+		err = ErrOfflineStore
+	}
+	return
+}
+
+// saveOffline save the message to the inboxes of the offline users.
+func saveOffline(appId int32, userIds []int64, msgId int64, msg []byte, operation, priority int32) {
+	if offlineStore == nil {
+		return
+	}
+	m := &OfflineMsg{MsgId: msgId, Expire: time.Now().Add(Conf.OfflineTTL).Unix(), Priority: priority, Operation: operation, Msg: msg}
+	for _, uid := range userIds {
+		if err := offlineStore.Save(appId, uid, m); err != nil {
+			log.Error("offlineStore.Save(%d, %d, %d) error(%v)", appId, uid, msgId, err)
+		}
+	}
+}
+
+// replayOffline push the offline messages to the new session of the user.
+func replayOffline(appId int32, userId int64, server int32, key string) {
+	if offlineStore == nil {
+		return
+	}
+	msgs, err := offlineStore.Fetch(appId, userId)
+	if err != nil {
+		log.Error("offlineStore.Fetch(%d, %d) error(%v)", appId, userId, err)
+		return
+	}
+	for _, m := range msgs {
+		if err = pushQueue.MPush(server, []string{key}, m.Msg, m.Operation, m.Priority, m.MsgId); err != nil {
+			log.Error("pushQueue.MPush(%d, \"%s\") error(%v)", server, key, err)
+			// keep it for the next connect
+			if err = offlineStore.Save(appId, userId, m); err != nil {
+				log.Error("offlineStore.Save(%d, %d, %d) error(%v)", appId, userId, m.MsgId, err)
+			}
+			continue
+		}
+		ackStore.Sent(m.MsgId, appId, userId)
+	}
+	if len(msgs) > 0 {
+		log.Info("replay %d offline messages to app: %d user: %d key: \"%s\"", len(msgs), appId, userId, key)
+	}
+}
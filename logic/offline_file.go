@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	log "code.google.com/p/log4go"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	fileStoreLocks     = 256
+	fileStoreExt       = ".inbox"
+	fileStoreHeaderLen = 28 // msgId(8) expire(8) priority(4) operation(4) msgLen(4)
+	fileStoreMaxMsgLen = 1 << 20
+	fileStoreClean     = 10 * time.Minute
+)
+
+// FileStore is the embedded on-disk offline store, every user has an inbox
+// file named by the userKey keeping the latest max messages, the file is
+// removed when fetched or all the messages in it expired.
+type FileStore struct {
+	dir   string
+	max   int
+	locks [fileStoreLocks]sync.Mutex
+	quit  chan struct{}
+}
+
+// NewFileStore create the store in dir, the inbox keep the latest max
+// messages, 0 unlimited.
+func NewFileStore(dir string, max int) (s *FileStore, err error) {
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	s = &FileStore{dir: dir, max: max, quit: make(chan struct{})}
+	go s.clean()
+	return
+}
+
+func (s *FileStore) path(appId int32, userId int64) string {
+	return filepath.Join(s.dir, userKey(appId, userId)+fileStoreExt)
+}
+
+func (s *FileStore) lock(appId int32, userId int64) *sync.Mutex {
+	return &s.locks[(uint64(appId)+uint64(userId))%fileStoreLocks]
+}
+
+// Save append the message to the inbox, the expired messages are dropped
+// and the inbox keep the latest max messages.
+func (s *FileStore) Save(appId int32, userId int64, m *OfflineMsg) (err error) {
+	var (
+		f    *os.File
+		msgs []*OfflineMsg
+		name = s.path(appId, userId)
+		l    = s.lock(appId, userId)
+	)
+	l.Lock()
+	defer l.Unlock()
+	if s.max <= 0 {
+		if f, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+			return
+		}
+		_, err = f.Write(encodeInbox(nil, m))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return
+	}
+	if msgs, _, err = s.read(name, time.Now().Unix()); err != nil {
+		return
+	}
+	if msgs = append(msgs, m); len(msgs) > s.max {
+		msgs = msgs[len(msgs)-s.max:]
+	}
+	return s.write(name, msgs)
+}
+
+// read the unexpired messages of the inbox, expired is the count of the
+// dropped ones.
+func (s *FileStore) read(name string, now int64) (msgs []*OfflineMsg, expired int, err error) {
+	var f *os.File
+	if f, err = os.Open(name); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	msgs, expired, err = readInbox(bufio.NewReader(f), now)
+	f.Close()
+	return
+}
+
+// write replace the inbox with the messages atomically.
+func (s *FileStore) write(name string, msgs []*OfflineMsg) (err error) {
+	var (
+		buf []byte
+		tmp = name + ".tmp"
+	)
+	for _, m := range msgs {
+		buf = encodeInbox(buf, m)
+	}
+	if err = ioutil.WriteFile(tmp, buf, 0644); err != nil {
+		return
+	}
+	return os.Rename(tmp, name)
+}
+
+func encodeInbox(buf []byte, m *OfflineMsg) []byte {
+	var header [fileStoreHeaderLen]byte
+	binary.BigEndian.PutUint64(header[0:], uint64(m.MsgId))
+	binary.BigEndian.PutUint64(header[8:], uint64(m.Expire))
+	binary.BigEndian.PutUint32(header[16:], uint32(m.Priority))
+	binary.BigEndian.PutUint32(header[20:], uint32(m.Operation))
+	binary.BigEndian.PutUint32(header[24:], uint32(len(m.Msg)))
+	buf = append(buf, header[:]...)
+	return append(buf, m.Msg...)
+}
+
+func (s *FileStore) Fetch(appId int32, userId int64) (msgs []*OfflineMsg, err error) {
+	var (
+		name = s.path(appId, userId)
+		l    = s.lock(appId, userId)
+	)
+	l.Lock()
+	defer l.Unlock()
+	if msgs, _, err = s.read(name, time.Now().Unix()); err != nil {
+		return
+	}
+	if err = os.Remove(name); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	if s.max > 0 && len(msgs) > s.max {
+		msgs = msgs[len(msgs)-s.max:]
+	}
+	return
+}
+
+// readInbox read the unexpired messages, expired is the count of the
+// dropped ones, a truncated tail record written by a crash is ignored.
+func readInbox(rd io.Reader, now int64) (msgs []*OfflineMsg, expired int, err error) {
+	var (
+		n      int
+		header = make([]byte, fileStoreHeaderLen)
+	)
+	for {
+		if _, err = io.ReadFull(rd, header); err != nil {
+			break
+		}
+		m := &OfflineMsg{
+			MsgId:     int64(binary.BigEndian.Uint64(header[0:])),
+			Expire:    int64(binary.BigEndian.Uint64(header[8:])),
+			Priority:  int32(binary.BigEndian.Uint32(header[16:])),
+			Operation: int32(binary.BigEndian.Uint32(header[20:])),
+		}
+		if n = int(binary.BigEndian.Uint32(header[24:])); n > fileStoreMaxMsgLen {
+			log.Error("offline inbox msg length: %d invalid", n)
+			break
+		}
+		m.Msg = make([]byte, n)
+		if _, err = io.ReadFull(rd, m.Msg); err != nil {
+			break
+		}
+		if m.Expire > now {
+			msgs = append(msgs, m)
+		} else {
+			expired++
+		}
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return
+}
+
+func (s *FileStore) Close() error {
+	close(s.quit)
+	return nil
+}
+
+// clean drop the expired messages of the inboxes periodically.
+func (s *FileStore) clean() {
+	var (
+		names []string
+		err   error
+	)
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-time.After(fileStoreClean):
+		}
+		if names, err = filepath.Glob(filepath.Join(s.dir, "*"+fileStoreExt)); err != nil {
+			log.Error("filepath.Glob(\"%s\") error(%v)", s.dir, err)
+			continue
+		}
+		for _, name := range names {
+			s.expire(name, time.Now().Unix())
+		}
+	}
+}
+
+// expire drop the expired messages of the inbox by their expire, the inbox
+// file is removed if all the messages in it expired.
+func (s *FileStore) expire(name string, now int64) {
+	var (
+		appId   int32
+		userId  int64
+		msgs    []*OfflineMsg
+		expired int
+		err     error
+	)
+	if appId, userId, err = decodeUserKey(strings.TrimSuffix(filepath.Base(name), fileStoreExt)); err != nil {
+		return
+	}
+	l := s.lock(appId, userId)
+	l.Lock()
+	defer l.Unlock()
+	if msgs, expired, err = s.read(name, now); err != nil {
+		log.Error("read inbox \"%s\" error(%v)", name, err)
+	} else if len(msgs) == 0 {
+		if err = os.Remove(name); err != nil && !os.IsNotExist(err) {
+			log.Error("os.Remove(\"%s\") error(%v)", name, err)
+		}
+	} else if expired > 0 {
+		if err = s.write(name, msgs); err != nil {
+			log.Error("write inbox \"%s\" error(%v)", name, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewFileStore(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	now := time.Now().Unix()
+	for i, expire := range []int64{now + 60, now - 1, now + 60, now + 60} {
+		if err = s.Save(0, 1, &OfflineMsg{MsgId: int64(i), Expire: expire, Priority: 1, Operation: 5, Msg: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the same user id of another app
+	if err = s.Save(2, 1, &OfflineMsg{MsgId: 4, Expire: now + 60, Msg: []byte{4}}); err != nil {
+		t.Fatal(err)
+	}
+	// the inbox is trimmed on save
+	msgs, _, err := s.read(s.path(0, 1), now)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("inbox: %v, error(%v)", msgs, err)
+	}
+	if msgs, err = s.Fetch(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	// the expired dropped, keep the latest 2
+	if len(msgs) != 2 || msgs[0].MsgId != 2 || msgs[1].MsgId != 3 || msgs[1].Priority != 1 || msgs[1].Operation != 5 || msgs[1].Msg[0] != 3 {
+		t.Fatalf("msgs: %v", msgs)
+	}
+	if msgs, err = s.Fetch(0, 1); err != nil || len(msgs) != 0 {
+		t.Fatalf("fetch again: %v, error(%v)", msgs, err)
+	}
+	if msgs, err = s.Fetch(2, 1); err != nil || len(msgs) != 1 || msgs[0].MsgId != 4 {
+		t.Fatalf("fetch app 2: %v, error(%v)", msgs, err)
+	}
+}
+
+func TestFileStoreExpire(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewFileStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	now := time.Now().Unix()
+	s.Save(0, 1, &OfflineMsg{MsgId: 1, Expire: now + 10})
+	s.Save(0, 1, &OfflineMsg{MsgId: 2, Expire: now + 60})
+	s.Save(0, 2, &OfflineMsg{MsgId: 3, Expire: now + 10})
+	// the inbox saved just now expire by the messages
+	s.expire(s.path(0, 1), now+30)
+	s.expire(s.path(0, 2), now+30)
+	msgs, expired, err := s.read(s.path(0, 1), now)
+	if err != nil || expired != 0 || len(msgs) != 1 || msgs[0].MsgId != 2 {
+		t.Fatalf("inbox: %v, expired: %d, error(%v)", msgs, expired, err)
+	}
+	if _, err = os.Stat(s.path(0, 2)); !os.IsNotExist(err) {
+		t.Fatalf("os.Stat() error(%v)", err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/Terry-Mao/goim/libs/registry"
+)
+
+const (
+	queueKafka  = "kafka"
+	queueDirect = "direct"
+)
+
+var (
+	pushQueue Queue
+)
+
+// Queue deliver the pushes to the comets, the kafka queue is consumed by the
+// job, the direct queue call the comet rpc itself without kafka and job.
+type Queue interface {
+	// MPush push the message to the sub keys of the comet.
+	MPush(server int32, subkeys []string, msg []byte, operation, priority int32, msgId int64) error
+	// MPushs push the message to the sub key of the same index, a single
+	// sub key receive all the messages, msgIds are the ids of the msgs.
+	MPushs(server int32, subkeys []string, msgs [][]byte, msgIds []int64, operation int32) error
+	// Broadcast push the message to all the channels of the app.
+	Broadcast(appId int32, msg []byte) error
+}
+
+// InitQueue init the push queue by Conf.QueueType, empty use kafka.
+func InitQueue() (err error) {
+	switch Conf.QueueType {
+	case "", queueKafka:
+		pushQueue, err = NewKafkaQueue(Conf.KafkaAddrs)
+	case queueDirect:
+		if Conf.RegistryType == "" {
+			pushQueue, err = NewDirectQueue(Conf.Comets)
+			break
+		}
+		var r registry.Registry
+		if r, err = registry.New(Conf.RegistryType, Conf.RegistryDir, Conf.RegistryTTL); err == nil {
+			pushQueue = NewRegistryDirectQueue(r, Conf.RegistryWatch)
+		}
+	default:
+		err = ErrQueueType
+	}
+	return
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"github.com/Terry-Mao/goim/define"
+	"github.com/Terry-Mao/goim/libs/comets"
+	"github.com/Terry-Mao/goim/libs/registry"
+	cproto "github.com/Terry-Mao/goim/proto/comet"
+	rpc "github.com/Terry-Mao/protorpc"
+	"sync"
+	"time"
+)
+
+const (
+	// the max sub keys of a comet rpc, same as the job
+	directPushMaxBlock = 1000
+)
+
+// DirectQueue call the comet rpc in logic, the small deployment could run
+// without kafka and job.
+type DirectQueue struct {
+	comets *comets.Comets
+}
+
+// NewDirectQueue connect the comets of the [comets] section, the comet not
+// available now is reconnected in background.
+func NewDirectQueue(addrs map[int32]string) (*DirectQueue, error) {
+	q := &DirectQueue{comets: comets.New()}
+	for serverId, addr := range addrs {
+		if err := q.comets.Add(serverId, addr); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// NewRegistryDirectQueue connect the comets of the registry, the comets
+// join and leave at runtime.
+func NewRegistryDirectQueue(r registry.Registry, interval time.Duration) *DirectQueue {
+	q := &DirectQueue{comets: comets.New()}
+	go func() {
+		for nodes := range registry.Watch(r, interval, nil) {
+			q.comets.Sync(nodes)
+		}
+	}()
+	return q
+}
+
+func (q *DirectQueue) MPush(server int32, subkeys []string, msg []byte, operation, priority int32, msgId int64) (err error) {
+	var (
+		c     *rpc.Client
+		i, j  int
+		reply = &cproto.MPushMsgReply{}
+	)
+	if c, err = q.comets.Get(server); err != nil {
+		return
+	}
+	for i = 0; i < len(subkeys); i = j {
+		if j = i + directPushMaxBlock; j > len(subkeys) {
+			j = len(subkeys)
+		}
+		arg := &cproto.MPushMsgArg{Keys: subkeys[i:j], Operation: comets.Operation(operation), Msg: msg, Priority: priority, MsgId: msgId}
+		if err = c.Call(comets.ServiceMPushMsg, arg, reply); err != nil {
+			log.Error("c.Call(\"%s\", %d) error(%v)", comets.ServiceMPushMsg, server, err)
+			return
+		}
+	}
+	return
+}
+
+func (q *DirectQueue) MPushs(server int32, subkeys []string, msgs [][]byte, msgIds []int64, operation int32) (err error) {
+	var (
+		c    *rpc.Client
+		i, j int
+		ops  []int32
+	)
+	if c, err = q.comets.Get(server); err != nil {
+		return
+	}
+	if len(subkeys) == 1 {
+		arg := &cproto.PushMsgsArg{Key: subkeys[0], Vers: make([]int32, len(msgs)), Operations: make([]int32, len(msgs)), Msgs: msgs, MsgIds: msgIds}
+		for i = 0; i < len(msgs); i++ {
+			arg.Operations[i] = comets.Operation(operation)
+		}
+		if err = c.Call(comets.ServicePushMsgs, arg, &cproto.PushMsgsReply{}); err != nil {
+			log.Error("c.Call(\"%s\", %d) error(%v)", comets.ServicePushMsgs, server, err)
+		}
+		return
+	}
+	if len(subkeys) != len(msgs) || len(msgs) != len(msgIds) {
+		return ErrPushMsgs
+	}
+	ops = make([]int32, len(subkeys))
+	for i = 0; i < len(ops); i++ {
+		ops[i] = comets.Operation(operation)
+	}
+	for i = 0; i < len(subkeys); i = j {
+		if j = i + directPushMaxBlock; j > len(subkeys) {
+			j = len(subkeys)
+		}
+		arg := &cproto.MPushMsgsArg{Keys: subkeys[i:j], Vers: make([]int32, j-i), Operations: ops[i:j], Msgs: msgs[i:j], MsgIds: msgIds[i:j]}
+		if err = c.Call(comets.ServiceMPushMsgs, arg, &cproto.MPushMsgsReply{}); err != nil {
+			log.Error("c.Call(\"%s\", %d) error(%v)", comets.ServiceMPushMsgs, server, err)
+			return
+		}
+	}
+	return
+}
+
+// Broadcast push the message to all the comets in parallel, error if any
+// comet failed.
+func (q *DirectQueue) Broadcast(appId int32, msg []byte) (err error) {
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		arg  = &cproto.BoardcastArg{Ver: 0, Operation: define.OP_SEND_SMS_REPLY, Msg: msg, AppId: appId}
+	)
+	for _, serverId := range q.comets.Servers() {
+		c, e := q.comets.Get(serverId)
+		if e != nil {
+			log.Error("broadcast comet: %d error(%v)", serverId, e)
+			lock.Lock()
+			err = e
+			lock.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(serverId int32, c *rpc.Client) {
+			defer wg.Done()
+			if e := c.Call(comets.ServiceBroadcast, arg, nil); e != nil {
+				log.Error("c.Call(\"%s\", %d) error(%v)", comets.ServiceBroadcast, serverId, e)
+				lock.Lock()
+				err = e
+				lock.Unlock()
+			}
+		}(serverId, c)
+	}
+	wg.Wait()
+	return
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	cproto "github.com/Terry-Mao/goim/proto/comet"
+	rpc "github.com/Terry-Mao/protorpc"
+	"net"
+	"sync/atomic"
+	"testing"
+)
+
+// testPushRPC count the broadcasts of the comets.
+type testPushRPC struct {
+	broadcasts int32
+}
+
+func (p *testPushRPC) Broadcast(arg *cproto.BoardcastArg, reply *cproto.NoReply) error {
+	atomic.AddInt32(&p.broadcasts, 1)
+	return nil
+}
+
+func TestDirectQueueBroadcast(t *testing.T) {
+	var (
+		p     = &testPushRPC{}
+		addrs = make(map[int32]string)
+	)
+	if err := rpc.RegisterName("PushRPC", p); err != nil {
+		t.Fatal(err)
+	}
+	for serverId := int32(1); serverId <= 2; serverId++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		go rpc.Accept(l)
+		addrs[serverId] = "tcp@" + l.Addr().String()
+	}
+	q, err := NewDirectQueue(addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if servers := q.comets.Servers(); len(servers) != 2 {
+		t.Fatalf("servers: %v", servers)
+	}
+	if err = q.Broadcast(1, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&p.broadcasts); n != 2 {
+		t.Fatalf("broadcasts: %d", n)
+	}
+}
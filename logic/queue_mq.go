@@ -0,0 +1,68 @@
+package main
+
+import (
+	log "code.google.com/p/log4go"
+	"github.com/Terry-Mao/goim/define"
+	"github.com/Terry-Mao/goim/libs/mq"
+	lproto "github.com/Terry-Mao/goim/proto/logic"
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	KafkaPushsTopic = "KafkaPushsTopic"
+)
+
+// MQQueue produce the pushes to the message queue, the job consume and push
+// them to the comets.
+type MQQueue struct {
+	producer mq.Producer
+	topic    string
+}
+
+func NewMQQueue(producer mq.Producer, topic string) *MQQueue {
+	return &MQQueue{producer: producer, topic: topic}
+}
+
+// NewKafkaQueue produce the pushes to the kafka topic of the job.
+func NewKafkaQueue(kafkaAddrs []string) (*MQQueue, error) {
+	producer, err := mq.NewKafkaProducer(kafkaAddrs)
+	if err != nil {
+		return nil, err
+	}
+	return NewMQQueue(producer, KafkaPushsTopic), nil
+}
+
+func (q *MQQueue) send(key string, v proto.Message) (err error) {
+	var vBytes []byte
+	if vBytes, err = proto.Marshal(v); err != nil {
+		return
+	}
+	return q.producer.Send(q.topic, key, vBytes)
+}
+
+func (q *MQQueue) MPush(server int32, subkeys []string, msg []byte, operation, priority int32, msgId int64) (err error) {
+	v := &lproto.PushsMsg{Server: server, SubKeys: subkeys, Msg: msg, Operation: operation, Priority: priority, MsgId: msgId}
+	if err = q.send(define.KAFKA_MESSAGE_MULTI, v); err != nil {
+		return
+	}
+	log.Debug("produce msg ok, msg:%s", msg)
+	return
+}
+
+func (q *MQQueue) MPushs(server int32, subkeys []string, msgs [][]byte, msgIds []int64, operation int32) (err error) {
+	v := &lproto.MPushsMsg{Server: server, SubKeys: subkeys, Msgs: msgs, Operation: operation, MsgIds: msgIds}
+	if err = q.send(define.KAFKA_MESSAGE_MULTI_MSGS, v); err != nil {
+		return
+	}
+	log.Debug("produce msgs ok, server:%d subkeys:%d", server, len(subkeys))
+	return
+}
+
+func (q *MQQueue) Broadcast(appId int32, msg []byte) (err error) {
+	v := &lproto.BroadcastMsg{AppId: appId, Msg: msg}
+	if err = q.send(define.KAFKA_MESSAGE_BROADCAST_APP, v); err != nil {
+		return
+	}
+	log.Debug("produce msg ok, broadcast app:%d msg:%s", appId, msg)
+	return
+}
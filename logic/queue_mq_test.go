@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/Terry-Mao/goim/define"
+	"github.com/Terry-Mao/goim/libs/mq"
+	lproto "github.com/Terry-Mao/goim/proto/logic"
+	"github.com/gogo/protobuf/proto"
+	"testing"
+)
+
+func TestMQQueue(t *testing.T) {
+	var (
+		m       = lproto.PushsMsg{}
+		b       = lproto.BroadcastMsg{}
+		ms      = lproto.MPushsMsg{}
+		mem     = mq.NewMemory(2)
+		q       = NewMQQueue(mem, KafkaPushsTopic)
+		c, _    = mem.Consumer(KafkaPushsTopic)
+		subkeys = []string{"1_1", "2_1"}
+	)
+	if err := q.MPush(1, subkeys, []byte("{}"), 5, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Broadcast(2, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-c.Messages()
+	if msg.Key != define.KAFKA_MESSAGE_MULTI {
+		t.Fatalf("key: %s", msg.Key)
+	}
+	if err := proto.Unmarshal(msg.Value, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Server != 1 || len(m.SubKeys) != 2 || m.Operation != 5 || m.Priority != 1 || m.MsgId != 10 {
+		t.Fatalf("msg: %v", m)
+	}
+	msg = <-c.Messages()
+	if msg.Key != define.KAFKA_MESSAGE_BROADCAST_APP {
+		t.Fatalf("key: %s", msg.Key)
+	}
+	if err := proto.Unmarshal(msg.Value, &b); err != nil || b.AppId != 2 {
+		t.Fatalf("broadcast: %v error(%v)", b, err)
+	}
+	if err := q.MPushs(1, subkeys, [][]byte{[]byte("{}"), []byte("[]")}, []int64{11, 12}, 5); err != nil {
+		t.Fatal(err)
+	}
+	msg = <-c.Messages()
+	if msg.Key != define.KAFKA_MESSAGE_MULTI_MSGS {
+		t.Fatalf("key: %s", msg.Key)
+	}
+	if err := proto.Unmarshal(msg.Value, &ms); err != nil {
+		t.Fatal(err)
+	}
+	if len(ms.Msgs) != 2 || len(ms.MsgIds) != 2 || ms.MsgIds[0] != 11 || ms.MsgIds[1] != 12 {
+		t.Fatalf("msgs: %v", ms)
+	}
+}
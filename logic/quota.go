@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	appQuota = NewAppQuota()
+)
+
+type quotaBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// AppQuota limit the push requests per second of the apps by Conf.AppQuota,
+// the app without quota is unlimited.
+type AppQuota struct {
+	lock    sync.Mutex
+	buckets map[int32]*quotaBucket
+}
+
+func NewAppQuota() *AppQuota {
+	return &AppQuota{buckets: make(map[int32]*quotaBucket)}
+}
+
+// Allow take a token of the app, the burst is the quota of one second.
+func (q *AppQuota) Allow(quotas map[int32]int, appId int32) (ok bool) {
+	var (
+		b     *quotaBucket
+		quota = quotas[appId]
+		now   = time.Now()
+	)
+	if quota <= 0 {
+		return true
+	}
+	q.lock.Lock()
+	if b, ok = q.buckets[appId]; !ok {
+		b = &quotaBucket{tokens: float64(quota), last: now}
+		q.buckets[appId] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * float64(quota)
+	if b.tokens > float64(quota) {
+		b.tokens = float64(quota)
+	}
+	b.last = now
+	if ok = b.tokens >= 1; ok {
+		b.tokens--
+	}
+	q.lock.Unlock()
+	return
+}
@@ -2,5 +2,8 @@ package main
 
 const (
 	OK          = 1
+	QuotaErr    = 65532
+	NotFoundErr = 65533
+	ParamErr    = 65534
 	InternalErr = 65535
 )
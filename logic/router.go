@@ -6,7 +6,8 @@ import (
 	inet "github.com/Terry-Mao/goim/libs/net"
 	rproto "github.com/Terry-Mao/goim/proto/router"
 	rpc "github.com/Terry-Mao/protorpc"
-	"strconv"
+	"sync"
+	"time"
 )
 
 var (
@@ -18,10 +19,31 @@ const (
 	routerService           = "RouterRPC"
 	routerServiceConnect    = "RouterRPC.Connect"
 	routerServiceDisconnect = "RouterRPC.Disconnect"
+	routerServiceGet        = "RouterRPC.Get"
 	routerServiceMGet       = "RouterRPC.MGet"
 	routerServiceGetAll     = "RouterRPC.GetAll"
+	routerServiceCount      = "RouterRPC.Count"
+	routerServiceDelServer  = "RouterRPC.DelServer"
+	routerServiceRenew      = "RouterRPC.Renew"
 )
 
+// routerCall call the router rpc, ErrRouterTimeout if no reply in
+// Conf.RouterRPCTimeout, the timeout call is left to the rpc client.
+func routerCall(client *rpc.Client, method string, arg, reply interface{}) (err error) {
+	if Conf.RouterRPCTimeout <= 0 {
+		return client.Call(method, arg, reply)
+	}
+	timer := time.NewTimer(Conf.RouterRPCTimeout)
+	select {
+	case call := <-client.Go(method, arg, reply, make(chan *rpc.Call, 1)).Done:
+		err = call.Error
+	case <-timer.C:
+		err = ErrRouterTimeout
+	}
+	timer.Stop()
+	return
+}
+
 func InitRouter() (err error) {
 	var (
 		network, addr string
@@ -62,22 +84,22 @@ func getRouterByServer(server string) (*rpc.Client, error) {
 	}
 }
 
-func getRouterByUID(userID int64) (*rpc.Client, error) {
-	return getRouterByServer(routerRing.Hash(strconv.FormatInt(userID, 10)))
+func getRouterByUID(appId int32, userID int64) (*rpc.Client, error) {
+	return getRouterByServer(getRouterNode(appId, userID))
 }
 
-func getRouterNode(userID int64) string {
-	return routerRing.Hash(strconv.FormatInt(userID, 10))
+func getRouterNode(appId int32, userID int64) string {
+	return routerRing.Hash(userKey(appId, userID))
 }
 
-func connect(userID int64, server int32) (seq int32, err error) {
+func connect(appId int32, userID int64, server int32, device string) (seq int32, err error) {
 	var client *rpc.Client
-	if client, err = getRouterByUID(userID); err != nil {
+	if client, err = getRouterByUID(appId, userID); err != nil {
 		return
 	}
-	arg := &rproto.ConnArg{UserId: userID, Server: server}
+	arg := &rproto.ConnArg{AppId: appId, UserId: userID, Server: server, Device: device}
 	reply := &rproto.ConnReply{}
-	if err = client.Call(routerServiceConnect, arg, reply); err != nil {
+	if err = routerCall(client, routerServiceConnect, arg, reply); err != nil {
 		log.Error("c.Call(\"%s\",\"%v\") error(%s)", routerServiceConnect, arg, err)
 	} else {
 		seq = reply.Seq
@@ -85,14 +107,14 @@ func connect(userID int64, server int32) (seq int32, err error) {
 	return
 }
 
-func disconnect(userID int64, seq int32) (has bool, err error) {
+func disconnect(appId int32, userID int64, seq int32) (has bool, err error) {
 	var client *rpc.Client
-	if client, err = getRouterByUID(userID); err != nil {
+	if client, err = getRouterByUID(appId, userID); err != nil {
 		return
 	}
-	arg := &rproto.DisconnArg{UserId: userID, Seq: seq}
+	arg := &rproto.DisconnArg{AppId: appId, UserId: userID, Seq: seq}
 	reply := &rproto.DisconnReply{}
-	if err = client.Call(routerServiceDisconnect, arg, reply); err != nil {
+	if err = routerCall(client, routerServiceDisconnect, arg, reply); err != nil {
 		log.Error("c.Call(\"%s\",\"%v\") error(%s)", routerServiceDisconnect, *arg, err)
 	} else {
 		has = reply.Has
@@ -100,61 +122,250 @@ func disconnect(userID int64, seq int32) (has bool, err error) {
 	return
 }
 
-func getSubkeys(serverId string, userIds []int64) (reply *rproto.MGetReply, err error) {
+// getSessions get the sessions of the user of the app.
+func getSessions(appId int32, userID int64) (reply *rproto.GetReply, err error) {
+	var client *rpc.Client
+	if client, err = getRouterByUID(appId, userID); err != nil {
+		return
+	}
+	arg := &rproto.GetArg{AppId: appId, UserId: userID}
+	reply = &rproto.GetReply{}
+	if err = routerCall(client, routerServiceGet, arg, reply); err != nil {
+		log.Error("client.Call(\"%s\",\"%v\") error(%s)", routerServiceGet, arg, err)
+	}
+	return
+}
+
+func getSubkeys(serverId string, appId int32, userIds []int64) (reply *rproto.MGetReply, err error) {
 	var client *rpc.Client
 	if client, err = getRouterByServer(serverId); err != nil {
 		return
 	}
-	arg := &rproto.MGetArg{UserIds: userIds}
+	arg := &rproto.MGetArg{AppId: appId, UserIds: userIds}
 	reply = &rproto.MGetReply{}
-	if err = client.Call(routerServiceMGet, arg, reply); err != nil {
+	if err = routerCall(client, routerServiceMGet, arg, reply); err != nil {
 		log.Error("client.Call(\"%s\",\"%v\") error(%s)", routerServiceMGet, arg, err)
 	}
 	return
 }
 
-func divideToRouter(userIds []int64) (divide map[int32][]string, err error) {
+// divideToNode group the users by the router node of the ketama ring.
+func divideToNode(appId int32, userIds []int64) map[string][]int64 {
 	var (
-		i, j         int
-		node, subkey string
-		subkeys      []string
-		reply        *rproto.MGetReply
-		server       int32
-		session      *rproto.GetReply
-		uid          int64
-		ids          []int64
-		ok           bool
-		m            = make(map[string][]int64)
+		node string
+		m    = make(map[string][]int64)
 	)
-	divide = make(map[int32][]string) //map[comet.serverId][]subkey
-	for i = 0; i < len(userIds); i++ {
-		node = getRouterNode(userIds[i])
-		if ids, ok = m[node]; !ok {
-			ids = []int64{userIds[i]}
-		} else {
-			ids = append(ids, userIds[i])
-		}
-		m[node] = ids
+	for i := 0; i < len(userIds); i++ {
+		node = getRouterNode(appId, userIds[i])
+		m[node] = append(m[node], userIds[i])
 	}
-	// TODO muti-routine get
-	for node, ids = range m {
-		if reply, err = getSubkeys(node, ids); err != nil {
-			log.Error("getSubkeys(\"%s\") error(%s)", node, err)
-			return
+	return m
+}
+
+// mgetSessions get the sessions of the users of the app, the router nodes
+// are called in parallel, the offline users are not returned. The users of
+// the failed router nodes are returned by failed, err is not nil only if
+// all the nodes failed.
+func mgetSessions(appId int32, userIds []int64) (sessions map[int64]*rproto.GetReply, failed []int64, err error) {
+	var (
+		wg    sync.WaitGroup
+		lock  sync.Mutex
+		nodes = divideToNode(appId, userIds)
+		fails int
+	)
+	sessions = make(map[int64]*rproto.GetReply, len(userIds))
+	for node, ids := range nodes {
+		wg.Add(1)
+		go func(node string, ids []int64) {
+			defer wg.Done()
+			reply, e := getSubkeys(node, appId, ids)
+			lock.Lock()
+			defer lock.Unlock()
+			if e != nil {
+				log.Error("getSubkeys(\"%s\") error(%s)", node, e)
+				failed = append(failed, ids...)
+				fails++
+				return
+			}
+			for i := 0; i < len(reply.UserIds); i++ {
+				if len(reply.Sessions[i].Seqs) > 0 {
+					sessions[reply.UserIds[i]] = reply.Sessions[i]
+				}
+			}
+		}(node, ids)
+	}
+	wg.Wait()
+	if fails > 0 && fails == len(nodes) {
+		err = ErrRouter
+	}
+	return
+}
+
+// countOnline count the online users and sessions of the app, all count
+// the users of all the apps, the router nodes are called in parallel. The
+// counts of the failed router nodes are missed and the nodes returned by
+// failed, err is not nil only if all the nodes failed.
+func countOnline(appId int32, all bool) (users, sessions int32, failed []string, err error) {
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		routers = getRouters()
+		arg     = &rproto.CountArg{AppId: appId, All: all}
+	)
+	for node := range routers {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			var (
+				e      error
+				client *rpc.Client
+				reply  = &rproto.CountReply{}
+			)
+			if client, e = getRouterByServer(node); e == nil {
+				if e = routerCall(client, routerServiceCount, arg, reply); e != nil {
+					log.Error("client.Call(\"%s\",\"%v\") error(%s)", routerServiceCount, arg, e)
+				}
+			}
+			lock.Lock()
+			if e != nil {
+				failed = append(failed, node)
+			} else {
+				users += reply.Users
+				sessions += reply.Sessions
+			}
+			lock.Unlock()
+		}(node)
+	}
+	wg.Wait()
+	if len(failed) > 0 && len(failed) == len(routers) {
+		err = ErrRouter
+	}
+	return
+}
+
+// delServer delete the sessions of the comet from all the routers in
+// parallel, err if any router failed.
+func delServer(server int32) (count int32, err error) {
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		arg  = &rproto.DelServerArg{Server: server}
+	)
+	for node := range getRouters() {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			var (
+				e      error
+				client *rpc.Client
+				reply  = &rproto.DelServerReply{}
+			)
+			if client, e = getRouterByServer(node); e == nil {
+				if e = routerCall(client, routerServiceDelServer, arg, reply); e != nil {
+					log.Error("client.Call(\"%s\",\"%v\") error(%s)", routerServiceDelServer, arg, e)
+				}
+			}
+			lock.Lock()
+			if e != nil {
+				err = e
+			} else {
+				count += reply.Count
+			}
+			lock.Unlock()
+		}(node)
+	}
+	wg.Wait()
+	return
+}
+
+// renew extend the leases of the sub keys of the comet on the routers in
+// parallel, the lost sub keys are expired or purged, the invalid keys are
+// lost too. The sub keys of the failed router nodes are not lost, err is not
+// nil only if all the router nodes failed.
+func renew(server int32, keys []string) (lost []string, err error) {
+	var (
+		i, fails int
+		appId    int32
+		uid      int64
+		seq      int32
+		node     string
+		e        error
+		arg      *rproto.RenewArg
+		ok       bool
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		args     = make(map[string]*rproto.RenewArg)
+		idxs     = make(map[string][]int)
+	)
+	for i = 0; i < len(keys); i++ {
+		if appId, uid, seq, e = decode(keys[i]); e != nil {
+			log.Error("decode(\"%s\") error(%s)", keys[i], e)
+			lost = append(lost, keys[i])
+			continue
 		}
-		for j = 0; j < len(reply.UserIds); j++ {
-			session = reply.Sessions[j]
-			uid = reply.UserIds[j]
-			for i = 0; i < len(session.Seqs); i++ {
-				subkey = encode(uid, session.Seqs[i])
-				server = session.Servers[i]
-				if subkeys, ok = divide[server]; !ok {
-					subkeys = []string{subkey}
-				} else {
-					subkeys = append(subkeys, subkey)
+		node = getRouterNode(appId, uid)
+		if arg, ok = args[node]; !ok {
+			arg = &rproto.RenewArg{Server: server}
+			args[node] = arg
+		}
+		arg.AppIds = append(arg.AppIds, appId)
+		arg.UserIds = append(arg.UserIds, uid)
+		arg.Seqs = append(arg.Seqs, seq)
+		idxs[node] = append(idxs[node], i)
+	}
+	for node, arg = range args {
+		wg.Add(1)
+		go func(node string, arg *rproto.RenewArg) {
+			defer wg.Done()
+			var (
+				e      error
+				client *rpc.Client
+				reply  = &rproto.RenewReply{}
+			)
+			if client, e = getRouterByServer(node); e == nil {
+				if e = routerCall(client, routerServiceRenew, arg, reply); e != nil {
+					log.Error("client.Call(\"%s\",\"%v\") error(%s)", routerServiceRenew, arg.Server, e)
+				}
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if e != nil {
+				fails++
+				return
+			}
+			for _, idx := range reply.Lost {
+				if int(idx) < len(idxs[node]) {
+					lost = append(lost, keys[idxs[node][idx]])
 				}
-				divide[server] = subkeys
 			}
+		}(node, arg)
+	}
+	wg.Wait()
+	if fails > 0 && fails == len(args) {
+		err = ErrRouter
+	}
+	return
+}
+
+// divideToRouter get the subkeys of the users of the app, group by comet.
+// The users of the failed router nodes are returned by failed, err is not
+// nil only if all the router nodes failed.
+func divideToRouter(appId int32, userIds []int64) (divide map[int32][]string, failed []int64, err error) {
+	var (
+		i        int
+		uid      int64
+		server   int32
+		session  *rproto.GetReply
+		sessions map[int64]*rproto.GetReply
+	)
+	if sessions, failed, err = mgetSessions(appId, userIds); err != nil {
+		return
+	}
+	divide = make(map[int32][]string) //map[comet.serverId][]subkey
+	for uid, session = range sessions {
+		for i = 0; i < len(session.Seqs); i++ {
+			server = session.Servers[i]
+			divide[server] = append(divide[server], encode(appId, uid, session.Seqs[i]))
 		}
 	}
 	return
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/Terry-Mao/goim/libs/hash/ketama"
+	rproto "github.com/Terry-Mao/goim/proto/router"
+	rpc "github.com/Terry-Mao/protorpc"
+	"net"
+	"testing"
+	"time"
+)
+
+// testRouterRPC reply every user a session of seq 1 on comet 1, sleep for
+// the app 1.
+type testRouterRPC struct {
+}
+
+func (r *testRouterRPC) MGet(arg *rproto.MGetArg, reply *rproto.MGetReply) error {
+	if arg.AppId == 1 {
+		time.Sleep(time.Second)
+	}
+	for _, uid := range arg.UserIds {
+		reply.UserIds = append(reply.UserIds, uid)
+		reply.Sessions = append(reply.Sessions, &rproto.GetReply{Seqs: []int32{1}, Servers: []int32{1}})
+	}
+	return nil
+}
+
+// Renew lost the sub keys not seq 1.
+func (r *testRouterRPC) Renew(arg *rproto.RenewArg, reply *rproto.RenewReply) error {
+	for i, seq := range arg.Seqs {
+		if seq != 1 {
+			reply.Lost = append(reply.Lost, int32(i))
+		}
+	}
+	return nil
+}
+
+// Count count one user with two sessions.
+func (r *testRouterRPC) Count(arg *rproto.CountArg, reply *rproto.CountReply) error {
+	reply.Users = 1
+	reply.Sessions = 2
+	return nil
+}
+
+func TestDivideToRouter(t *testing.T) {
+	var (
+		uids [2]int64
+		none *rpc.Client
+	)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err = rpc.RegisterName(routerService, &testRouterRPC{}); err != nil {
+		t.Fatal(err)
+	}
+	go rpc.Accept(l)
+	c, err := rpc.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Conf = NewConfig()
+	Conf.RouterRPCTimeout = 100 * time.Millisecond
+	routerServiceMap = map[string]**rpc.Client{"1": &c, "2": &none}
+	routerRing = ketama.NewRing(ketama.Base)
+	routerRing.AddNode("1", 1)
+	routerRing.AddNode("2", 1)
+	routerRing.Bake()
+	// uids[0] on the router 1, uids[1] on the unavailable router 2
+	for uid := int64(1); uids[0] == 0 || uids[1] == 0; uid++ {
+		if getRouterNode(0, uid) == "1" {
+			uids[0] = uid
+		} else {
+			uids[1] = uid
+		}
+	}
+	divide, failed, err := divideToRouter(0, uids[:])
+	if err != nil || len(failed) != 1 || failed[0] != uids[1] {
+		t.Fatalf("divideToRouter() failed: %v error(%v)", failed, err)
+	}
+	if len(divide[1]) != 1 || divide[1][0] != encode(0, uids[0], 1) {
+		t.Fatalf("divide: %v", divide)
+	}
+	if _, _, err = divideToRouter(0, uids[1:]); err != ErrRouter {
+		t.Fatalf("divideToRouter() error(%v)", err)
+	}
+	if _, err = getSubkeys("1", 1, uids[:1]); err != ErrRouterTimeout {
+		t.Fatalf("getSubkeys() error(%v)", err)
+	}
+	// the keys of the unavailable router are not lost
+	keys := []string{encode(0, uids[0], 1), encode(0, uids[0], 2), "bad", encode(0, uids[1], 1)}
+	lost, err := renew(1, keys)
+	if err != nil || len(lost) != 2 || lost[0] != "bad" || lost[1] != keys[1] {
+		t.Fatalf("renew() lost: %v error(%v)", lost, err)
+	}
+	if _, err = renew(1, keys[3:]); err != ErrRouter {
+		t.Fatalf("renew() error(%v)", err)
+	}
+	// the count of the unavailable router is missed
+	users, sessions, nodes, err := countOnline(0, true)
+	if err != nil || users != 1 || sessions != 2 || len(nodes) != 1 || nodes[0] != "2" {
+		t.Fatalf("countOnline() users: %d, sessions: %d, failed: %v, error(%v)", users, sessions, nodes, err)
+	}
+	routerServiceMap = map[string]**rpc.Client{"2": &none}
+	if _, _, _, err = countOnline(0, true); err != ErrRouter {
+		t.Fatalf("countOnline() error(%v)", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	rpc "github.com/Terry-Mao/protorpc"
 
 	"net"
+	"time"
 )
 
 func InitRPC(auther Auther) (err error) {
@@ -55,12 +56,37 @@ func (r *RPC) Connect(args *lproto.ConnArg, rep *lproto.ConnReply) (err error) {
 		return
 	}
 	var (
-		uid = r.auther.Auth(args.Token)
+		id  *Identity
 		seq int32
 	)
-	if seq, err = connect(uid, args.Server); err == nil {
-		rep.Key = encode(uid, seq)
+	if id, err = r.auther.Auth(args.Token); err != nil {
+		log.Error("auther.Auth(token: %s) error(%v)", tokenDigest(args.Token), err)
+		return
+	}
+	if seq, err = connect(id.AppId, id.UserId, args.Server, id.Device); err == nil {
+		rep.Key = encode(id.AppId, id.UserId, seq)
+		rep.AppId = id.AppId
+	}
+	return
+}
+
+// Online replay the offline messages to the key, the comet call it after the
+// key registered, the messages pushed before are dropped by the comet.
+func (r *RPC) Online(args *lproto.OnlineArg, rep *lproto.OnlineReply) (err error) {
+	if args == nil {
+		err = ErrOnlineArgs
+		log.Error("Online() error(%v)", err)
+		return
+	}
+	var (
+		appId int32
+		uid   int64
+	)
+	if appId, uid, _, err = decode(args.Key); err != nil {
+		log.Error("decode(\"%s\") error(%s)", args.Key, err)
+		return
 	}
+	replayOffline(appId, uid, args.Server, args.Key)
 	return
 }
 
@@ -72,13 +98,67 @@ func (r *RPC) Disconnect(args *lproto.DisconnArg, rep *lproto.DisconnReply) (err
 		return
 	}
 	var (
-		uid int64
-		seq int32
+		appId int32
+		uid   int64
+		seq   int32
+	)
+	if appId, uid, seq, err = decode(args.Key); err != nil {
+		log.Error("decode(\"%s\") error(%s)", args.Key, err)
+		return
+	}
+	rep.Has, err = disconnect(appId, uid, seq)
+	return
+}
+
+// Heartbeat renew the comet, the sessions of the comet restarted or timeout
+// are purged from the routers, the comet must close its connections for the
+// clients to reconnect if purged.
+func (r *RPC) Heartbeat(args *lproto.HeartbeatArg, rep *lproto.HeartbeatReply) (err error) {
+	if args == nil {
+		err = ErrHeartbeatArgs
+		log.Error("Heartbeat() error(%v)", err)
+		return
+	}
+	if cometLiveness.Heartbeat(args.Server, args.StartTime, args.First, time.Now()) {
+		log.Warn("comet server: %d restarted or timeout", args.Server)
+		purgeServer(args.Server)
+		rep.Purged = true
+	}
+	return
+}
+
+// Renew extend the leases of the sessions of the live connections of the
+// comet, the comet must kick the clients of the lost keys for them to
+// reconnect.
+func (r *RPC) Renew(args *lproto.RenewArg, rep *lproto.RenewReply) (err error) {
+	if args == nil {
+		err = ErrRenewArgs
+		log.Error("Renew() error(%v)", err)
+		return
+	}
+	if rep.Lost, err = renew(args.Server, args.Keys); err != nil {
+		log.Error("renew(%d) error(%v)", args.Server, err)
+	}
+	return
+}
+
+// Ack record the message acked by the user of the key
+func (r *RPC) Ack(args *lproto.AckArg, rep *lproto.AckReply) (err error) {
+	if args == nil {
+		err = ErrAckArgs
+		log.Error("Ack() error(%v)", err)
+		return
+	}
+	var (
+		appId int32
+		uid   int64
 	)
-	if uid, seq, err = decode(args.Key); err != nil {
+	if appId, uid, _, err = decode(args.Key); err != nil {
 		log.Error("decode(\"%s\") error(%s)", args.Key, err)
 		return
 	}
-	rep.Has, err = disconnect(uid, seq)
+	if !ackStore.Ack(args.MsgId, appId, uid) {
+		log.Warn("ack msg: %d app: %d user: %d not exists", args.MsgId, appId, uid)
+	}
 	return
 }
@@ -43,6 +43,8 @@ type PushMsgArg struct {
 	Ver       int32  `protobuf:"varint,2,opt,name=ver,proto3" json:"ver,omitempty"`
 	Operation int32  `protobuf:"varint,3,opt,name=operation,proto3" json:"operation,omitempty"`
 	Msg       []byte `protobuf:"bytes,4,opt,name=msg,proto3" json:"msg,omitempty"`
+	Priority  int32  `protobuf:"varint,5,opt,name=priority,proto3" json:"priority,omitempty"`
+	MsgId     int64  `protobuf:"varint,6,opt,name=msgId,proto3" json:"msgId,omitempty"`
 }
 
 func (m *PushMsgArg) Reset()         { *m = PushMsgArg{} }
@@ -54,6 +56,7 @@ type PushMsgsArg struct {
 	Vers       []int32  `protobuf:"varint,2,rep,name=vers" json:"vers,omitempty"`
 	Operations []int32  `protobuf:"varint,3,rep,name=operations" json:"operations,omitempty"`
 	Msgs       [][]byte `protobuf:"bytes,4,rep,name=msgs" json:"msgs,omitempty"`
+	MsgIds     []int64  `protobuf:"varint,5,rep,name=msgIds" json:"msgIds,omitempty"`
 }
 
 func (m *PushMsgsArg) Reset()         { *m = PushMsgsArg{} }
@@ -73,6 +76,8 @@ type MPushMsgArg struct {
 	Ver       int32    `protobuf:"varint,2,opt,name=ver,proto3" json:"ver,omitempty"`
 	Operation int32    `protobuf:"varint,3,opt,name=operation,proto3" json:"operation,omitempty"`
 	Msg       []byte   `protobuf:"bytes,4,opt,name=msg,proto3" json:"msg,omitempty"`
+	Priority  int32    `protobuf:"varint,5,opt,name=priority,proto3" json:"priority,omitempty"`
+	MsgId     int64    `protobuf:"varint,6,opt,name=msgId,proto3" json:"msgId,omitempty"`
 }
 
 func (m *MPushMsgArg) Reset()         { *m = MPushMsgArg{} }
@@ -92,6 +97,7 @@ type MPushMsgsArg struct {
 	Vers       []int32  `protobuf:"varint,2,rep,name=vers" json:"vers,omitempty"`
 	Operations []int32  `protobuf:"varint,3,rep,name=operations" json:"operations,omitempty"`
 	Msgs       [][]byte `protobuf:"bytes,4,rep,name=msgs" json:"msgs,omitempty"`
+	MsgIds     []int64  `protobuf:"varint,5,rep,name=msgIds" json:"msgIds,omitempty"`
 }
 
 func (m *MPushMsgsArg) Reset()         { *m = MPushMsgsArg{} }
@@ -110,6 +116,7 @@ type BoardcastArg struct {
 	Ver       int32  `protobuf:"varint,1,opt,name=ver,proto3" json:"ver,omitempty"`
 	Operation int32  `protobuf:"varint,2,opt,name=operation,proto3" json:"operation,omitempty"`
 	Msg       []byte `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`
+	AppId     int32  `protobuf:"varint,4,opt,name=appId,proto3" json:"appId,omitempty"`
 }
 
 func (m *BoardcastArg) Reset()         { *m = BoardcastArg{} }
@@ -252,6 +259,36 @@ func (m *PushMsgArg) Unmarshal(data []byte) error {
 			}
 			m.Msg = append([]byte{}, data[iNdEx:postIndex]...)
 			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Priority", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Priority |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.MsgId |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -373,6 +410,23 @@ func (m *PushMsgsArg) Unmarshal(data []byte) error {
 			m.Msgs = append(m.Msgs, make([]byte, postIndex-iNdEx))
 			copy(m.Msgs[len(m.Msgs)-1], data[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgIds", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.MsgIds = append(m.MsgIds, v)
 		default:
 			var sizeOfWire int
 			for {
@@ -546,6 +600,36 @@ func (m *MPushMsgArg) Unmarshal(data []byte) error {
 			}
 			m.Msg = append([]byte{}, data[iNdEx:postIndex]...)
 			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Priority", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Priority |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.MsgId |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -724,6 +808,23 @@ func (m *MPushMsgsArg) Unmarshal(data []byte) error {
 			m.Msgs = append(m.Msgs, make([]byte, postIndex-iNdEx))
 			copy(m.Msgs[len(m.Msgs)-1], data[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgIds", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.MsgIds = append(m.MsgIds, v)
 		default:
 			var sizeOfWire int
 			for {
@@ -875,6 +976,21 @@ func (m *BoardcastArg) Unmarshal(data []byte) error {
 			}
 			m.Msg = append([]byte{}, data[iNdEx:postIndex]...)
 			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.AppId |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -1007,6 +1123,12 @@ func (m *PushMsgArg) Size() (n int) {
 			n += 1 + l + sovComet(uint64(l))
 		}
 	}
+	if m.Priority != 0 {
+		n += 1 + sovComet(uint64(m.Priority))
+	}
+	if m.MsgId != 0 {
+		n += 1 + sovComet(uint64(m.MsgId))
+	}
 	return n
 }
 
@@ -1033,6 +1155,11 @@ func (m *PushMsgsArg) Size() (n int) {
 			n += 1 + l + sovComet(uint64(l))
 		}
 	}
+	if len(m.MsgIds) > 0 {
+		for _, e := range m.MsgIds {
+			n += 1 + sovComet(uint64(e))
+		}
+	}
 	return n
 }
 
@@ -1066,6 +1193,12 @@ func (m *MPushMsgArg) Size() (n int) {
 			n += 1 + l + sovComet(uint64(l))
 		}
 	}
+	if m.Priority != 0 {
+		n += 1 + sovComet(uint64(m.Priority))
+	}
+	if m.MsgId != 0 {
+		n += 1 + sovComet(uint64(m.MsgId))
+	}
 	return n
 }
 
@@ -1103,6 +1236,11 @@ func (m *MPushMsgsArg) Size() (n int) {
 			n += 1 + l + sovComet(uint64(l))
 		}
 	}
+	if len(m.MsgIds) > 0 {
+		for _, e := range m.MsgIds {
+			n += 1 + sovComet(uint64(e))
+		}
+	}
 	return n
 }
 
@@ -1130,6 +1268,9 @@ func (m *BoardcastArg) Size() (n int) {
 			n += 1 + l + sovComet(uint64(l))
 		}
 	}
+	if m.AppId != 0 {
+		n += 1 + sovComet(uint64(m.AppId))
+	}
 	return n
 }
 
@@ -1203,6 +1344,16 @@ func (m *PushMsgArg) MarshalTo(data []byte) (n int, err error) {
 			i += copy(data[i:], m.Msg)
 		}
 	}
+	if m.Priority != 0 {
+		data[i] = 0x28
+		i++
+		i = encodeVarintComet(data, i, uint64(m.Priority))
+	}
+	if m.MsgId != 0 {
+		data[i] = 0x30
+		i++
+		i = encodeVarintComet(data, i, uint64(m.MsgId))
+	}
 	return i, nil
 }
 
@@ -1249,6 +1400,13 @@ func (m *PushMsgsArg) MarshalTo(data []byte) (n int, err error) {
 			i += copy(data[i:], b)
 		}
 	}
+	if len(m.MsgIds) > 0 {
+		for _, num := range m.MsgIds {
+			data[i] = 0x28
+			i++
+			i = encodeVarintComet(data, i, uint64(num))
+		}
+	}
 	return i, nil
 }
 
@@ -1323,6 +1481,16 @@ func (m *MPushMsgArg) MarshalTo(data []byte) (n int, err error) {
 			i += copy(data[i:], m.Msg)
 		}
 	}
+	if m.Priority != 0 {
+		data[i] = 0x28
+		i++
+		i = encodeVarintComet(data, i, uint64(m.Priority))
+	}
+	if m.MsgId != 0 {
+		data[i] = 0x30
+		i++
+		i = encodeVarintComet(data, i, uint64(m.MsgId))
+	}
 	return i, nil
 }
 
@@ -1401,6 +1569,13 @@ func (m *MPushMsgsArg) MarshalTo(data []byte) (n int, err error) {
 			i += copy(data[i:], b)
 		}
 	}
+	if len(m.MsgIds) > 0 {
+		for _, num := range m.MsgIds {
+			data[i] = 0x28
+			i++
+			i = encodeVarintComet(data, i, uint64(num))
+		}
+	}
 	return i, nil
 }
 
@@ -1460,6 +1635,11 @@ func (m *BoardcastArg) MarshalTo(data []byte) (n int, err error) {
 			i += copy(data[i:], m.Msg)
 		}
 	}
+	if m.AppId != 0 {
+		data[i] = 0x20
+		i++
+		i = encodeVarintComet(data, i, uint64(m.AppId))
+	}
 	return i, nil
 }
 
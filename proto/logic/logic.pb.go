@@ -10,12 +10,22 @@
 
 	It has these top-level messages:
 		PushsMsg
+		MPushsMsg
+		BroadcastMsg
 		PingArg
 		PingReply
 		ConnArg
 		ConnReply
 		DisconnArg
 		DisconnReply
+		AckArg
+		AckReply
+		HeartbeatArg
+		HeartbeatReply
+		RenewArg
+		RenewReply
+		OnlineArg
+		OnlineReply
 */
 package proto
 
@@ -30,15 +40,39 @@ import fmt "fmt"
 var _ = proto1.Marshal
 
 type PushsMsg struct {
-	Server  int32    `protobuf:"varint,1,opt,name=server,proto3" json:"server,omitempty"`
-	SubKeys []string `protobuf:"bytes,2,rep,name=subKeys" json:"subKeys,omitempty"`
-	Msg     []byte   `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`
+	Server    int32    `protobuf:"varint,1,opt,name=server,proto3" json:"server,omitempty"`
+	SubKeys   []string `protobuf:"bytes,2,rep,name=subKeys" json:"subKeys,omitempty"`
+	Msg       []byte   `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`
+	Priority  int32    `protobuf:"varint,4,opt,name=priority,proto3" json:"priority,omitempty"`
+	MsgId     int64    `protobuf:"varint,5,opt,name=msgId,proto3" json:"msgId,omitempty"`
+	Operation int32    `protobuf:"varint,6,opt,name=operation,proto3" json:"operation,omitempty"`
 }
 
 func (m *PushsMsg) Reset()         { *m = PushsMsg{} }
 func (m *PushsMsg) String() string { return proto1.CompactTextString(m) }
 func (*PushsMsg) ProtoMessage()    {}
 
+type MPushsMsg struct {
+	Server    int32    `protobuf:"varint,1,opt,name=server,proto3" json:"server,omitempty"`
+	SubKeys   []string `protobuf:"bytes,2,rep,name=subKeys" json:"subKeys,omitempty"`
+	Msgs      [][]byte `protobuf:"bytes,3,rep,name=msgs" json:"msgs,omitempty"`
+	Operation int32    `protobuf:"varint,4,opt,name=operation,proto3" json:"operation,omitempty"`
+	MsgIds    []int64  `protobuf:"varint,5,rep,name=msgIds" json:"msgIds,omitempty"`
+}
+
+func (m *MPushsMsg) Reset()         { *m = MPushsMsg{} }
+func (m *MPushsMsg) String() string { return proto1.CompactTextString(m) }
+func (*MPushsMsg) ProtoMessage()    {}
+
+type BroadcastMsg struct {
+	AppId int32  `protobuf:"varint,1,opt,name=appId,proto3" json:"appId,omitempty"`
+	Msg   []byte `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty"`
+}
+
+func (m *BroadcastMsg) Reset()         { *m = BroadcastMsg{} }
+func (m *BroadcastMsg) String() string { return proto1.CompactTextString(m) }
+func (*BroadcastMsg) ProtoMessage()    {}
+
 type PingArg struct {
 }
 
@@ -63,7 +97,8 @@ func (m *ConnArg) String() string { return proto1.CompactTextString(m) }
 func (*ConnArg) ProtoMessage()    {}
 
 type ConnReply struct {
-	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	AppId int32  `protobuf:"varint,2,opt,name=appId,proto3" json:"appId,omitempty"`
 }
 
 func (m *ConnReply) Reset()         { *m = ConnReply{} }
@@ -86,6 +121,73 @@ func (m *DisconnReply) Reset()         { *m = DisconnReply{} }
 func (m *DisconnReply) String() string { return proto1.CompactTextString(m) }
 func (*DisconnReply) ProtoMessage()    {}
 
+type AckArg struct {
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	MsgId int64  `protobuf:"varint,2,opt,name=msgId,proto3" json:"msgId,omitempty"`
+}
+
+func (m *AckArg) Reset()         { *m = AckArg{} }
+func (m *AckArg) String() string { return proto1.CompactTextString(m) }
+func (*AckArg) ProtoMessage()    {}
+
+type AckReply struct {
+}
+
+func (m *AckReply) Reset()         { *m = AckReply{} }
+func (m *AckReply) String() string { return proto1.CompactTextString(m) }
+func (*AckReply) ProtoMessage()    {}
+
+type HeartbeatArg struct {
+	Server    int32 `protobuf:"varint,1,opt,name=server,proto3" json:"server,omitempty"`
+	StartTime int64 `protobuf:"varint,2,opt,name=startTime,proto3" json:"startTime,omitempty"`
+	First     bool  `protobuf:"varint,3,opt,name=first,proto3" json:"first,omitempty"`
+}
+
+func (m *HeartbeatArg) Reset()         { *m = HeartbeatArg{} }
+func (m *HeartbeatArg) String() string { return proto1.CompactTextString(m) }
+func (*HeartbeatArg) ProtoMessage()    {}
+
+type HeartbeatReply struct {
+	Purged bool `protobuf:"varint,1,opt,name=purged,proto3" json:"purged,omitempty"`
+}
+
+func (m *HeartbeatReply) Reset()         { *m = HeartbeatReply{} }
+func (m *HeartbeatReply) String() string { return proto1.CompactTextString(m) }
+func (*HeartbeatReply) ProtoMessage()    {}
+
+type RenewArg struct {
+	Server int32    `protobuf:"varint,1,opt,name=server,proto3" json:"server,omitempty"`
+	Keys   []string `protobuf:"bytes,2,rep,name=keys" json:"keys,omitempty"`
+}
+
+func (m *RenewArg) Reset()         { *m = RenewArg{} }
+func (m *RenewArg) String() string { return proto1.CompactTextString(m) }
+func (*RenewArg) ProtoMessage()    {}
+
+type RenewReply struct {
+	Lost []string `protobuf:"bytes,1,rep,name=lost" json:"lost,omitempty"`
+}
+
+func (m *RenewReply) Reset()         { *m = RenewReply{} }
+func (m *RenewReply) String() string { return proto1.CompactTextString(m) }
+func (*RenewReply) ProtoMessage()    {}
+
+type OnlineArg struct {
+	Server int32  `protobuf:"varint,1,opt,name=server,proto3" json:"server,omitempty"`
+	Key    string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (m *OnlineArg) Reset()         { *m = OnlineArg{} }
+func (m *OnlineArg) String() string { return proto1.CompactTextString(m) }
+func (*OnlineArg) ProtoMessage()    {}
+
+type OnlineReply struct {
+}
+
+func (m *OnlineReply) Reset()         { *m = OnlineReply{} }
+func (m *OnlineReply) String() string { return proto1.CompactTextString(m) }
+func (*OnlineReply) ProtoMessage()    {}
+
 func init() {
 }
 func (m *PushsMsg) Unmarshal(data []byte) error {
@@ -166,6 +268,51 @@ func (m *PushsMsg) Unmarshal(data []byte) error {
 			}
 			m.Msg = append([]byte{}, data[iNdEx:postIndex]...)
 			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Priority", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Priority |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.MsgId |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Operation", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Operation |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -189,7 +336,7 @@ func (m *PushsMsg) Unmarshal(data []byte) error {
 
 	return nil
 }
-func (m *PingArg) Unmarshal(data []byte) error {
+func (m *MPushsMsg) Unmarshal(data []byte) error {
 	l := len(data)
 	iNdEx := 0
 	for iNdEx < l {
@@ -206,48 +353,100 @@ func (m *PingArg) Unmarshal(data []byte) error {
 			}
 		}
 		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
 		switch fieldNum {
-		default:
-			var sizeOfWire int
-			for {
-				sizeOfWire++
-				wire >>= 7
-				if wire == 0 {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Server", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Server |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
 					break
 				}
 			}
-			iNdEx -= sizeOfWire
-			skippy, err := skipLogic(data[iNdEx:])
-			if err != nil {
-				return err
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SubKeys", wireType)
 			}
-			if (iNdEx + skippy) > l {
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + int(stringLen)
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			iNdEx += skippy
-		}
-	}
-
-	return nil
-}
-func (m *PingReply) Unmarshal(data []byte) error {
-	l := len(data)
-	iNdEx := 0
-	for iNdEx < l {
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if iNdEx >= l {
+			m.SubKeys = append(m.SubKeys, string(data[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Msgs", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := data[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+			m.Msgs = append(m.Msgs, make([]byte, postIndex-iNdEx))
+			copy(m.Msgs[len(m.Msgs)-1], data[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Operation", wireType)
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		switch fieldNum {
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Operation |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgIds", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.MsgIds = append(m.MsgIds, v)
 		default:
 			var sizeOfWire int
 			for {
@@ -271,7 +470,7 @@ func (m *PingReply) Unmarshal(data []byte) error {
 
 	return nil
 }
-func (m *ConnArg) Unmarshal(data []byte) error {
+func (m *BroadcastMsg) Unmarshal(data []byte) error {
 	l := len(data)
 	iNdEx := 0
 	for iNdEx < l {
@@ -291,42 +490,42 @@ func (m *ConnArg) Unmarshal(data []byte) error {
 		wireType := int(wire & 0x7)
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Token", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppId", wireType)
 			}
-			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if iNdEx >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				m.AppId |= (int32(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			postIndex := iNdEx + int(stringLen)
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Token = string(data[iNdEx:postIndex])
-			iNdEx = postIndex
 		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Server", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Msg", wireType)
 			}
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if iNdEx >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[iNdEx]
 				iNdEx++
-				m.Server |= (int32(b) & 0x7F) << shift
+				byteLen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Msg = append([]byte{}, data[iNdEx:postIndex]...)
+			iNdEx = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -350,7 +549,7 @@ func (m *ConnArg) Unmarshal(data []byte) error {
 
 	return nil
 }
-func (m *ConnReply) Unmarshal(data []byte) error {
+func (m *PingArg) Unmarshal(data []byte) error {
 	l := len(data)
 	iNdEx := 0
 	for iNdEx < l {
@@ -367,30 +566,7 @@ func (m *ConnReply) Unmarshal(data []byte) error {
 			}
 		}
 		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
 		switch fieldNum {
-		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := data[iNdEx]
-				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			postIndex := iNdEx + int(stringLen)
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Key = string(data[iNdEx:postIndex])
-			iNdEx = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -414,7 +590,7 @@ func (m *ConnReply) Unmarshal(data []byte) error {
 
 	return nil
 }
-func (m *DisconnArg) Unmarshal(data []byte) error {
+func (m *PingReply) Unmarshal(data []byte) error {
 	l := len(data)
 	iNdEx := 0
 	for iNdEx < l {
@@ -431,30 +607,7 @@ func (m *DisconnArg) Unmarshal(data []byte) error {
 			}
 		}
 		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
 		switch fieldNum {
-		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := data[iNdEx]
-				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			postIndex := iNdEx + int(stringLen)
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Key = string(data[iNdEx:postIndex])
-			iNdEx = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -478,7 +631,7 @@ func (m *DisconnArg) Unmarshal(data []byte) error {
 
 	return nil
 }
-func (m *DisconnReply) Unmarshal(data []byte) error {
+func (m *ConnArg) Unmarshal(data []byte) error {
 	l := len(data)
 	iNdEx := 0
 	for iNdEx < l {
@@ -498,22 +651,775 @@ func (m *DisconnReply) Unmarshal(data []byte) error {
 		wireType := int(wire & 0x7)
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Has", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Token", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Token = string(data[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Server", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Server |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *ConnReply) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = string(data[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.AppId |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *DisconnArg) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = string(data[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *DisconnReply) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Has", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Has = bool(v != 0)
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *AckArg) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = string(data[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.MsgId |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *AckReply) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		switch fieldNum {
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *HeartbeatArg) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Server", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Server |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTime", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.StartTime |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field First", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.First = bool(v != 0)
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *HeartbeatReply) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Purged", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Purged = bool(v != 0)
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *RenewArg) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Server", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Server |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Keys", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Keys = append(m.Keys, string(data[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *RenewReply) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Lost", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Lost = append(m.Lost, string(data[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *OnlineArg) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Server", wireType)
 			}
-			var v int
 			for shift := uint(0); ; shift += 7 {
 				if iNdEx >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[iNdEx]
 				iNdEx++
-				v |= (int(b) & 0x7F) << shift
+				m.Server |= (int32(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Has = bool(v != 0)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = string(data[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipLogic(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *OnlineReply) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		switch fieldNum {
 		default:
 			var sizeOfWire int
 			for {
@@ -639,6 +1545,59 @@ func (m *PushsMsg) Size() (n int) {
 			n += 1 + l + sovLogic(uint64(l))
 		}
 	}
+	if m.Priority != 0 {
+		n += 1 + sovLogic(uint64(m.Priority))
+	}
+	if m.MsgId != 0 {
+		n += 1 + sovLogic(uint64(m.MsgId))
+	}
+	if m.Operation != 0 {
+		n += 1 + sovLogic(uint64(m.Operation))
+	}
+	return n
+}
+
+func (m *MPushsMsg) Size() (n int) {
+	var l int
+	_ = l
+	if m.Server != 0 {
+		n += 1 + sovLogic(uint64(m.Server))
+	}
+	if len(m.SubKeys) > 0 {
+		for _, s := range m.SubKeys {
+			l = len(s)
+			n += 1 + l + sovLogic(uint64(l))
+		}
+	}
+	if len(m.Msgs) > 0 {
+		for _, b := range m.Msgs {
+			l = len(b)
+			n += 1 + l + sovLogic(uint64(l))
+		}
+	}
+	if m.Operation != 0 {
+		n += 1 + sovLogic(uint64(m.Operation))
+	}
+	if len(m.MsgIds) > 0 {
+		for _, e := range m.MsgIds {
+			n += 1 + sovLogic(uint64(e))
+		}
+	}
+	return n
+}
+
+func (m *BroadcastMsg) Size() (n int) {
+	var l int
+	_ = l
+	if m.AppId != 0 {
+		n += 1 + sovLogic(uint64(m.AppId))
+	}
+	if m.Msg != nil {
+		l = len(m.Msg)
+		if l > 0 {
+			n += 1 + l + sovLogic(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -674,6 +1633,9 @@ func (m *ConnReply) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovLogic(uint64(l))
 	}
+	if m.AppId != 0 {
+		n += 1 + sovLogic(uint64(m.AppId))
+	}
 	return n
 }
 
@@ -696,6 +1658,95 @@ func (m *DisconnReply) Size() (n int) {
 	return n
 }
 
+func (m *AckArg) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovLogic(uint64(l))
+	}
+	if m.MsgId != 0 {
+		n += 1 + sovLogic(uint64(m.MsgId))
+	}
+	return n
+}
+
+func (m *AckReply) Size() (n int) {
+	var l int
+	_ = l
+	return n
+}
+
+func (m *HeartbeatArg) Size() (n int) {
+	var l int
+	_ = l
+	if m.Server != 0 {
+		n += 1 + sovLogic(uint64(m.Server))
+	}
+	if m.StartTime != 0 {
+		n += 1 + sovLogic(uint64(m.StartTime))
+	}
+	if m.First {
+		n += 2
+	}
+	return n
+}
+
+func (m *HeartbeatReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.Purged {
+		n += 2
+	}
+	return n
+}
+
+func (m *RenewArg) Size() (n int) {
+	var l int
+	_ = l
+	if m.Server != 0 {
+		n += 1 + sovLogic(uint64(m.Server))
+	}
+	if len(m.Keys) > 0 {
+		for _, s := range m.Keys {
+			l = len(s)
+			n += 1 + l + sovLogic(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *RenewReply) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Lost) > 0 {
+		for _, s := range m.Lost {
+			l = len(s)
+			n += 1 + l + sovLogic(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *OnlineArg) Size() (n int) {
+	var l int
+	_ = l
+	if m.Server != 0 {
+		n += 1 + sovLogic(uint64(m.Server))
+	}
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovLogic(uint64(l))
+	}
+	return n
+}
+
+func (m *OnlineReply) Size() (n int) {
+	var l int
+	_ = l
+	return n
+}
+
 func sovLogic(x uint64) (n int) {
 	for {
 		n++
@@ -704,12 +1755,73 @@ func sovLogic(x uint64) (n int) {
 			break
 		}
 	}
-	return n
-}
-func sozLogic(x uint64) (n int) {
-	return sovLogic(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+	return n
+}
+func sozLogic(x uint64) (n int) {
+	return sovLogic(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *PushsMsg) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *PushsMsg) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Server != 0 {
+		data[i] = 0x8
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.Server))
+	}
+	if len(m.SubKeys) > 0 {
+		for _, s := range m.SubKeys {
+			data[i] = 0x12
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				data[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			data[i] = uint8(l)
+			i++
+			i += copy(data[i:], s)
+		}
+	}
+	if m.Msg != nil {
+		if len(m.Msg) > 0 {
+			data[i] = 0x1a
+			i++
+			i = encodeVarintLogic(data, i, uint64(len(m.Msg)))
+			i += copy(data[i:], m.Msg)
+		}
+	}
+	if m.Priority != 0 {
+		data[i] = 0x20
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.Priority))
+	}
+	if m.MsgId != 0 {
+		data[i] = 0x28
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.MsgId))
+	}
+	if m.Operation != 0 {
+		data[i] = 0x30
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.Operation))
+	}
+	return i, nil
 }
-func (m *PushsMsg) Marshal() (data []byte, err error) {
+
+func (m *MPushsMsg) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
 	n, err := m.MarshalTo(data)
@@ -719,7 +1831,7 @@ func (m *PushsMsg) Marshal() (data []byte, err error) {
 	return data[:n], nil
 }
 
-func (m *PushsMsg) MarshalTo(data []byte) (n int, err error) {
+func (m *MPushsMsg) MarshalTo(data []byte) (n int, err error) {
 	var i int
 	_ = i
 	var l int
@@ -744,9 +1856,52 @@ func (m *PushsMsg) MarshalTo(data []byte) (n int, err error) {
 			i += copy(data[i:], s)
 		}
 	}
+	if len(m.Msgs) > 0 {
+		for _, b := range m.Msgs {
+			data[i] = 0x1a
+			i++
+			i = encodeVarintLogic(data, i, uint64(len(b)))
+			i += copy(data[i:], b)
+		}
+	}
+	if m.Operation != 0 {
+		data[i] = 0x20
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.Operation))
+	}
+	if len(m.MsgIds) > 0 {
+		for _, num := range m.MsgIds {
+			data[i] = 0x28
+			i++
+			i = encodeVarintLogic(data, i, uint64(num))
+		}
+	}
+	return i, nil
+}
+
+func (m *BroadcastMsg) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *BroadcastMsg) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.AppId != 0 {
+		data[i] = 0x8
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.AppId))
+	}
 	if m.Msg != nil {
 		if len(m.Msg) > 0 {
-			data[i] = 0x1a
+			data[i] = 0x12
 			i++
 			i = encodeVarintLogic(data, i, uint64(len(m.Msg)))
 			i += copy(data[i:], m.Msg)
@@ -841,6 +1996,11 @@ func (m *ConnReply) MarshalTo(data []byte) (n int, err error) {
 		i = encodeVarintLogic(data, i, uint64(len(m.Key)))
 		i += copy(data[i:], m.Key)
 	}
+	if m.AppId != 0 {
+		data[i] = 0x10
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.AppId))
+	}
 	return i, nil
 }
 
@@ -896,6 +2056,237 @@ func (m *DisconnReply) MarshalTo(data []byte) (n int, err error) {
 	return i, nil
 }
 
+func (m *AckArg) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *AckArg) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Key) > 0 {
+		data[i] = 0xa
+		i++
+		i = encodeVarintLogic(data, i, uint64(len(m.Key)))
+		i += copy(data[i:], m.Key)
+	}
+	if m.MsgId != 0 {
+		data[i] = 0x10
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.MsgId))
+	}
+	return i, nil
+}
+
+func (m *AckReply) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *AckReply) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	return i, nil
+}
+
+func (m *HeartbeatArg) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *HeartbeatArg) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Server != 0 {
+		data[i] = 0x8
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.Server))
+	}
+	if m.StartTime != 0 {
+		data[i] = 0x10
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.StartTime))
+	}
+	if m.First {
+		data[i] = 0x18
+		i++
+		if m.First {
+			data[i] = 1
+		} else {
+			data[i] = 0
+		}
+		i++
+	}
+	return i, nil
+}
+
+func (m *HeartbeatReply) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *HeartbeatReply) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Purged {
+		data[i] = 0x8
+		i++
+		if m.Purged {
+			data[i] = 1
+		} else {
+			data[i] = 0
+		}
+		i++
+	}
+	return i, nil
+}
+
+func (m *RenewArg) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *RenewArg) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Server != 0 {
+		data[i] = 0x8
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.Server))
+	}
+	if len(m.Keys) > 0 {
+		for _, s := range m.Keys {
+			data[i] = 0x12
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				data[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			data[i] = uint8(l)
+			i++
+			i += copy(data[i:], s)
+		}
+	}
+	return i, nil
+}
+
+func (m *RenewReply) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *RenewReply) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Lost) > 0 {
+		for _, s := range m.Lost {
+			data[i] = 0xa
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				data[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			data[i] = uint8(l)
+			i++
+			i += copy(data[i:], s)
+		}
+	}
+	return i, nil
+}
+
+func (m *OnlineArg) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *OnlineArg) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Server != 0 {
+		data[i] = 0x8
+		i++
+		i = encodeVarintLogic(data, i, uint64(m.Server))
+	}
+	if len(m.Key) > 0 {
+		data[i] = 0x12
+		i++
+		i = encodeVarintLogic(data, i, uint64(len(m.Key)))
+		i += copy(data[i:], m.Key)
+	}
+	return i, nil
+}
+
+func (m *OnlineReply) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *OnlineReply) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	return i, nil
+}
+
 func encodeFixed64Logic(data []byte, offset int, v uint64) int {
 	data[offset] = uint8(v)
 	data[offset+1] = uint8(v >> 8)
@@ -22,6 +22,12 @@
 		MGetReply
 		GetSeqCountArg
 		GetSeqCountReply
+		CountArg
+		CountReply
+		DelServerArg
+		DelServerReply
+		RenewArg
+		RenewReply
 */
 package proto
 
@@ -50,8 +56,10 @@ func (m *NoReply) String() string { return proto1.CompactTextString(m) }
 func (*NoReply) ProtoMessage()    {}
 
 type ConnArg struct {
-	UserId int64 `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
-	Server int32 `protobuf:"varint,2,opt,name=server,proto3" json:"server,omitempty"`
+	UserId int64  `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Server int32  `protobuf:"varint,2,opt,name=server,proto3" json:"server,omitempty"`
+	AppId  int32  `protobuf:"varint,3,opt,name=appId,proto3" json:"appId,omitempty"`
+	Device string `protobuf:"bytes,4,opt,name=device,proto3" json:"device,omitempty"`
 }
 
 func (m *ConnArg) Reset()         { *m = ConnArg{} }
@@ -69,6 +77,7 @@ func (*ConnReply) ProtoMessage()    {}
 type DisconnArg struct {
 	UserId int64 `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
 	Seq    int32 `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"`
+	AppId  int32 `protobuf:"varint,3,opt,name=appId,proto3" json:"appId,omitempty"`
 }
 
 func (m *DisconnArg) Reset()         { *m = DisconnArg{} }
@@ -85,6 +94,7 @@ func (*DisconnReply) ProtoMessage()    {}
 
 type GetArg struct {
 	UserId int64 `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	AppId  int32 `protobuf:"varint,2,opt,name=appId,proto3" json:"appId,omitempty"`
 }
 
 func (m *GetArg) Reset()         { *m = GetArg{} }
@@ -92,8 +102,9 @@ func (m *GetArg) String() string { return proto1.CompactTextString(m) }
 func (*GetArg) ProtoMessage()    {}
 
 type GetReply struct {
-	Seqs    []int32 `protobuf:"varint,1,rep,name=seqs" json:"seqs,omitempty"`
-	Servers []int32 `protobuf:"varint,2,rep,name=servers" json:"servers,omitempty"`
+	Seqs    []int32  `protobuf:"varint,1,rep,name=seqs" json:"seqs,omitempty"`
+	Servers []int32  `protobuf:"varint,2,rep,name=servers" json:"servers,omitempty"`
+	Devices []string `protobuf:"bytes,3,rep,name=devices" json:"devices,omitempty"`
 }
 
 func (m *GetReply) Reset()         { *m = GetReply{} }
@@ -103,6 +114,7 @@ func (*GetReply) ProtoMessage()    {}
 type GetAllReply struct {
 	UserIds  []int64     `protobuf:"varint,1,rep,name=userIds" json:"userIds,omitempty"`
 	Sessions []*GetReply `protobuf:"bytes,2,rep,name=sessions" json:"sessions,omitempty"`
+	AppIds   []int32     `protobuf:"varint,3,rep,name=appIds" json:"appIds,omitempty"`
 }
 
 func (m *GetAllReply) Reset()         { *m = GetAllReply{} }
@@ -118,6 +130,7 @@ func (m *GetAllReply) GetSessions() []*GetReply {
 
 type MGetArg struct {
 	UserIds []int64 `protobuf:"varint,1,rep,name=userIds" json:"userIds,omitempty"`
+	AppId   int32   `protobuf:"varint,2,opt,name=appId,proto3" json:"appId,omitempty"`
 }
 
 func (m *MGetArg) Reset()         { *m = MGetArg{} }
@@ -142,6 +155,7 @@ func (m *MGetReply) GetSessions() []*GetReply {
 
 type GetSeqCountArg struct {
 	UserId int64 `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	AppId  int32 `protobuf:"varint,2,opt,name=appId,proto3" json:"appId,omitempty"`
 }
 
 func (m *GetSeqCountArg) Reset()         { *m = GetSeqCountArg{} }
@@ -156,6 +170,59 @@ func (m *GetSeqCountReply) Reset()         { *m = GetSeqCountReply{} }
 func (m *GetSeqCountReply) String() string { return proto1.CompactTextString(m) }
 func (*GetSeqCountReply) ProtoMessage()    {}
 
+type CountArg struct {
+	AppId int32 `protobuf:"varint,1,opt,name=appId,proto3" json:"appId,omitempty"`
+	All   bool  `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"`
+}
+
+func (m *CountArg) Reset()         { *m = CountArg{} }
+func (m *CountArg) String() string { return proto1.CompactTextString(m) }
+func (*CountArg) ProtoMessage()    {}
+
+type CountReply struct {
+	Users    int32 `protobuf:"varint,1,opt,name=users,proto3" json:"users,omitempty"`
+	Sessions int32 `protobuf:"varint,2,opt,name=sessions,proto3" json:"sessions,omitempty"`
+}
+
+func (m *CountReply) Reset()         { *m = CountReply{} }
+func (m *CountReply) String() string { return proto1.CompactTextString(m) }
+func (*CountReply) ProtoMessage()    {}
+
+type DelServerArg struct {
+	Server int32 `protobuf:"varint,1,opt,name=server,proto3" json:"server,omitempty"`
+}
+
+func (m *DelServerArg) Reset()         { *m = DelServerArg{} }
+func (m *DelServerArg) String() string { return proto1.CompactTextString(m) }
+func (*DelServerArg) ProtoMessage()    {}
+
+type DelServerReply struct {
+	Count int32 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (m *DelServerReply) Reset()         { *m = DelServerReply{} }
+func (m *DelServerReply) String() string { return proto1.CompactTextString(m) }
+func (*DelServerReply) ProtoMessage()    {}
+
+type RenewArg struct {
+	Server  int32   `protobuf:"varint,1,opt,name=server,proto3" json:"server,omitempty"`
+	AppIds  []int32 `protobuf:"varint,2,rep,name=appIds" json:"appIds,omitempty"`
+	UserIds []int64 `protobuf:"varint,3,rep,name=userIds" json:"userIds,omitempty"`
+	Seqs    []int32 `protobuf:"varint,4,rep,name=seqs" json:"seqs,omitempty"`
+}
+
+func (m *RenewArg) Reset()         { *m = RenewArg{} }
+func (m *RenewArg) String() string { return proto1.CompactTextString(m) }
+func (*RenewArg) ProtoMessage()    {}
+
+type RenewReply struct {
+	Lost []int32 `protobuf:"varint,1,rep,name=lost" json:"lost,omitempty"`
+}
+
+func (m *RenewReply) Reset()         { *m = RenewReply{} }
+func (m *RenewReply) String() string { return proto1.CompactTextString(m) }
+func (*RenewReply) ProtoMessage()    {}
+
 func init() {
 }
 func (m *NoArg) Unmarshal(data []byte) error {
@@ -289,6 +356,43 @@ func (m *ConnArg) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.AppId |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Device", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Device = string(data[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -418,6 +522,21 @@ func (m *DisconnArg) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.AppId |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -534,6 +653,21 @@ func (m *GetArg) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.AppId |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -610,6 +744,28 @@ func (m *GetReply) Unmarshal(data []byte) error {
 				}
 			}
 			m.Servers = append(m.Servers, v)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Devices", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := iNdEx + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Devices = append(m.Devices, string(data[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -694,6 +850,23 @@ func (m *GetAllReply) Unmarshal(data []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppIds", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.AppIds = append(m.AppIds, v)
 		default:
 			var sizeOfWire int
 			for {
@@ -753,6 +926,21 @@ func (m *MGetArg) Unmarshal(data []byte) error {
 				}
 			}
 			m.UserIds = append(m.UserIds, v)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.AppId |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -894,6 +1082,21 @@ func (m *GetSeqCountArg) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.AppId |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -974,14 +1177,14 @@ func (m *GetSeqCountReply) Unmarshal(data []byte) error {
 
 	return nil
 }
-func skipRouter(data []byte) (n int, err error) {
+func (m *CountArg) Unmarshal(data []byte) error {
 	l := len(data)
 	iNdEx := 0
 	for iNdEx < l {
 		var wire uint64
 		for shift := uint(0); ; shift += 7 {
 			if iNdEx >= l {
-				return 0, io.ErrUnexpectedEOF
+				return io.ErrUnexpectedEOF
 			}
 			b := data[iNdEx]
 			iNdEx++
@@ -990,121 +1193,558 @@ func skipRouter(data []byte) (n int, err error) {
 				break
 			}
 		}
+		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
-		switch wireType {
-		case 0:
-			for {
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppId", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
 				if iNdEx >= l {
-					return 0, io.ErrUnexpectedEOF
+					return io.ErrUnexpectedEOF
 				}
+				b := data[iNdEx]
 				iNdEx++
-				if data[iNdEx-1] < 0x80 {
+				m.AppId |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
 					break
 				}
 			}
-			return iNdEx, nil
-		case 1:
-			iNdEx += 8
-			return iNdEx, nil
 		case 2:
-			var length int
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field All", wireType)
+			}
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if iNdEx >= l {
-					return 0, io.ErrUnexpectedEOF
+					return io.ErrUnexpectedEOF
 				}
 				b := data[iNdEx]
 				iNdEx++
-				length |= (int(b) & 0x7F) << shift
+				v |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			iNdEx += length
-			return iNdEx, nil
-		case 3:
+			m.All = bool(v != 0)
+		default:
+			var sizeOfWire int
 			for {
-				var innerWire uint64
-				var start int = iNdEx
-				for shift := uint(0); ; shift += 7 {
-					if iNdEx >= l {
-						return 0, io.ErrUnexpectedEOF
-					}
-					b := data[iNdEx]
-					iNdEx++
-					innerWire |= (uint64(b) & 0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				innerWireType := int(innerWire & 0x7)
-				if innerWireType == 4 {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
 					break
 				}
-				next, err := skipRouter(data[start:])
-				if err != nil {
-					return 0, err
-				}
-				iNdEx = start + next
 			}
-			return iNdEx, nil
-		case 4:
-			return iNdEx, nil
-		case 5:
-			iNdEx += 4
-			return iNdEx, nil
-		default:
-			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+			iNdEx -= sizeOfWire
+			skippy, err := skipRouter(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
 		}
 	}
-	panic("unreachable")
-}
-func (m *NoArg) Size() (n int) {
-	var l int
-	_ = l
-	return n
-}
-
-func (m *NoReply) Size() (n int) {
-	var l int
-	_ = l
-	return n
-}
-
-func (m *ConnArg) Size() (n int) {
-	var l int
-	_ = l
-	if m.UserId != 0 {
-		n += 1 + sovRouter(uint64(m.UserId))
-	}
-	if m.Server != 0 {
-		n += 1 + sovRouter(uint64(m.Server))
-	}
-	return n
-}
-
-func (m *ConnReply) Size() (n int) {
-	var l int
-	_ = l
-	if m.Seq != 0 {
-		n += 1 + sovRouter(uint64(m.Seq))
-	}
-	return n
-}
 
-func (m *DisconnArg) Size() (n int) {
-	var l int
-	_ = l
-	if m.UserId != 0 {
-		n += 1 + sovRouter(uint64(m.UserId))
-	}
-	if m.Seq != 0 {
-		n += 1 + sovRouter(uint64(m.Seq))
-	}
-	return n
+	return nil
 }
-
-func (m *DisconnReply) Size() (n int) {
-	var l int
+func (m *CountReply) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Users", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Users |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sessions", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Sessions |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipRouter(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *DelServerArg) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Server", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Server |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipRouter(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *DelServerReply) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Count |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipRouter(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *RenewArg) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Server", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Server |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppIds", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.AppIds = append(m.AppIds, v)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UserIds", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.UserIds = append(m.UserIds, v)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seqs", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Seqs = append(m.Seqs, v)
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipRouter(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func (m *RenewReply) Unmarshal(data []byte) error {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Lost", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Lost = append(m.Lost, v)
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			iNdEx -= sizeOfWire
+			skippy, err := skipRouter(data[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	return nil
+}
+func skipRouter(data []byte) (n int, err error) {
+	l := len(data)
+	iNdEx := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := data[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for {
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if data[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+			return iNdEx, nil
+		case 1:
+			iNdEx += 8
+			return iNdEx, nil
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			iNdEx += length
+			return iNdEx, nil
+		case 3:
+			for {
+				var innerWire uint64
+				var start int = iNdEx
+				for shift := uint(0); ; shift += 7 {
+					if iNdEx >= l {
+						return 0, io.ErrUnexpectedEOF
+					}
+					b := data[iNdEx]
+					iNdEx++
+					innerWire |= (uint64(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				innerWireType := int(innerWire & 0x7)
+				if innerWireType == 4 {
+					break
+				}
+				next, err := skipRouter(data[start:])
+				if err != nil {
+					return 0, err
+				}
+				iNdEx = start + next
+			}
+			return iNdEx, nil
+		case 4:
+			return iNdEx, nil
+		case 5:
+			iNdEx += 4
+			return iNdEx, nil
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+	}
+	panic("unreachable")
+}
+func (m *NoArg) Size() (n int) {
+	var l int
+	_ = l
+	return n
+}
+
+func (m *NoReply) Size() (n int) {
+	var l int
+	_ = l
+	return n
+}
+
+func (m *ConnArg) Size() (n int) {
+	var l int
+	_ = l
+	if m.UserId != 0 {
+		n += 1 + sovRouter(uint64(m.UserId))
+	}
+	if m.Server != 0 {
+		n += 1 + sovRouter(uint64(m.Server))
+	}
+	if m.AppId != 0 {
+		n += 1 + sovRouter(uint64(m.AppId))
+	}
+	l = len(m.Device)
+	if l > 0 {
+		n += 1 + l + sovRouter(uint64(l))
+	}
+	return n
+}
+
+func (m *ConnReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.Seq != 0 {
+		n += 1 + sovRouter(uint64(m.Seq))
+	}
+	return n
+}
+
+func (m *DisconnArg) Size() (n int) {
+	var l int
+	_ = l
+	if m.UserId != 0 {
+		n += 1 + sovRouter(uint64(m.UserId))
+	}
+	if m.Seq != 0 {
+		n += 1 + sovRouter(uint64(m.Seq))
+	}
+	if m.AppId != 0 {
+		n += 1 + sovRouter(uint64(m.AppId))
+	}
+	return n
+}
+
+func (m *DisconnReply) Size() (n int) {
+	var l int
 	_ = l
 	if m.Has {
 		n += 2
@@ -1118,6 +1758,9 @@ func (m *GetArg) Size() (n int) {
 	if m.UserId != 0 {
 		n += 1 + sovRouter(uint64(m.UserId))
 	}
+	if m.AppId != 0 {
+		n += 1 + sovRouter(uint64(m.AppId))
+	}
 	return n
 }
 
@@ -1134,6 +1777,12 @@ func (m *GetReply) Size() (n int) {
 			n += 1 + sovRouter(uint64(e))
 		}
 	}
+	if len(m.Devices) > 0 {
+		for _, s := range m.Devices {
+			l = len(s)
+			n += 1 + l + sovRouter(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -1151,6 +1800,11 @@ func (m *GetAllReply) Size() (n int) {
 			n += 1 + l + sovRouter(uint64(l))
 		}
 	}
+	if len(m.AppIds) > 0 {
+		for _, e := range m.AppIds {
+			n += 1 + sovRouter(uint64(e))
+		}
+	}
 	return n
 }
 
@@ -1162,6 +1816,9 @@ func (m *MGetArg) Size() (n int) {
 			n += 1 + sovRouter(uint64(e))
 		}
 	}
+	if m.AppId != 0 {
+		n += 1 + sovRouter(uint64(m.AppId))
+	}
 	return n
 }
 
@@ -1188,6 +1845,9 @@ func (m *GetSeqCountArg) Size() (n int) {
 	if m.UserId != 0 {
 		n += 1 + sovRouter(uint64(m.UserId))
 	}
+	if m.AppId != 0 {
+		n += 1 + sovRouter(uint64(m.AppId))
+	}
 	return n
 }
 
@@ -1200,6 +1860,83 @@ func (m *GetSeqCountReply) Size() (n int) {
 	return n
 }
 
+func (m *CountArg) Size() (n int) {
+	var l int
+	_ = l
+	if m.AppId != 0 {
+		n += 1 + sovRouter(uint64(m.AppId))
+	}
+	if m.All {
+		n += 2
+	}
+	return n
+}
+
+func (m *CountReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.Users != 0 {
+		n += 1 + sovRouter(uint64(m.Users))
+	}
+	if m.Sessions != 0 {
+		n += 1 + sovRouter(uint64(m.Sessions))
+	}
+	return n
+}
+
+func (m *DelServerArg) Size() (n int) {
+	var l int
+	_ = l
+	if m.Server != 0 {
+		n += 1 + sovRouter(uint64(m.Server))
+	}
+	return n
+}
+
+func (m *DelServerReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.Count != 0 {
+		n += 1 + sovRouter(uint64(m.Count))
+	}
+	return n
+}
+
+func (m *RenewArg) Size() (n int) {
+	var l int
+	_ = l
+	if m.Server != 0 {
+		n += 1 + sovRouter(uint64(m.Server))
+	}
+	if len(m.AppIds) > 0 {
+		for _, e := range m.AppIds {
+			n += 1 + sovRouter(uint64(e))
+		}
+	}
+	if len(m.UserIds) > 0 {
+		for _, e := range m.UserIds {
+			n += 1 + sovRouter(uint64(e))
+		}
+	}
+	if len(m.Seqs) > 0 {
+		for _, e := range m.Seqs {
+			n += 1 + sovRouter(uint64(e))
+		}
+	}
+	return n
+}
+
+func (m *RenewReply) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Lost) > 0 {
+		for _, e := range m.Lost {
+			n += 1 + sovRouter(uint64(e))
+		}
+	}
+	return n
+}
+
 func sovRouter(x uint64) (n int) {
 	for {
 		n++
@@ -1274,6 +2011,17 @@ func (m *ConnArg) MarshalTo(data []byte) (n int, err error) {
 		i++
 		i = encodeVarintRouter(data, i, uint64(m.Server))
 	}
+	if m.AppId != 0 {
+		data[i] = 0x18
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.AppId))
+	}
+	if len(m.Device) > 0 {
+		data[i] = 0x22
+		i++
+		i = encodeVarintRouter(data, i, uint64(len(m.Device)))
+		i += copy(data[i:], m.Device)
+	}
 	return i, nil
 }
 
@@ -1325,6 +2073,11 @@ func (m *DisconnArg) MarshalTo(data []byte) (n int, err error) {
 		i++
 		i = encodeVarintRouter(data, i, uint64(m.Seq))
 	}
+	if m.AppId != 0 {
+		data[i] = 0x18
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.AppId))
+	}
 	return i, nil
 }
 
@@ -1376,6 +2129,11 @@ func (m *GetArg) MarshalTo(data []byte) (n int, err error) {
 		i++
 		i = encodeVarintRouter(data, i, uint64(m.UserId))
 	}
+	if m.AppId != 0 {
+		data[i] = 0x10
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.AppId))
+	}
 	return i, nil
 }
 
@@ -1408,6 +2166,21 @@ func (m *GetReply) MarshalTo(data []byte) (n int, err error) {
 			i = encodeVarintRouter(data, i, uint64(num))
 		}
 	}
+	if len(m.Devices) > 0 {
+		for _, s := range m.Devices {
+			data[i] = 0x1a
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				data[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			data[i] = uint8(l)
+			i++
+			i += copy(data[i:], s)
+		}
+	}
 	return i, nil
 }
 
@@ -1445,6 +2218,13 @@ func (m *GetAllReply) MarshalTo(data []byte) (n int, err error) {
 			i += n
 		}
 	}
+	if len(m.AppIds) > 0 {
+		for _, num := range m.AppIds {
+			data[i] = 0x18
+			i++
+			i = encodeVarintRouter(data, i, uint64(num))
+		}
+	}
 	return i, nil
 }
 
@@ -1470,6 +2250,11 @@ func (m *MGetArg) MarshalTo(data []byte) (n int, err error) {
 			i = encodeVarintRouter(data, i, uint64(num))
 		}
 	}
+	if m.AppId != 0 {
+		data[i] = 0x10
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.AppId))
+	}
 	return i, nil
 }
 
@@ -1530,6 +2315,11 @@ func (m *GetSeqCountArg) MarshalTo(data []byte) (n int, err error) {
 		i++
 		i = encodeVarintRouter(data, i, uint64(m.UserId))
 	}
+	if m.AppId != 0 {
+		data[i] = 0x10
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.AppId))
+	}
 	return i, nil
 }
 
@@ -1556,6 +2346,182 @@ func (m *GetSeqCountReply) MarshalTo(data []byte) (n int, err error) {
 	return i, nil
 }
 
+func (m *CountArg) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *CountArg) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.AppId != 0 {
+		data[i] = 0x8
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.AppId))
+	}
+	if m.All {
+		data[i] = 0x10
+		i++
+		if m.All {
+			data[i] = 1
+		} else {
+			data[i] = 0
+		}
+		i++
+	}
+	return i, nil
+}
+
+func (m *CountReply) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *CountReply) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Users != 0 {
+		data[i] = 0x8
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.Users))
+	}
+	if m.Sessions != 0 {
+		data[i] = 0x10
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.Sessions))
+	}
+	return i, nil
+}
+
+func (m *DelServerArg) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *DelServerArg) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Server != 0 {
+		data[i] = 0x8
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.Server))
+	}
+	return i, nil
+}
+
+func (m *DelServerReply) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *DelServerReply) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Count != 0 {
+		data[i] = 0x8
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.Count))
+	}
+	return i, nil
+}
+
+func (m *RenewArg) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *RenewArg) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Server != 0 {
+		data[i] = 0x8
+		i++
+		i = encodeVarintRouter(data, i, uint64(m.Server))
+	}
+	if len(m.AppIds) > 0 {
+		for _, num := range m.AppIds {
+			data[i] = 0x10
+			i++
+			i = encodeVarintRouter(data, i, uint64(num))
+		}
+	}
+	if len(m.UserIds) > 0 {
+		for _, num := range m.UserIds {
+			data[i] = 0x18
+			i++
+			i = encodeVarintRouter(data, i, uint64(num))
+		}
+	}
+	if len(m.Seqs) > 0 {
+		for _, num := range m.Seqs {
+			data[i] = 0x20
+			i++
+			i = encodeVarintRouter(data, i, uint64(num))
+		}
+	}
+	return i, nil
+}
+
+func (m *RenewReply) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *RenewReply) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Lost) > 0 {
+		for _, num := range m.Lost {
+			data[i] = 0x8
+			i++
+			i = encodeVarintRouter(data, i, uint64(num))
+		}
+	}
+	return i, nil
+}
+
 func encodeFixed64Router(data []byte, offset int, v uint64) int {
 	data[offset] = uint8(v)
 	data[offset+1] = uint8(v >> 8)
@@ -1,128 +1,414 @@
 package main
 
 import (
+	log "code.google.com/p/log4go"
 	"sync"
 	"time"
 )
 
+// userKey is the user id scoped by the app id, the user ids of the apps
+// may collide.
+type userKey struct {
+	appId  int32
+	userId int64
+}
+
 type Bucket struct {
-	bLock    sync.RWMutex       // protect the session map
-	sessions map[int64]*Session // map[user_id] ->  map[sub_id] -> server_id
+	bLock    sync.RWMutex                   // protect the session map
+	sessions map[userKey]*Session           // map[app_id, user_id] ->  map[sub_id] -> server_id
+	servers  map[int32]map[userKey]struct{} // map[server_id] -> users, the reverse index for purge
 	server   int
 	cleaner  *Cleaner
+	store    *Store // log the changes for warm restart, nil disabled
 }
 
 // NewBucket new a bucket struct. store the subkey with im channel.
 func NewBucket(session, server, cleaner int) *Bucket {
 	b := new(Bucket)
-	b.sessions = make(map[int64]*Session, session)
+	b.sessions = make(map[userKey]*Session, session)
+	b.servers = make(map[int32]map[userKey]struct{})
 	b.server = server
 	b.cleaner = NewCleaner(cleaner)
 	go b.clean()
+	if Conf.SessionLease > 0 {
+		go b.expire()
+	}
 	return b
 }
 
+// lease return the lease expire of the session put or renewed now, 0 never
+// expire if the lease disabled.
+func lease(now time.Time) int64 {
+	if Conf.SessionLease <= 0 {
+		return 0
+	}
+	return now.Add(Conf.SessionLease).UnixNano()
+}
+
 // Put put a channel according with user id.
-func (b *Bucket) Put(userId int64, server int32) (seq int32) {
+func (b *Bucket) Put(appId int32, userId int64, server int32, device string) (seq int32) {
 	var (
-		s  *Session
-		ok bool
+		s   *Session
+		ok  bool
+		key = userKey{appId: appId, userId: userId}
 	)
 	b.bLock.Lock()
-	if s, ok = b.sessions[userId]; !ok {
+	if s, ok = b.sessions[key]; !ok {
 		s = NewSession(b.server)
-		b.sessions[userId] = s
+		b.sessions[key] = s
+	}
+	seq = s.Put(server, device, lease(time.Now()))
+	b.index(server, key)
+	if b.store != nil {
+		b.store.Put(appId, userId, seq, server, device)
 	}
-	seq = s.Put(server)
 	b.bLock.Unlock()
 	return
 }
 
-func (b *Bucket) Get(userId int64) (seqs []int32, servers []int32) {
+// SetStore log the changes of the sessions to the store.
+func (b *Bucket) SetStore(store *Store) {
+	b.bLock.Lock()
+	b.store = store
+	b.bLock.Unlock()
+}
+
+// restore put the stored sub key, the lease is renewed for the comets to
+// renew it after the restart.
+func (b *Bucket) restore(appId int32, userId int64, seq, server int32, device string) {
+	var (
+		s   *Session
+		ok  bool
+		key = userKey{appId: appId, userId: userId}
+	)
+	b.bLock.Lock()
+	if s, ok = b.sessions[key]; !ok {
+		s = NewSession(b.server)
+		b.sessions[key] = s
+	}
+	s.Restore(seq, server, device, lease(time.Now()))
+	b.index(server, key)
+	b.bLock.Unlock()
+}
+
+// restoreSeq restore the stored seq counter of the user.
+func (b *Bucket) restoreSeq(appId int32, userId int64, seq int32) {
+	var (
+		s   *Session
+		ok  bool
+		key = userKey{appId: appId, userId: userId}
+	)
+	b.bLock.Lock()
+	if s, ok = b.sessions[key]; !ok {
+		s = NewSession(b.server)
+		b.sessions[key] = s
+	}
+	s.RestoreSeq(seq)
+	b.bLock.Unlock()
+}
+
+// restoreDel delete the stored sub key, the empty session is kept with its
+// seq counter for the reclaim after the replay.
+func (b *Bucket) restoreDel(appId int32, userId int64, seq int32) {
+	var (
+		s   *Session
+		ok  bool
+		key = userKey{appId: appId, userId: userId}
+	)
+	b.bLock.Lock()
+	if s, ok = b.sessions[key]; !ok {
+		s = NewSession(b.server)
+		b.sessions[key] = s
+	}
+	if server, has := s.Server(seq); has {
+		s.Del(seq)
+		if !s.HasServer(server) {
+			b.unindex(server, key)
+		}
+	}
+	s.RestoreSeq(seq)
+	b.bLock.Unlock()
+}
+
+// reclaim push the empty sessions restored to the cleaner, the seq counters
+// of the users offline are kept in the session expire as the live ones.
+func (b *Bucket) reclaim() {
+	var empty []userKey
+	b.bLock.RLock()
+	for key, s := range b.sessions {
+		if s.Size() == 0 {
+			empty = append(empty, key)
+		}
+	}
+	b.bLock.RUnlock()
+	for _, key := range empty {
+		b.cleaner.PushFront(key, Conf.SessionExpire)
+	}
+}
+
+// Dump encode the seq counters and the sub keys of the users as the store
+// records, the seq counters of the empty sessions wait for clean are dumped
+// too, so the sub keys of the users offline are not reused after restart.
+func (b *Bucket) Dump() (buf []byte) {
+	var (
+		seqs, servers []int32
+		devices       []string
+		o             = new(storeOp)
+	)
+	b.bLock.RLock()
+	for key, s := range b.sessions {
+		*o = storeOp{op: storeOpSeq, appId: key.appId, userId: key.userId, seq: s.seq}
+		buf = encodeStoreOp(buf, o)
+		seqs, servers, devices = s.Servers()
+		for i := 0; i < len(seqs); i++ {
+			*o = storeOp{op: storeOpPut, appId: key.appId, userId: key.userId, seq: seqs[i], server: servers[i], device: devices[i]}
+			buf = encodeStoreOp(buf, o)
+		}
+	}
+	b.bLock.RUnlock()
+	return
+}
+
+// index add the user to the reverse index of the server.
+func (b *Bucket) index(server int32, key userKey) {
+	users, ok := b.servers[server]
+	if !ok {
+		users = make(map[userKey]struct{})
+		b.servers[server] = users
+	}
+	users[key] = struct{}{}
+}
+
+// unindex remove the user from the reverse index of the server.
+func (b *Bucket) unindex(server int32, key userKey) {
+	if users, ok := b.servers[server]; ok {
+		delete(users, key)
+		if len(users) == 0 {
+			delete(b.servers, server)
+		}
+	}
+}
+
+func (b *Bucket) Get(appId int32, userId int64) (seqs []int32, servers []int32, devices []string) {
 	b.bLock.RLock()
-	if s, ok := b.sessions[userId]; ok {
-		seqs, servers = s.Servers()
+	if s, ok := b.sessions[userKey{appId: appId, userId: userId}]; ok {
+		seqs, servers, devices = s.Servers()
 	}
 	b.bLock.RUnlock()
 	return
 }
 
-func (b *Bucket) GetAll() (userIds []int64, seqs [][]int32, servers [][]int32) {
+func (b *Bucket) GetAll() (appIds []int32, userIds []int64, seqs [][]int32, servers [][]int32, devices [][]string) {
 	b.bLock.RLock()
 	i := len(b.sessions)
+	appIds = make([]int32, i)
 	userIds = make([]int64, i)
 	seqs = make([][]int32, i)
 	servers = make([][]int32, i)
-	for userId, s := range b.sessions {
+	devices = make([][]string, i)
+	for key, s := range b.sessions {
 		i--
-		userIds[i] = userId
-		seqs[i], servers[i] = s.Servers()
+		appIds[i] = key.appId
+		userIds[i] = key.userId
+		seqs[i], servers[i], devices[i] = s.Servers()
 	}
 	b.bLock.RUnlock()
 	return
 }
 
-func (b *Bucket) Count(userId int64) (count int) {
+func (b *Bucket) Count(appId int32, userId int64) (count int) {
 	b.bLock.RLock()
-	if s, ok := b.sessions[userId]; ok {
+	if s, ok := b.sessions[userKey{appId: appId, userId: userId}]; ok {
 		count = s.Size()
 	}
 	b.bLock.RUnlock()
 	return
 }
 
-func (b *Bucket) del(userId int64) {
+// Online count the online users and sessions of the app, all count the
+// users of all the apps, the empty sessions wait for clean are skipped.
+func (b *Bucket) Online(appId int32, all bool) (users, sessions int) {
+	var size int
+	b.bLock.RLock()
+	for key, s := range b.sessions {
+		if !all && key.appId != appId {
+			continue
+		}
+		if size = s.Size(); size > 0 {
+			users++
+			sessions += size
+		}
+	}
+	b.bLock.RUnlock()
+	return
+}
+
+func (b *Bucket) del(key userKey) {
 	var (
 		s  *Session
 		ok bool
 	)
-	if s, ok = b.sessions[userId]; ok {
+	if s, ok = b.sessions[key]; ok {
 		if s.Size() == 0 {
-			delete(b.sessions, userId)
+			delete(b.sessions, key)
 		}
 	}
 }
 
-func (b *Bucket) Del(userId int64) {
+func (b *Bucket) Del(appId int32, userId int64) {
 	b.bLock.Lock()
-	b.del(userId)
+	b.del(userKey{appId: appId, userId: userId})
 	b.bLock.Unlock()
 }
 
 // Del delete the channel by sub key.
-func (b *Bucket) DelSession(userId int64, seq int32) (ok bool) {
+func (b *Bucket) DelSession(appId int32, userId int64, seq int32) (ok bool) {
 	var (
 		s     *Session
 		empty bool
+		key   = userKey{appId: appId, userId: userId}
 	)
 	b.bLock.Lock()
-	if s, ok = b.sessions[userId]; ok {
+	if s, ok = b.sessions[key]; ok {
+		server, has := s.Server(seq)
 		// WARN:
 		// delete(b.sessions, userId)
 		// empty is a dirty data, we use here for try lru clean discard session.
 		// when one user flapped connect & disconnect, this also can reduce
 		// frequently new & free object, gc is slow!!!
 		empty = s.Del(seq)
+		if has && !s.HasServer(server) {
+			b.unindex(server, key)
+		}
+		if has && b.store != nil {
+			b.store.Del(appId, userId, seq)
+		}
 	}
 	b.bLock.Unlock()
 	// lru
 	if empty {
-		b.cleaner.PushFront(userId, Conf.SessionExpire)
+		b.cleaner.PushFront(key, Conf.SessionExpire)
 	}
 	return
 }
 
-func (b *Bucket) clean() {
+// DelServer delete all the sessions on the server, used when the comet
+// died without disconnect, return the deleted sessions count.
+func (b *Bucket) DelServer(server int32) (count int) {
+	var (
+		s     *Session
+		ok    bool
+		seqs  []int32
+		empty []userKey
+	)
+	b.bLock.Lock()
+	for key := range b.servers[server] {
+		if s, ok = b.sessions[key]; !ok {
+			continue
+		}
+		seqs = s.DelServer(server)
+		count += len(seqs)
+		if b.store != nil {
+			for _, seq := range seqs {
+				b.store.Del(key.appId, key.userId, seq)
+			}
+		}
+		if s.Size() == 0 {
+			empty = append(empty, key)
+		}
+	}
+	delete(b.servers, server)
+	b.bLock.Unlock()
+	// lru
+	for _, key := range empty {
+		b.cleaner.PushFront(key, Conf.SessionExpire)
+	}
+	return
+}
+
+// Renew extend the lease of the sub key on the server, false if the session
+// lost, expired or purged, the comet must kick the client.
+func (b *Bucket) Renew(appId int32, userId int64, seq, server int32) (ok bool) {
+	b.bLock.Lock()
+	if s, has := b.sessions[userKey{appId: appId, userId: userId}]; has {
+		ok = s.Renew(seq, server, lease(time.Now()))
+	}
+	b.bLock.Unlock()
+	return
+}
+
+// Expire delete the sessions whose lease lapsed, the ghost sessions left by
+// the lost disconnect, return the deleted sessions count.
+func (b *Bucket) Expire(now time.Time) (count int) {
 	var (
+		s       *Session
+		ok      bool
 		i       int
-		userIds []int64
+		server  int32
+		seqs    []int32
+		servers []int32
+		key     userKey
+		keys    []userKey
+		empty   []userKey
+		ts      = now.UnixNano()
+	)
+	b.bLock.RLock()
+	for key, s = range b.sessions {
+		if s.Expired(ts) {
+			keys = append(keys, key)
+		}
+	}
+	b.bLock.RUnlock()
+	if len(keys) == 0 {
+		return
+	}
+	b.bLock.Lock()
+	for _, key = range keys {
+		if s, ok = b.sessions[key]; !ok {
+			continue
+		}
+		seqs, servers = s.Expire(ts)
+		count += len(servers)
+		for i, server = range servers {
+			if !s.HasServer(server) {
+				b.unindex(server, key)
+			}
+			if b.store != nil {
+				b.store.Del(key.appId, key.userId, seqs[i])
+			}
+		}
+		if len(servers) > 0 && s.Size() == 0 {
+			empty = append(empty, key)
+		}
+	}
+	b.bLock.Unlock()
+	// lru
+	for _, key := range empty {
+		b.cleaner.PushFront(key, Conf.SessionExpire)
+	}
+	return
+}
+
+func (b *Bucket) expire() {
+	for {
+		time.Sleep(Conf.SessionLease / 2)
+		if count := b.Expire(time.Now()); count > 0 {
+			log.Info("bucket expire sessions: %d", count)
+		}
+	}
+}
+
+func (b *Bucket) clean() {
+	var (
+		i    int
+		keys []userKey
 	)
 	for {
-		userIds = b.cleaner.Clean()
-		if len(userIds) != 0 {
+		keys = b.cleaner.Clean()
+		if len(keys) != 0 {
 			b.bLock.Lock()
-			for i = 0; i < len(userIds); i++ {
-				b.del(userIds[i])
+			for i = 0; i < len(keys); i++ {
+				b.del(keys[i])
 			}
 			b.bLock.Unlock()
 			continue
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestMain set the config once, the goroutines of the buckets read it.
+func TestMain(m *testing.M) {
+	Conf = NewConfig()
+	Conf.SessionLease = time.Second
+	os.Exit(m.Run())
+}
+
+func TestBucketOnline(t *testing.T) {
+	b := NewBucket(10, 10, 10)
+	b.Put(0, 1, 1, "")
+	b.Put(0, 1, 2, "ios")
+	b.Put(1, 1, 1, "")
+	seq := b.Put(0, 2, 1, "")
+	b.DelSession(0, 2, seq)
+	if users, sessions := b.Online(0, false); users != 1 || sessions != 2 {
+		t.Errorf("Online(0) users: %d, sessions: %d", users, sessions)
+		t.FailNow()
+	}
+	if users, sessions := b.Online(0, true); users != 2 || sessions != 3 {
+		t.Errorf("Online(all) users: %d, sessions: %d", users, sessions)
+		t.FailNow()
+	}
+}
+
+func TestBucketDelServer(t *testing.T) {
+	b := NewBucket(10, 10, 10)
+	b.Put(0, 1, 1, "")
+	seq := b.Put(0, 1, 2, "")
+	b.Put(0, 2, 1, "")
+	b.Put(1, 3, 2, "")
+	if count := b.DelServer(1); count != 2 {
+		t.Errorf("DelServer(1) count: %d", count)
+		t.FailNow()
+	}
+	if seqs, servers, _ := b.Get(0, 1); len(seqs) != 1 || seqs[0] != seq || servers[0] != 2 {
+		t.Errorf("Get(0, 1) seqs: %v, servers: %v", seqs, servers)
+		t.FailNow()
+	}
+	if seqs, _, _ := b.Get(0, 2); len(seqs) != 0 {
+		t.Errorf("Get(0, 2) seqs: %v", seqs)
+		t.FailNow()
+	}
+	// the index of the server 2 keep the users with other sessions
+	b.DelSession(0, 1, seq)
+	if users := len(b.servers[2]); users != 1 {
+		t.Errorf("server 2 users: %d", users)
+		t.FailNow()
+	}
+	if count := b.DelServer(2); count != 1 {
+		t.Errorf("DelServer(2) count: %d", count)
+		t.FailNow()
+	}
+	if len(b.servers) != 0 {
+		t.Errorf("servers: %v", b.servers)
+		t.FailNow()
+	}
+}
+
+func TestBucketLease(t *testing.T) {
+	b := NewBucket(10, 10, 10)
+	seq := b.Put(0, 1, 1, "")
+	b.Put(0, 1, 2, "")
+	b.Put(0, 2, 1, "")
+	if b.Renew(0, 1, seq, 2) || b.Renew(0, 3, seq, 1) {
+		t.Fatal("Renew() the sub key not on the server")
+	}
+	if count := b.Expire(time.Now()); count != 0 {
+		t.Fatalf("Expire(now) count: %d", count)
+	}
+	// renew the seq on the server 1 only
+	time.Sleep(400 * time.Millisecond)
+	if !b.Renew(0, 1, seq, 1) {
+		t.Fatal("Renew() lost")
+	}
+	if count := b.Expire(time.Now().Add(800 * time.Millisecond)); count != 2 {
+		t.Fatalf("Expire() count: %d", count)
+	}
+	if seqs, _, _ := b.Get(0, 1); len(seqs) != 1 || seqs[0] != seq {
+		t.Fatalf("Get(0, 1) seqs: %v", seqs)
+	}
+	if _, ok := b.servers[2]; ok || len(b.servers[1]) != 1 {
+		t.Fatalf("servers: %v", b.servers)
+	}
+	if b.Renew(0, 2, 1, 1) {
+		t.Fatal("Renew() the expired sub key")
+	}
+}
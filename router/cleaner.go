@@ -10,7 +10,7 @@ const (
 )
 
 type CleanData struct {
-	Key        int64
+	Key        userKey
 	expireTime time.Time
 	next, prev *CleanData
 }
@@ -19,7 +19,7 @@ type Cleaner struct {
 	cLock sync.Mutex
 	size  int
 	root  CleanData
-	maps  map[int64]*CleanData
+	maps  map[userKey]*CleanData
 }
 
 func NewCleaner(cleaner int) *Cleaner {
@@ -27,11 +27,11 @@ func NewCleaner(cleaner int) *Cleaner {
 	c.root.next = &c.root
 	c.root.prev = &c.root
 	c.size = 0
-	c.maps = make(map[int64]*CleanData, cleaner)
+	c.maps = make(map[userKey]*CleanData, cleaner)
 	return c
 }
 
-func (c *Cleaner) PushFront(key int64, expire time.Duration) {
+func (c *Cleaner) PushFront(key userKey, expire time.Duration) {
 	c.cLock.Lock()
 	if e, ok := c.maps[key]; ok {
 		// update time
@@ -71,13 +71,13 @@ func (c *Cleaner) moveToFront(e *CleanData) {
 	}
 }
 
-func (c *Cleaner) Remove(key int64) {
+func (c *Cleaner) Remove(key userKey) {
 	c.cLock.Lock()
 	c.remove(key)
 	c.cLock.Unlock()
 }
 
-func (c *Cleaner) remove(key int64) {
+func (c *Cleaner) remove(key userKey) {
 	if e, ok := c.maps[key]; ok {
 		delete(c.maps, key)
 		e.prev.next = e.next
@@ -88,12 +88,12 @@ func (c *Cleaner) remove(key int64) {
 	}
 }
 
-func (c *Cleaner) Clean() (keys []int64) {
+func (c *Cleaner) Clean() (keys []userKey) {
 	var (
 		i int
 		e *CleanData
 	)
-	keys = make([]int64, 0, maxCleanNum)
+	keys = make([]userKey, 0, maxCleanNum)
 	c.cLock.Lock()
 	for i = 0; i < maxCleanNum; i++ {
 		if e = c.back(); e != nil {
@@ -35,6 +35,11 @@ type Config struct {
 	// session
 	Session       int           `goconf:"session:session"`
 	SessionExpire time.Duration `goconf:"session:expire:time"`
+	SessionLease  time.Duration `goconf:"session:lease:time"`
+	// store
+	StoreDir      string        `goconf:"store:dir"`
+	StoreSnapshot time.Duration `goconf:"store:snapshot:time"`
+	StoreSync     time.Duration `goconf:"store:sync:time"`
 }
 
 func NewConfig() *Config {
@@ -56,6 +61,9 @@ func NewConfig() *Config {
 		// session
 		Session:       1000,
 		SessionExpire: time.Hour * 1,
+		// store
+		StoreSnapshot: time.Minute * 10,
+		StoreSync:     time.Second * 1,
 	}
 }
 
@@ -69,6 +77,9 @@ func InitConfig() (err error) {
 	if err := gconf.Unmarshal(Conf); err != nil {
 		return err
 	}
+	if Conf.StoreDir != "" && (Conf.StoreSnapshot <= 0 || Conf.StoreSync <= 0) {
+		return ErrStorePeriod
+	}
 	return nil
 }
 
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"errors"
+)
+
+var (
+	ErrRenewArgs   = errors.New("renew rpc args error")
+	ErrStorePeriod = errors.New("store snapshot and sync must be greater than 0")
+)
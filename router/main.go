@@ -27,9 +27,14 @@ func main() {
 	for i := 0; i < Conf.Bucket; i++ {
 		buckets[i] = NewBucket(Conf.Session, Conf.Server, Conf.Cleaner)
 	}
+	// replay the sessions before serving
+	if err := InitStore(buckets); err != nil {
+		panic(err)
+	}
 	if err := InitRPC(buckets); err != nil {
 		panic(err)
 	}
 	// block until a signal is received.
 	InitSignal()
+	CloseStore(buckets)
 }
@@ -53,17 +53,17 @@ func (r *RouterRPC) bucket(userId int64) *Bucket {
 }
 
 func (r *RouterRPC) Connect(arg *proto.ConnArg, reply *proto.ConnReply) error {
-	reply.Seq = r.bucket(arg.UserId).Put(arg.UserId, arg.Server)
+	reply.Seq = r.bucket(arg.UserId).Put(arg.AppId, arg.UserId, arg.Server, arg.Device)
 	return nil
 }
 
 func (r *RouterRPC) Disconnect(arg *proto.DisconnArg, reply *proto.DisconnReply) error {
-	reply.Has = r.bucket(arg.UserId).DelSession(arg.UserId, arg.Seq)
+	reply.Has = r.bucket(arg.UserId).DelSession(arg.AppId, arg.UserId, arg.Seq)
 	return nil
 }
 
 func (r *RouterRPC) Get(arg *proto.GetArg, reply *proto.GetReply) error {
-	reply.Seqs, reply.Servers = r.bucket(arg.UserId).Get(arg.UserId)
+	reply.Seqs, reply.Servers, reply.Devices = r.bucket(arg.UserId).Get(arg.AppId, arg.UserId)
 	return nil
 }
 
@@ -71,16 +71,19 @@ func (r *RouterRPC) GetAll(arg *proto.NoArg, reply *proto.GetAllReply) error {
 	var (
 		i             int64
 		j             int
+		appIds        []int32
 		userIds       []int64
 		seqs, servers [][]int32
+		devices       [][]string
 		session       *proto.GetReply
 	)
 	for i = 0; i < r.BucketIdx; i++ {
-		userIds, seqs, servers = r.Buckets[i].GetAll()
+		appIds, userIds, seqs, servers, devices = r.Buckets[i].GetAll()
+		reply.AppIds = append(reply.AppIds, appIds...)
 		reply.UserIds = append(reply.UserIds, userIds...)
 		for j = 0; j < len(userIds); j++ {
 			session = new(proto.GetReply)
-			session.Seqs, session.Servers = seqs[j], servers[j]
+			session.Seqs, session.Servers, session.Devices = seqs[j], servers[j], devices[j]
 			reply.Sessions = append(reply.Sessions, session)
 		}
 	}
@@ -98,14 +101,51 @@ func (r *RouterRPC) MGet(arg *proto.MGetArg, reply *proto.MGetReply) error {
 	for i = 0; i < len(arg.UserIds); i++ {
 		userId = arg.UserIds[i]
 		session = new(proto.GetReply)
-		session.Seqs, session.Servers = r.bucket(userId).Get(userId)
+		session.Seqs, session.Servers, session.Devices = r.bucket(userId).Get(arg.AppId, userId)
 		reply.UserIds[i] = userId
 		reply.Sessions[i] = session
 	}
 	return nil
 }
 
+func (r *RouterRPC) Count(arg *proto.CountArg, reply *proto.CountReply) error {
+	var (
+		i               int64
+		users, sessions int
+	)
+	for i = 0; i < r.BucketIdx; i++ {
+		users, sessions = r.Buckets[i].Online(arg.AppId, arg.All)
+		reply.Users += int32(users)
+		reply.Sessions += int32(sessions)
+	}
+	return nil
+}
+
+// DelServer delete all the sessions of the dead comet.
+func (r *RouterRPC) DelServer(arg *proto.DelServerArg, reply *proto.DelServerReply) error {
+	var i int64
+	for i = 0; i < r.BucketIdx; i++ {
+		reply.Count += int32(r.Buckets[i].DelServer(arg.Server))
+	}
+	log.Info("del server: %d sessions: %d", arg.Server, reply.Count)
+	return nil
+}
+
+// Renew extend the leases of the sub keys of the comet, the indexes of the
+// lost sub keys are replied.
+func (r *RouterRPC) Renew(arg *proto.RenewArg, reply *proto.RenewReply) error {
+	if len(arg.AppIds) != len(arg.Seqs) || len(arg.UserIds) != len(arg.Seqs) {
+		return ErrRenewArgs
+	}
+	for i := 0; i < len(arg.Seqs); i++ {
+		if !r.bucket(arg.UserIds[i]).Renew(arg.AppIds[i], arg.UserIds[i], arg.Seqs[i], arg.Server) {
+			reply.Lost = append(reply.Lost, int32(i))
+		}
+	}
+	return nil
+}
+
 func (r *RouterRPC) GetSeqCount(arg *proto.GetSeqCountArg, reply *proto.GetSeqCountReply) error {
-	reply.Count = int32(r.bucket(arg.UserId).Count(arg.UserId))
+	reply.Count = int32(r.bucket(arg.UserId).Count(arg.AppId, arg.UserId))
 	return nil
 }
@@ -2,13 +2,17 @@ package main
 
 type Session struct {
 	seq     int32
-	servers map[int32]int32 // map[user_id] ->  map[sub_id] -> server_id
+	servers map[int32]int32  // map[user_id] ->  map[sub_id] -> server_id
+	devices map[int32]string // map[sub_id] -> device, empty not stored
+	leases  map[int32]int64  // map[sub_id] -> lease expire unix nano, 0 never expire
 }
 
 // NewSession new a session struct. store the seq and serverid.
 func NewSession(server int) *Session {
 	s := new(Session)
 	s.servers = make(map[int32]int32, server)
+	s.devices = make(map[int32]string)
+	s.leases = make(map[int32]int64, server)
 	s.seq = 0
 	return s
 }
@@ -18,24 +22,110 @@ func (s *Session) nextSeq() int32 {
 	return s.seq
 }
 
-// Put put a session according with sub key.
-func (s *Session) Put(server int32) (seq int32) {
+// Put put a session according with sub key, the session expire at the
+// lease unless renewed.
+func (s *Session) Put(server int32, device string, lease int64) (seq int32) {
 	seq = s.nextSeq()
 	s.servers[seq] = server
+	s.leases[seq] = lease
+	if device != "" {
+		s.devices[seq] = device
+	}
 	return
 }
 
-func (s *Session) Servers() (seqs []int32, servers []int32) {
+func (s *Session) Servers() (seqs []int32, servers []int32, devices []string) {
 	var (
 		i           = len(s.servers)
 		seq, server int32
 	)
 	seqs = make([]int32, i)
 	servers = make([]int32, i)
+	devices = make([]string, i)
 	for seq, server = range s.servers {
 		i--
 		seqs[i] = seq
 		servers[i] = server
+		devices[i] = s.devices[seq]
+	}
+	return
+}
+
+// Server return the server of the sub key.
+func (s *Session) Server(seq int32) (server int32, ok bool) {
+	server, ok = s.servers[seq]
+	return
+}
+
+// HasServer return true if any sub key on the server.
+func (s *Session) HasServer(server int32) bool {
+	for _, sv := range s.servers {
+		if sv == server {
+			return true
+		}
+	}
+	return false
+}
+
+// DelServer delete the sessions on the server, return the deleted seqs.
+func (s *Session) DelServer(server int32) (seqs []int32) {
+	for seq, sv := range s.servers {
+		if sv == server {
+			delete(s.servers, seq)
+			delete(s.devices, seq)
+			delete(s.leases, seq)
+			seqs = append(seqs, seq)
+		}
+	}
+	return
+}
+
+// Restore put the sub key of the stored seq, the seq counter is kept ahead.
+func (s *Session) Restore(seq, server int32, device string, lease int64) {
+	s.servers[seq] = server
+	if device != "" {
+		s.devices[seq] = device
+	}
+	s.leases[seq] = lease
+	s.RestoreSeq(seq)
+}
+
+// RestoreSeq keep the seq counter not behind the stored seq.
+func (s *Session) RestoreSeq(seq int32) {
+	if seq > s.seq {
+		s.seq = seq
+	}
+}
+
+// Renew extend the lease of the sub key, false if the sub key not exists
+// on the server.
+func (s *Session) Renew(seq, server int32, lease int64) bool {
+	if sv, ok := s.servers[seq]; !ok || sv != server {
+		return false
+	}
+	s.leases[seq] = lease
+	return true
+}
+
+// Expired return true if any lease of the sub keys lapsed.
+func (s *Session) Expired(now int64) bool {
+	for _, lease := range s.leases {
+		if lease != 0 && lease < now {
+			return true
+		}
+	}
+	return false
+}
+
+// Expire delete the sub keys whose lease lapsed, return the seqs and
+// servers of the deleted sub keys.
+func (s *Session) Expire(now int64) (seqs, servers []int32) {
+	for seq, lease := range s.leases {
+		if lease != 0 && lease < now {
+			seqs = append(seqs, seq)
+			servers = append(servers, s.servers[seq])
+			s.Del(seq)
+		}
 	}
 	return
 }
@@ -43,6 +133,8 @@ func (s *Session) Servers() (seqs []int32, servers []int32) {
 // Del delete the session by sub key.
 func (s *Session) Del(seq int32) bool {
 	delete(s.servers, seq)
+	delete(s.devices, seq)
+	delete(s.leases, seq)
 	return (len(s.servers) == 0)
 }
 
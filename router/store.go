@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	log "code.google.com/p/log4go"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	storeOpPut = 1
+	storeOpDel = 2
+	storeOpSeq = 3
+
+	storeHeaderLen    = 23 // op(1) appId(4) userId(8) seq(4) server(4) deviceLen(2)
+	storeMaxDeviceLen = 1<<16 - 1
+
+	storeSnapshotFile = "snapshot"
+	storeLogFile      = "oplog"
+	storeOldLogFile   = "oplog.1"
+)
+
+var (
+	routerStore *Store
+)
+
+// storeOp is a record of the oplog and snapshot, the put record set the sub
+// key, the seq record set the seq counter of the user.
+type storeOp struct {
+	op     byte
+	appId  int32
+	userId int64
+	seq    int32
+	server int32
+	device string
+}
+
+// Store persist the sessions of the buckets in dir for warm restart, every
+// Put and Del is appended to the oplog, the purged and expired sub keys are
+// logged as Del, the snapshot is the compacted records of all the sessions
+// written periodically. On startup the snapshot, the rotated oplog and the
+// oplog are replayed in order, the records only touch its own sub key and
+// are idempotent, so the ones already in the snapshot are harmless.
+type Store struct {
+	lock sync.Mutex
+	dir  string
+	f    *os.File
+	w    *bufio.Writer
+	buf  []byte
+	quit chan struct{}
+}
+
+func NewStore(dir string) (s *Store, err error) {
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	s = &Store{dir: dir, quit: make(chan struct{})}
+	if err = s.open(); err != nil {
+		s = nil
+	}
+	return
+}
+
+// InitStore replay the stored sessions into the buckets then log the
+// changes of them, empty Conf.StoreDir disable it.
+func InitStore(bs []*Bucket) (err error) {
+	if Conf.StoreDir == "" {
+		return
+	}
+	if routerStore, err = NewStore(Conf.StoreDir); err != nil {
+		log.Error("NewStore(\"%s\") error(%v)", Conf.StoreDir, err)
+		return
+	}
+	if err = routerStore.Load(bs); err != nil {
+		log.Error("store.Load() error(%v)", err)
+		return
+	}
+	for _, b := range bs {
+		b.SetStore(routerStore)
+	}
+	go routerStore.proc(bs)
+	return
+}
+
+// CloseStore snapshot the buckets for the next start and close the store.
+func CloseStore(bs []*Bucket) {
+	if routerStore == nil {
+		return
+	}
+	if err := routerStore.Snapshot(bs); err != nil {
+		log.Error("store.Snapshot() error(%v)", err)
+	}
+	if err := routerStore.Close(); err != nil {
+		log.Error("store.Close() error(%v)", err)
+	}
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+func (s *Store) open() (err error) {
+	if s.f, err = os.OpenFile(s.path(storeLogFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+		return
+	}
+	s.w = bufio.NewWriter(s.f)
+	return
+}
+
+// Put log the sub key put.
+func (s *Store) Put(appId int32, userId int64, seq, server int32, device string) {
+	s.write(&storeOp{op: storeOpPut, appId: appId, userId: userId, seq: seq, server: server, device: device})
+}
+
+// Del log the sub key deleted.
+func (s *Store) Del(appId int32, userId int64, seq int32) {
+	s.write(&storeOp{op: storeOpDel, appId: appId, userId: userId, seq: seq})
+}
+
+func (s *Store) write(o *storeOp) {
+	s.lock.Lock()
+	s.buf = encodeStoreOp(s.buf[:0], o)
+	if _, err := s.w.Write(s.buf); err != nil {
+		log.Error("store write oplog error(%v)", err)
+	}
+	s.lock.Unlock()
+}
+
+// Flush write the buffered oplog to the file.
+func (s *Store) Flush() (err error) {
+	s.lock.Lock()
+	err = s.w.Flush()
+	s.lock.Unlock()
+	return
+}
+
+// rotate rename the oplog to the old one and open a new oplog, the old
+// oplog of the last failed snapshot is kept and the oplog continue.
+func (s *Store) rotate() (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err = os.Stat(s.path(storeOldLogFile)); err == nil || !os.IsNotExist(err) {
+		return
+	}
+	if err = s.w.Flush(); err != nil {
+		return
+	}
+	if err = s.f.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(s.path(storeLogFile), s.path(storeOldLogFile)); err != nil {
+		return
+	}
+	return s.open()
+}
+
+// Snapshot write all the sessions of the buckets to the snapshot file, the
+// oplog before it is removed.
+func (s *Store) Snapshot(bs []*Bucket) (err error) {
+	if err = s.rotate(); err != nil {
+		return
+	}
+	return s.dump(bs)
+}
+
+// dump write the snapshot of the buckets after the rotate, the changes
+// between them are both in the snapshot and the new oplog.
+func (s *Store) dump(bs []*Bucket) (err error) {
+	var (
+		f   *os.File
+		tmp = s.path(storeSnapshotFile + ".tmp")
+	)
+	if f, err = os.Create(tmp); err != nil {
+		return
+	}
+	for _, b := range bs {
+		if _, err = f.Write(b.Dump()); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return
+	}
+	if err = os.Rename(tmp, s.path(storeSnapshotFile)); err != nil {
+		return
+	}
+	if err = os.Remove(s.path(storeOldLogFile)); err != nil && os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+// Load replay the snapshot, the old oplog and the oplog into the buckets.
+// The order matters: the snapshot is dumped after the rotate so it covers
+// the old oplog, the old oplog of a failed snapshot holds the changes after
+// the last snapshot, and the changes logged between the rotate and the dump
+// are in both the snapshot and the oplog, so the oplog is replayed last.
+func (s *Store) Load(bs []*Bucket) (err error) {
+	var (
+		f     *os.File
+		count int
+	)
+	for _, name := range []string{storeSnapshotFile, storeOldLogFile, storeLogFile} {
+		if f, err = os.Open(s.path(name)); err != nil {
+			if os.IsNotExist(err) {
+				err = nil
+				continue
+			}
+			return
+		}
+		count, err = replayStore(bufio.NewReader(f), bs)
+		f.Close()
+		if err != nil {
+			return
+		}
+		log.Info("store replay \"%s\" records: %d", name, count)
+	}
+	for _, b := range bs {
+		b.reclaim()
+	}
+	return
+}
+
+func (s *Store) Close() (err error) {
+	close(s.quit)
+	s.lock.Lock()
+	if err = s.w.Flush(); err == nil {
+		err = s.f.Close()
+	}
+	s.lock.Unlock()
+	return
+}
+
+// proc flush the oplog every Conf.StoreSync and snapshot the buckets every
+// Conf.StoreSnapshot.
+func (s *Store) proc(bs []*Bucket) {
+	var (
+		err      error
+		flush    = time.NewTicker(Conf.StoreSync)
+		snapshot = time.NewTicker(Conf.StoreSnapshot)
+	)
+	defer flush.Stop()
+	defer snapshot.Stop()
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-flush.C:
+			if err = s.Flush(); err != nil {
+				log.Error("store.Flush() error(%v)", err)
+			}
+		case <-snapshot.C:
+			if err = s.Snapshot(bs); err != nil {
+				log.Error("store.Snapshot() error(%v)", err)
+			}
+		}
+	}
+}
+
+func encodeStoreOp(buf []byte, o *storeOp) []byte {
+	var (
+		header [storeHeaderLen]byte
+		device = o.device
+	)
+	if len(device) > storeMaxDeviceLen {
+		device = device[:storeMaxDeviceLen]
+	}
+	header[0] = o.op
+	binary.BigEndian.PutUint32(header[1:], uint32(o.appId))
+	binary.BigEndian.PutUint64(header[5:], uint64(o.userId))
+	binary.BigEndian.PutUint32(header[13:], uint32(o.seq))
+	binary.BigEndian.PutUint32(header[17:], uint32(o.server))
+	binary.BigEndian.PutUint16(header[21:], uint16(len(device)))
+	buf = append(buf, header[:]...)
+	return append(buf, device...)
+}
+
+func readStoreOp(rd io.Reader, header []byte, o *storeOp) (err error) {
+	if _, err = io.ReadFull(rd, header); err != nil {
+		return
+	}
+	o.op = header[0]
+	o.appId = int32(binary.BigEndian.Uint32(header[1:]))
+	o.userId = int64(binary.BigEndian.Uint64(header[5:]))
+	o.seq = int32(binary.BigEndian.Uint32(header[13:]))
+	o.server = int32(binary.BigEndian.Uint32(header[17:]))
+	device := make([]byte, binary.BigEndian.Uint16(header[21:]))
+	if _, err = io.ReadFull(rd, device); err != nil {
+		return
+	}
+	o.device = string(device)
+	return
+}
+
+// replayStore apply the records to the buckets, a truncated tail record
+// written by a crash is ignored.
+func replayStore(rd io.Reader, bs []*Bucket) (count int, err error) {
+	var (
+		o      storeOp
+		b      *Bucket
+		header = make([]byte, storeHeaderLen)
+	)
+	for {
+		if err = readStoreOp(rd, header, &o); err != nil {
+			break
+		}
+		b = bs[int(o.userId%int64(len(bs)))]
+		switch o.op {
+		case storeOpPut:
+			b.restore(o.appId, o.userId, o.seq, o.server, o.device)
+		case storeOpSeq:
+			b.restoreSeq(o.appId, o.userId, o.seq)
+		case storeOpDel:
+			b.restoreDel(o.appId, o.userId, o.seq)
+		default:
+			log.Error("store record op: %d invalid", o.op)
+			return
+		}
+		count++
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "router-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := []*Bucket{NewBucket(10, 10, 10), NewBucket(10, 10, 10)}
+	for _, b := range bs {
+		b.SetStore(s)
+	}
+	bs[1].Put(0, 1, 1, "ios")
+	seq := bs[1].Put(0, 1, 2, "")
+	bs[0].Put(1, 2, 3, "")
+	if err = s.Snapshot(bs); err != nil {
+		t.Fatal(err)
+	}
+	// the changes after the snapshot are in the oplog
+	bs[1].DelSession(0, 1, seq)
+	bs[1].Put(0, 3, 3, "")
+	bs[0].DelServer(3)
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// a truncated record of the crash
+	f, err := os.OpenFile(filepath.Join(dir, storeLogFile), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{storeOpPut, 0, 0})
+	f.Close()
+	if s, err = NewStore(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rs := []*Bucket{NewBucket(10, 10, 10), NewBucket(10, 10, 10)}
+	if err = s.Load(rs); err != nil {
+		t.Fatal(err)
+	}
+	if seqs, servers, devices := rs[1].Get(0, 1); len(seqs) != 1 || seqs[0] != 1 || servers[0] != 1 || devices[0] != "ios" {
+		t.Fatalf("Get(0, 1) seqs: %v, servers: %v, devices: %v", seqs, servers, devices)
+	}
+	if seqs, _, _ := rs[0].Get(1, 2); len(seqs) != 0 {
+		t.Fatalf("Get(1, 2) seqs: %v", seqs)
+	}
+	// the purge of the bucket 0 keep the sessions of the bucket 1
+	if seqs, servers, _ := rs[1].Get(0, 3); len(seqs) != 1 || servers[0] != 3 {
+		t.Fatalf("Get(0, 3) seqs: %v, servers: %v", seqs, servers)
+	}
+	// the seq counter is restored
+	if seq = rs[1].Put(0, 1, 1, ""); seq != 3 {
+		t.Fatalf("Put() seq: %d", seq)
+	}
+}
+
+func TestStoreRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "router-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := []*Bucket{NewBucket(10, 10, 10)}
+	bs[0].SetStore(s)
+	seq := bs[0].Put(0, 1, 1, "")
+	bs[0].Put(0, 2, 1, "")
+	// user 2 goes offline, only its seq counter is left
+	bs[0].DelSession(0, 2, 1)
+	if err = s.rotate(); err != nil {
+		t.Fatal(err)
+	}
+	// the changes between the rotate and the dump
+	bs[0].DelSession(0, 1, seq)
+	bs[0].Put(0, 3, 1, "")
+	if err = s.dump(bs); err != nil {
+		t.Fatal(err)
+	}
+	bs[0].DelSession(0, 3, 1)
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = NewStore(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rs := []*Bucket{NewBucket(10, 10, 10)}
+	if err = s.Load(rs); err != nil {
+		t.Fatal(err)
+	}
+	for _, userId := range []int64{1, 2, 3} {
+		if seqs, _, _ := rs[0].Get(0, userId); len(seqs) != 0 {
+			t.Fatalf("Get(0, %d) seqs: %v", userId, seqs)
+		}
+		// the seq counters of the users offline are restored
+		if seq = rs[0].Put(0, userId, 1, ""); seq != 2 {
+			t.Fatalf("Put(0, %d) seq: %d", userId, seq)
+		}
+	}
+}